@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler serves the administration endpoints
+type AdminHandler struct {
+	UserService *service.UserService
+	AuthService *service.AuthService
+}
+
+// NewAdminHandler initializes AdminHandler
+func NewAdminHandler(userService *service.UserService, authService *service.AuthService) *AdminHandler {
+	return &AdminHandler{
+		UserService: userService,
+		AuthService: authService,
+	}
+}
+
+// GetUser returns any user by ID
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, err := h.UserService.GetUser(c.Param("id"))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", user)
+}
+
+// SetUserRoles replaces the roles of a user
+func (h *AdminHandler) SetUserRoles(c *gin.Context) {
+	var req struct {
+		Roles []string `json:"roles" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.UserService.SetRoles(c.Param("id"), req.Roles)
+	switch {
+	case errors.Is(err, service.ErrInvalidRole):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, repository.ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User roles updated successfully", user)
+}
+
+// ResetTwoFactor turns off a user's two-factor authentication, for users who
+// lost both their authenticator app and recovery codes
+func (h *AdminHandler) ResetTwoFactor(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.AuthService.ResetTwoFactor(principal.UserID, c.Param("id"))
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication reset successfully", nil)
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingRevoker remembers which users were signed out
+type recordingRevoker struct {
+	loggedOut []string
+}
+
+func (r *recordingRevoker) LogoutAll(userID string) error {
+	r.loggedOut = append(r.loggedOut, userID)
+	return nil
+}
+
+func TestAdminHandlerSetUserRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		body      string
+		want      int
+		wantRoles []string
+	}{
+		{"valid roles", "u1", `{"roles":["user","seller"]}`, http.StatusOK, []string{"user", "seller"}},
+		{"unknown role", "u1", `{"roles":["user","root"]}`, http.StatusBadRequest, []string{"user"}},
+		{"no roles", "u1", `{"roles":[]}`, http.StatusBadRequest, []string{"user"}},
+		{"missing roles", "u1", `{}`, http.StatusBadRequest, []string{"user"}},
+		{"unknown user", "u2", `{"roles":["user"]}`, http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			ctx := context.Background()
+			users := repository.NewMemoryUserRepository()
+			if err := users.Create(ctx, &models.User{ID: "u1", Email: "alice@example.com", Roles: []string{models.RoleUser}}); err != nil {
+				t.Fatalf("Create() = %v", err)
+			}
+			sessions := &recordingRevoker{}
+			h := NewAdminHandler(service.NewUserService(users, sessions, nil, nil), nil)
+			router := gin.New()
+			router.PUT("/users/:id/roles", h.SetUserRoles)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/users/"+tt.id+"/roles", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("PUT roles = %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+
+			// Only a role change signs the user out
+			wantLoggedOut := ""
+			if tt.want == http.StatusOK {
+				wantLoggedOut = tt.id
+			}
+			if got := strings.Join(sessions.loggedOut, ","); got != wantLoggedOut {
+				t.Fatalf("signed out %q, want %q", got, wantLoggedOut)
+			}
+
+			if tt.wantRoles == nil {
+				return
+			}
+			user, err := users.GetByID(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("GetByID() = %v", err)
+			}
+			if strings.Join(user.Roles, ",") != strings.Join(tt.wantRoles, ",") {
+				t.Fatalf("roles = %v, want %v", user.Roles, tt.wantRoles)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package v1
+
+import (
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminRoutes registers the admin routes; every route requires
+// authentication plus the permission it is guarded by
+func RegisterAdminRoutes(router *gin.RouterGroup, adminHandler *AdminHandler, auth gin.HandlerFunc) {
+	admin := router.Group("/admin", auth)
+	admin.GET("/users/:id", middleware.RequirePermission(models.PermissionUsersRead), adminHandler.GetUser)
+	admin.PUT("/users/:id/roles", middleware.RequirePermission(models.PermissionRolesManage), adminHandler.SetUserRoles)
+	admin.DELETE("/users/:id/2fa", middleware.RequirePermission(models.PermissionMFAReset), adminHandler.ResetTwoFactor)
+}
@@ -1,10 +1,14 @@
 package v1
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
 	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
 	"github.com/Dffarhn/bakulenapi/pkg/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -13,113 +17,689 @@ type GoogleAuthRequest struct {
 	IDToken string `json:"idToken"`
 }
 
+// authResponse is returned by endpoints that both create a user and sign it in
+type authResponse struct {
+	*models.AuthTokens
+	User *models.User `json:"user"`
+}
+
 // AuthHandler struct
 type AuthHandler struct {
 	AuthService *service.AuthService
 }
 
 // NewAuthHandler initializes AuthHandler
-func NewAuthHandler() *AuthHandler {
+func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 	return &AuthHandler{
-		AuthService: service.NewAuthService(),
+		AuthService: authService,
 	}
 }
 
 // RegisterUser handles user registration
 func (h *AuthHandler) RegisterUser(c *gin.Context) {
 	var req struct {
-		Username        string `json:"username"`
-		Email           string `json:"email"`
-		Password        string `json:"password"`
-		RetypedPassword string `json:"retyped_password"`
+		Username        string `json:"username" binding:"required,username"`
+		Email           string `json:"email" binding:"required,email"`
+		Password        string `json:"password" binding:"required"`
+		RetypedPassword string `json:"retyped_password" binding:"required,eqfield=Password"`
 		FCMToken        string `json:"fcm_token"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	user, token, err := h.AuthService.Register(req.Email, req.Username, req.Password, req.FCMToken)
+	client := clientInfo(c)
+	client.FCMToken = req.FCMToken
+	user, tokens, err := h.AuthService.Register(req.Email, req.Username, req.Password, client)
+	if validationFailed(c, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrEmailTaken) || errors.Is(err, repository.ErrUsernameTaken) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusCreated, "User registered successfully", gin.H{
-		"token": token,
-		"user":  user,
+	utils.SuccessResponse(c, http.StatusCreated, "User registered successfully", authResponse{
+		AuthTokens: tokens,
+		User:       user,
 	})
 }
 
 // LoginUser handles user login
 func (h *AuthHandler) LoginUser(c *gin.Context) {
 	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Println("[ERROR] Invalid request format:", err)
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	log.Printf("[INFO] User login attempt: Email - %s", req.Email)
-
-	token, err := h.AuthService.Login(req.Email, req.Password)
+	tokens, challenge, err := h.AuthService.Login(req.Email, req.Password, clientInfo(c))
+	if respondThrottled(c, err) {
+		log.Println("[WARNING] Login throttled for IP:", c.ClientIP())
+		return
+	}
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
 	if err != nil {
 		log.Println("[ERROR] Login failed:", err)
-		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Login failed")
 		return
 	}
 
-	log.Println("[INFO] Login successful, token generated")
+	respondLogin(c, "Login successful", tokens, challenge)
+}
 
-	utils.SuccessResponse(c, http.StatusOK, "Login successful", gin.H{
-		"token": token,
-	})
+// respondLogin responds with the tokens of a sign-in, or with the challenge
+// when the user still has to pass two-factor authentication through VerifyMFA
+func respondLogin(c *gin.Context, message string, tokens *models.AuthTokens, challenge *models.MFAChallenge) {
+	if challenge != nil {
+		utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication required", challenge)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, message, tokens)
+}
+
+// respondThrottled writes the 429 response for a ThrottledError and reports whether err was one
+func respondThrottled(c *gin.Context, err error) bool {
+	var throttled *service.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	utils.TooManyRequestsResponse(c, throttled.RetryAfter, err.Error())
+	return true
+}
+
+// VerifyMFA completes a sign-in that returned a two-factor challenge
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"mfa_token" binding:"required"`
+		// Code is a TOTP code or a recovery code
+		Code string `json:"code" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := h.AuthService.VerifyMFA(req.ChallengeToken, req.Code, clientInfo(c))
+	switch {
+	case respondThrottled(c, err):
+		return
+	case errors.Is(err, service.ErrInvalidMFAChallenge), errors.Is(err, service.ErrInvalidMFACode):
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	case err != nil:
+		log.Println("[ERROR] Two-factor verification failed:", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Two-factor verification failed")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Login successful", tokens)
 }
 
 // made it when use google idtoken
 // GoogleLogin handles Google login verification
 func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 	var req struct {
-		IDToken string `json:"idToken"`
+		IDToken string `json:"idToken" binding:"required"`
+		// Password confirms linking Google to an existing account with the same email
+		Password string `json:"password"`
 	}
 
-	// Bind JSON request body
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Verify Google ID Token
-	token, err := h.AuthService.VerifyGoogleIDToken(req.IDToken)
+	h.loginWithProvider(c, models.ProviderGoogle, req.IDToken, req.Password)
+}
+
+// ProviderLogin signs in with an ID token from the OpenID Connect provider named in the path
+func (h *AuthHandler) ProviderLogin(c *gin.Context) {
+	var req struct {
+		IDToken string `json:"id_token" binding:"required"`
+		// Password confirms linking the provider to an existing account with the same email
+		Password string `json:"password"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	h.loginWithProvider(c, c.Param("provider"), req.IDToken, req.Password)
+}
+
+// FirebaseLogin exchanges a Firebase Auth ID token for our tokens
+func (h *AuthHandler) FirebaseLogin(c *gin.Context) {
+	var req struct {
+		IDToken string `json:"id_token" binding:"required"`
+		// Password confirms linking Firebase to an existing account with the same email
+		Password string `json:"password"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, challenge, err := h.AuthService.LoginWithFirebase(req.IDToken, req.Password, clientInfo(c))
+	if respondLinkError(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrFirebaseNotConfigured) {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	respondLogin(c, "Login or Register successful", tokens, challenge)
+}
+
+// loginWithProvider verifies a provider ID token and responds with our tokens
+func (h *AuthHandler) loginWithProvider(c *gin.Context, provider, idToken, password string) {
+	tokens, challenge, err := h.AuthService.LoginWithProvider(provider, idToken, password, clientInfo(c))
+	if respondThrottled(c, err) {
+		log.Println("[WARNING] Provider login throttled for IP:", c.ClientIP())
+		return
+	}
+	if respondLinkError(c, err) {
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	// Respond with user details
-	utils.SuccessResponse(c, http.StatusOK, "Login or Register successful", gin.H{
-		"token": token,
-	})
+	respondLogin(c, "Login or Register successful", tokens, challenge)
 }
 
-// store fcm token
+// RefreshToken exchanges a refresh token for a new access/refresh token pair
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := h.AuthService.Refresh(req.RefreshToken, clientInfo(c))
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Token refreshed successfully", tokens)
+}
+
+// Logout revokes the current access token and, if provided, its refresh token
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// The body is optional; only reject it when it is malformed
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.AuthService.Logout(principal, req.RefreshToken); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Logged out successfully", nil)
+}
+
+// LogoutAll revokes every token of the current user on all devices
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.AuthService.LogoutAll(principal.UserID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Logged out from all devices successfully", nil)
+}
+
+// VerifyEmail confirms the email address a verification link was sent to
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.AuthService.VerifyEmail(req.Token)
+	if errors.Is(err, service.ErrInvalidVerificationToken) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Email verified successfully", nil)
+}
+
+// ResendVerificationEmail sends the current user a new verification link
+func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.AuthService.ResendVerificationEmail(principal.UserID)
+	if errors.Is(err, service.ErrEmailAlreadyVerified) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Verification email sent", nil)
+}
+
+// ForgotPassword emails a password reset link if an account exists for the email
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.AuthService.ForgotPassword(req.Email); err != nil {
+		log.Println("[ERROR] Forgot password failed:", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send password reset email")
+		return
+	}
+
+	// Same answer whether or not the account exists
+	utils.SuccessResponse(c, http.StatusOK, "If an account exists for this email, a password reset link has been sent", nil)
+}
+
+// ResetPassword sets a new password using the token from a reset email
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.AuthService.ResetPassword(req.Token, req.Password)
+	if validationFailed(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrInvalidResetToken) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Password reset successfully", nil)
+}
+
+// ChangePassword replaces the current user's password and signs out their other sessions
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.AuthService.ChangePassword(principal, req.CurrentPassword, req.NewPassword, clientInfo(c))
+	switch {
+	case respondThrottled(c, err):
+		log.Println("[WARNING] Password change throttled for user:", principal.UserID)
+		return
+	case validationFailed(c, err):
+		return
+	case errors.Is(err, service.ErrIncorrectPassword),
+		errors.Is(err, service.ErrSamePassword):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, service.ErrNoPassword):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Password changed successfully", nil)
+}
+
+// ListIdentities returns the sign-in methods linked to the current user
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	identities, err := h.AuthService.Identities(principal.UserID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Identities retrieved successfully", identities)
+}
+
+// LinkIdentity links a sign-in method to the current user: a new password,
+// or a provider account proven by one of its ID tokens
+func (h *AuthHandler) LinkIdentity(c *gin.Context) {
+	var req struct {
+		IDToken  string `json:"id_token"`
+		Password string `json:"password"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	provider := c.Param("provider")
+	credential := req.IDToken
+	if provider == models.ProviderPassword {
+		credential = req.Password
+	}
+
+	identities, err := h.AuthService.LinkIdentity(principal, provider, credential)
+	if validationFailed(c, err) || respondLinkError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Sign-in method linked successfully", identities)
+}
+
+// UnlinkIdentity removes a sign-in method from the current user
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	identities, err := h.AuthService.UnlinkIdentity(principal, c.Param("provider"))
+	if respondLinkError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Sign-in method unlinked successfully", identities)
+}
+
+// respondLinkError writes the response for account linking errors and reports whether err was one
+func respondLinkError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrLinkConfirmationRequired),
+		errors.Is(err, service.ErrProviderEmailNotVerified),
+		errors.Is(err, service.ErrProviderAlreadyLinked),
+		errors.Is(err, service.ErrLastProvider),
+		errors.Is(err, repository.ErrIdentityTaken):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrIncorrectPassword):
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrProviderNotLinked), errors.Is(err, service.ErrUnknownProvider):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// EnrollTOTP starts two-factor enrollment and returns the secret for the
+// current user's authenticator app, as an otpauth URI and QR code
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	enrollment, err := h.AuthService.EnrollTOTP(principal)
+	if respondTwoFactorError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scan the QR code with your authenticator app, then confirm a code", enrollment)
+}
+
+// ConfirmTOTP enables two-factor authentication with a first code from the
+// authenticator app and returns the recovery codes
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	codes, err := h.AuthService.ConfirmTOTP(principal, req.Code)
+	if respondTwoFactorError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication enabled, store the recovery codes safely", gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	codes, err := h.AuthService.RegenerateRecoveryCodes(principal, req.Code, c.ClientIP())
+	if respondTwoFactorError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Recovery codes regenerated, store them safely", gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor turns two-factor authentication off for the current user
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.AuthService.DisableTwoFactor(principal, req.Code, c.ClientIP())
+	if respondTwoFactorError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// respondTwoFactorError writes the response for two-factor setup errors and reports whether err was one
+func respondTwoFactorError(c *gin.Context, err error) bool {
+	switch {
+	case respondThrottled(c, err):
+	case errors.Is(err, service.ErrTwoFactorEnabled),
+		errors.Is(err, service.ErrTwoFactorNotEnabled),
+		errors.Is(err, service.ErrTwoFactorNotEnrolled):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidMFACode):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// ListSessions returns the devices the caller is signed in on
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	sessions, err := h.AuthService.ListSessions(principal)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeSession signs one of the caller's devices out
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.AuthService.RevokeSession(principal, c.Param("id"))
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// StoreFCMToken records the push notification token of the caller's device
 func (h *AuthHandler) StoreFCMToken(c *gin.Context) {
 	var req struct {
-		FCMToken string `json:"fcm_token"`
+		FCMToken string `json:"fcm_token" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	userID := c.GetString("userID")
-	if err := h.AuthService.StoreFCMToken(userID, req.FCMToken); err != nil {
+	err := h.AuthService.StoreFCMToken(principal, req.FCMToken)
+	if errors.Is(err, service.ErrNoSession) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
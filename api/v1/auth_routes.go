@@ -4,9 +4,34 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterAuthRoutes(router *gin.RouterGroup, authHandler *AuthHandler) {
+// RegisterAuthRoutes registers the auth routes; auth is the authentication middleware
+func RegisterAuthRoutes(router *gin.RouterGroup, authHandler *AuthHandler, auth gin.HandlerFunc) {
 	// Register user routes
 	router.POST("/auth/register", authHandler.RegisterUser)
 	router.POST("/auth/login", authHandler.LoginUser)
+	router.POST("/auth/mfa", authHandler.VerifyMFA)
 	router.POST("/auth/google", authHandler.GoogleLogin)
+	router.POST("/auth/refresh", authHandler.RefreshToken)
+	router.POST("/auth/logout", auth, authHandler.Logout)
+	router.POST("/auth/logout-all", auth, authHandler.LogoutAll)
+	router.POST("/auth/verify-email", authHandler.VerifyEmail)
+	router.POST("/auth/resend-verification", auth, authHandler.ResendVerificationEmail)
+	router.POST("/auth/forgot-password", authHandler.ForgotPassword)
+	router.POST("/auth/reset-password", authHandler.ResetPassword)
+	router.POST("/auth/firebase", authHandler.FirebaseLogin)
+	// Any other name is looked up in the OpenID Connect provider registry
+	router.POST("/auth/:provider", authHandler.ProviderLogin)
+
+	// Credential changes live under /users but are served by the auth handler
+	router.PUT("/users/password", auth, authHandler.ChangePassword)
+	router.GET("/users/identities", auth, authHandler.ListIdentities)
+	router.POST("/users/identities/:provider", auth, authHandler.LinkIdentity)
+	router.DELETE("/users/identities/:provider", auth, authHandler.UnlinkIdentity)
+	router.POST("/users/2fa/totp", auth, authHandler.EnrollTOTP)
+	router.POST("/users/2fa/totp/confirm", auth, authHandler.ConfirmTOTP)
+	router.POST("/users/2fa/recovery-codes", auth, authHandler.RegenerateRecoveryCodes)
+	router.DELETE("/users/2fa", auth, authHandler.DisableTwoFactor)
+	router.GET("/users/sessions", auth, authHandler.ListSessions)
+	router.DELETE("/users/sessions/:id", auth, authHandler.RevokeSession)
+	router.PUT("/users/fcm-token", auth, authHandler.StoreFCMToken)
 }
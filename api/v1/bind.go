@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON binds the request body into req and writes the error response
+// itself when it fails: field errors for failed binding tags, or a generic
+// message for malformed JSON. It reports whether the handler may continue.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	if fields, ok := validation.FromBindError(err); ok {
+		utils.ValidationErrorResponse(c, fields)
+		return false
+	}
+	utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+	return false
+}
+
+// validationFailed writes a field error response if err carries validation errors
+func validationFailed(c *gin.Context, err error) bool {
+	var fields validation.Errors
+	if !errors.As(err, &fields) {
+		return false
+	}
+	utils.ValidationErrorResponse(c, fields)
+	return true
+}
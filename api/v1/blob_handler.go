@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/pkg/blob"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBlobBytes bounds the size of an object uploaded to a signed URL
+const maxBlobBytes = 25 << 20
+
+// BlobHandler serves the signed URLs of a local blob store
+type BlobHandler struct {
+	Store  blob.Store
+	Signer *blob.URLSigner
+}
+
+// NewBlobHandler initializes BlobHandler
+func NewBlobHandler(store blob.Store, signer *blob.URLSigner) *BlobHandler {
+	return &BlobHandler{
+		Store:  store,
+		Signer: signer,
+	}
+}
+
+// GetBlob serves an object to holders of a signed download URL
+func (h *BlobHandler) GetBlob(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !h.verify(c, key, "") {
+		return
+	}
+
+	reader, object, err := h.Store.Get(c.Request.Context(), key)
+	if respondBlobError(c, err) {
+		return
+	}
+	defer reader.Close()
+
+	// The URL stays the same until it expires, so clients may cache it as long
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	maxAge := max(0, expires-time.Now().Unix())
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, reader, map[string]string{
+		"Cache-Control": fmt.Sprintf("private, max-age=%d", maxAge),
+	})
+}
+
+// PutBlob stores an object sent to a signed upload URL
+func (h *BlobHandler) PutBlob(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	contentType := c.ContentType()
+	if !h.verify(c, key, c.GetHeader("Content-Type")) {
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxBlobBytes)
+	err := h.Store.Put(c.Request.Context(), key, body, contentType)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Objects must be at most %d MB", maxBlobBytes>>20))
+		return
+	}
+	if respondBlobError(c, err) {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Object stored successfully", nil)
+}
+
+// verify checks the request's signature and writes the error response if it is not valid
+func (h *BlobHandler) verify(c *gin.Context, key, contentType string) bool {
+	err := h.Signer.Verify(c.Request.Method, key, c.Request.URL.Query(), contentType, time.Now())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// respondBlobError writes the response for store errors and reports whether there was one
+func respondBlobError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, blob.ErrNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, blob.ErrInvalidKey):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+	return true
+}
@@ -0,0 +1,12 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterBlobRoutes registers the routes behind the signed URLs of local
+// blob stores; the signature in the URL is the only credential
+func RegisterBlobRoutes(router *gin.RouterGroup, blobHandler *BlobHandler) {
+	router.GET("/blobs/*key", blobHandler.GetBlob)
+	router.PUT("/blobs/*key", blobHandler.PutBlob)
+}
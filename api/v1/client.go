@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers apps send to name the device a session belongs to
+const (
+	headerDeviceName     = "X-Device-Name"
+	headerDevicePlatform = "X-Device-Platform"
+)
+
+// maxClientField bounds client supplied values stored with a session
+const maxClientField = 256
+
+// clientInfo describes the device the request comes from. The platform is
+// guessed from the user agent when the app does not send it.
+func clientInfo(c *gin.Context) service.ClientInfo {
+	userAgent := truncate(c.Request.UserAgent())
+	platform := strings.ToLower(truncate(c.GetHeader(headerDevicePlatform)))
+	if platform == "" {
+		platform = platformFromUserAgent(userAgent)
+	}
+
+	return service.ClientInfo{
+		IP:         c.ClientIP(),
+		UserAgent:  userAgent,
+		DeviceName: truncate(c.GetHeader(headerDeviceName)),
+		Platform:   platform,
+	}
+}
+
+// platformFromUserAgent returns the operating system named by a user agent, if any
+func platformFromUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ios"), strings.Contains(ua, "cfnetwork"):
+		return "ios"
+	case strings.Contains(ua, "windows"):
+		return "windows"
+	case strings.Contains(ua, "mac os"), strings.Contains(ua, "macintosh"):
+		return "macos"
+	case strings.Contains(ua, "linux"):
+		return "linux"
+	}
+	return ""
+}
+
+// truncate trims value and cuts it to at most maxClientField bytes without
+// splitting a UTF-8 sequence
+func truncate(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) <= maxClientField {
+		return value
+	}
+	cut := maxClientField
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"short", "  Pixel 8  ", "Pixel 8"},
+		{"ascii over the limit", strings.Repeat("a", maxClientField+10), strings.Repeat("a", maxClientField)},
+		// é is two bytes; the limit falls in the middle of the last one
+		{"multibyte across the limit", "a" + strings.Repeat("é", maxClientField/2), "a" + strings.Repeat("é", maxClientField/2-1)},
+		{"four byte runes", strings.Repeat("🍞", maxClientField), strings.Repeat("🍞", maxClientField/4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.value)
+			if got != tt.want {
+				t.Fatalf("truncate() = %q (%d bytes), want %q (%d bytes)", got, len(got), tt.want, len(tt.want))
+			}
+			if !utf8.ValidString(got) {
+				t.Fatalf("truncate() = %q, not valid UTF-8", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys our tokens can be verified with
+type JWKSHandler struct {
+	Keys *utils.KeySet
+}
+
+// NewJWKSHandler initializes JWKSHandler
+func NewJWKSHandler(keys *utils.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		Keys: keys,
+	}
+}
+
+// GetJWKS serves the key set as a plain JWKS document, as verifiers expect it
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.Keys.JWKS())
+}
@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/Dffarhn/bakulenapi/pkg/imaging"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadHandler serves direct-to-storage uploads
+type UploadHandler struct {
+	UploadService *service.UploadService
+}
+
+// NewUploadHandler initializes UploadHandler
+func NewUploadHandler(uploadService *service.UploadService) *UploadHandler {
+	return &UploadHandler{
+		UploadService: uploadService,
+	}
+}
+
+// StartUpload returns a presigned URL the caller PUTs the file to, and the
+// upload ID to complete it with afterwards
+func (h *UploadHandler) StartUpload(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		Purpose     string `json:"purpose" binding:"required"`
+		ContentType string `json:"content_type" binding:"required"`
+		Size        int64  `json:"size" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ticket, err := h.UploadService.StartUpload(principal.UserID, req.Purpose, req.ContentType, req.Size)
+	if validationFailed(c, err) || respondImageError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Upload started successfully", ticket)
+}
+
+// CompleteUpload processes the file sent for an upload and attaches it
+func (h *UploadHandler) CompleteUpload(c *gin.Context) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	profile, err := h.UploadService.CompleteUpload(principal.UserID, c.Param("id"))
+	if respondImageError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, repository.ErrUploadNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, service.ErrUploadIncomplete), errors.Is(err, repository.ErrUploadClaimed):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, service.ErrUploadExpired):
+		utils.ErrorResponse(c, http.StatusGone, err.Error())
+		return
+	case errors.Is(err, service.ErrUnknownUploadPurpose):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Upload completed successfully", profile)
+}
+
+// respondImageError writes the response for errors shared by every image
+// upload path and reports whether there was one
+func respondImageError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrUploadsUnavailable):
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, service.ErrUploadTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, imaging.ErrUnsupportedType), errors.Is(err, imaging.ErrTooManyPixels):
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		return false
+	}
+	return true
+}
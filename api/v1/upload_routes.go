@@ -0,0 +1,13 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterUploadRoutes registers the direct-to-storage upload routes; auth is
+// the authentication middleware and verified guards routes that may require a
+// verified email
+func RegisterUploadRoutes(router *gin.RouterGroup, uploadHandler *UploadHandler, auth, verified gin.HandlerFunc) {
+	router.POST("/uploads", auth, verified, uploadHandler.StartUpload)
+	router.POST("/uploads/:id/complete", auth, verified, uploadHandler.CompleteUpload)
+}
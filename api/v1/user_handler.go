@@ -1,13 +1,15 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 
+	"github.com/Dffarhn/bakulenapi/internal/repository"
 	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
 	"github.com/Dffarhn/bakulenapi/pkg/utils"
-	_ "image/jpeg"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,82 +17,95 @@ type UserHandler struct {
 	UserService *service.UserService
 }
 
-func NewUserHandler() *UserHandler {
+// NewUserHandler initializes UserHandler
+func NewUserHandler(userService *service.UserService) *UserHandler {
 	return &UserHandler{
-		UserService: service.NewUserService(),
+		UserService: userService,
 	}
 }
 
 func (h *UserHandler) GetUser(c *gin.Context) {
-	// Retrieve the userId from the context
-	userID, exists := c.Get("userId")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "User ID not found in context")
+	// Retrieve the authenticated user from the context
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Convert userID to string (it may have been stored as an interface{})
-	userIDStr, ok := userID.(string)
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid User ID format")
+	// Now you can use the user ID to fetch the user data
+	profile, err := h.UserService.GetPrivateProfile(principal.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 		return
 	}
-
-	// Now you can use userIDStr to fetch the user data
-	user, err := h.UserService.GetUser(userIDStr)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", user)
+	utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", profile)
 }
 
-//update user
-func (h *UserHandler) UpdateUser(c *gin.Context) {
-	// Retrieve the userId from the context
-	userID, exists := c.Get("userId")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "User ID not found in context")
+// GetUserProfile returns the public profile of any user
+func (h *UserHandler) GetUserProfile(c *gin.Context) {
+	profile, err := h.UserService.GetPublicProfile(c.Param("id"))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 		return
 	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", profile)
+}
 
-	// Convert userID to string (it may have been stored as an interface{})
-	userIDStr, ok := userID.(string)
+//update user
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	// Retrieve the authenticated user from the context
+	principal, ok := middleware.GetPrincipal(c)
 	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid User ID format")
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	// Prepare a map to hold the fields to update
 	data := make(map[string]interface{})
 
-	// Check if username is provided
-	if name := c.PostForm("name"); name != "" {
-		data["name"] = name
+	// Text fields that are sent are set, even when empty so they can be cleared
+	for _, field := range []string{"name", "bio", "phone", "location"} {
+		if value, ok := c.GetPostForm(field); ok {
+			data[field] = value
+		}
 	}
 
-	// Check if profile picture is provided
-	file, _, err := c.Request.FormFile("profile_picture")
+	// Check if profile picture is provided; large pictures are better sent
+	// straight to storage through POST /v1/uploads
+	var picture []byte
+	file, header, err := c.Request.FormFile("profile_picture")
 	if err == nil {
-		// Convert and upload the image if a profile picture is provided
-		fileBytes, err := io.ReadAll(file)
-		if err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("Error reading file: %v", err))
+		if header.Size > service.MaxImageUploadBytes {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, service.ErrUploadTooLarge.Error())
 			return
 		}
 
-		// Upload the webp image
-		imageURL, err := utils.UploadImage(fmt.Sprintf("%s.webp", utils.GenerateUniqueFilename("user")), fileBytes)
+		picture, err = io.ReadAll(file)
 		if err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("Error uploading image: %v", err))
+			utils.ErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("Error reading file: %v", err))
 			return
 		}
-		data["profile_picture"] = imageURL
 	}
 
-	// Call the service to update user fields
-	err = h.UserService.UpdateUser(userIDStr, data)
+	// Call the service to update user fields; the picture is only stored once they are valid
+	err = h.UserService.UpdateUser(principal.UserID, data, picture)
+	if validationFailed(c, err) || respondImageError(c, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrUserNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("Error updating user: %v", err))
 		return
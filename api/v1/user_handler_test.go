@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// tokenAuthenticator accepts the tokens in principals
+type tokenAuthenticator struct {
+	principals map[string]*utils.Principal
+}
+
+func (a tokenAuthenticator) Authenticate(ctx context.Context, token string) (*utils.Principal, error) {
+	principal, ok := a.principals[token]
+	if !ok {
+		return nil, utils.ErrInvalidToken
+	}
+	return principal, nil
+}
+
+func TestUserHandlerProfileViews(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	alice := &models.User{
+		ID:            "u1",
+		Email:         "alice@example.com",
+		Username:      "alice",
+		Password:      "$2a$10$hash",
+		FCMToken:      "legacy-fcm",
+		EmailVerified: true,
+		Roles:         []string{models.RoleUser},
+		Identities:    []models.Identity{{Provider: models.ProviderPassword, Email: "alice@example.com"}},
+		TwoFactor:     &models.TwoFactor{Enabled: true, Secret: "JBSWY3DPEHPK3PXP", RecoveryCodes: []string{"hash"}},
+		Name:          "Alice",
+		Bio:           "Sells sourdough",
+		Phone:         "+628123456789",
+		PhoneVerified: true,
+		CreatedAt:     time.Now(),
+	}
+	if err := users.Create(ctx, alice); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	h := NewUserHandler(service.NewUserService(users, nil, nil, nil))
+	auth := middleware.AuthMiddleware(tokenAuthenticator{principals: map[string]*utils.Principal{
+		"alice-token": {UserID: alice.ID},
+	}})
+	router := gin.New()
+	RegisterUserRoutes(router.Group(""), h, auth, func(c *gin.Context) { c.Next() })
+
+	// get returns the top level keys of the response data
+	get := func(target, token string) map[string]json.RawMessage {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", target, rec.Code, rec.Body)
+		}
+		var resp struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decoding %s = %v", rec.Body, err)
+		}
+		return resp.Data
+	}
+
+	// Secrets and device tokens are in neither view
+	hidden := []string{"password", "fcm_token", "fcmToken", "secret", "recovery_codes"}
+
+	public := get("/users/"+alice.ID, "")
+	for _, key := range append(hidden, "email", "phone", "identities", "two_factor", "roles", "email_verified", "phone_verified") {
+		if _, ok := public[key]; ok {
+			t.Errorf("public profile has %q: %s", key, public[key])
+		}
+	}
+	for _, key := range []string{"id", "username", "name", "bio", "badges", "joined_at"} {
+		if _, ok := public[key]; !ok {
+			t.Errorf("public profile lacks %q", key)
+		}
+	}
+
+	private := get("/users", "alice-token")
+	for _, key := range []string{"id", "username", "email", "phone", "identities", "two_factor", "roles"} {
+		if _, ok := private[key]; !ok {
+			t.Errorf("private profile lacks %q", key)
+		}
+	}
+	for _, key := range hidden {
+		if _, ok := private[key]; ok {
+			t.Errorf("private profile has %q: %s", key, private[key])
+		}
+	}
+	var twoFactor map[string]json.RawMessage
+	if err := json.Unmarshal(private["two_factor"], &twoFactor); err != nil {
+		t.Fatalf("decoding two_factor = %v", err)
+	}
+	for _, key := range []string{"secret", "Secret", "last_step", "LastStep", "recovery_codes", "RecoveryCodes"} {
+		if _, ok := twoFactor[key]; ok {
+			t.Errorf("private two_factor has %q", key)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /users/unknown = %d, want 404", rec.Code)
+	}
+}
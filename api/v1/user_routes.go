@@ -1,12 +1,16 @@
 package v1
 
 import (
-	"github.com/Dffarhn/bakulenapi/pkg/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterUserRoutes(router *gin.RouterGroup, userHandler *UserHandler) {
+// RegisterUserRoutes registers the user routes; auth is the authentication
+// middleware and verified guards routes that may require a verified email
+func RegisterUserRoutes(router *gin.RouterGroup, userHandler *UserHandler, auth, verified gin.HandlerFunc) {
 	// Register user routes
-	router.GET("/users", middleware.AuthMiddleware() ,userHandler.GetUser)
-	router.PUT("/users", middleware.AuthMiddleware() ,userHandler.UpdateUser)
+	router.GET("/users", auth, userHandler.GetUser)
+	router.PUT("/users", auth, verified, userHandler.UpdateUser)
+	// Public profiles need no authentication; /users/* routes registered
+	// elsewhere take precedence over the id
+	router.GET("/users/:id", userHandler.GetUserProfile)
 }
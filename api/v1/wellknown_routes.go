@@ -0,0 +1,10 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWellKnownRoutes registers the /.well-known routes; router should be the root group
+func RegisterWellKnownRoutes(router *gin.RouterGroup, jwksHandler *JWKSHandler) {
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+}
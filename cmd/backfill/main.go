@@ -0,0 +1,44 @@
+// Command backfill prepares users stored before emails were normalized and
+// reserved, see FirestoreUserRepository.BackfillReservations. Run it once with
+// the API's configuration after deploying unique emails and usernames.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables
+	godotenv.Load()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Backend != config.BackendFirestore {
+		log.Fatalf("Nothing to backfill for the %q backend", cfg.Backend)
+	}
+
+	ctx := context.Background()
+	fb, err := config.NewFirebase(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize Firebase: %v", err)
+	}
+	defer fb.Close()
+
+	users := repository.NewFirestoreUserRepository(fb.Firestore)
+	report, err := users.BackfillReservations(ctx)
+	if err != nil {
+		log.Fatalf("Backfill stopped after %d users: %v", report.Users, err)
+	}
+
+	log.Printf("Checked %d users: normalized %d emails, created %d reservations", report.Users, report.Normalized, report.Reserved)
+	for _, conflict := range report.Conflicts {
+		log.Printf("Conflict: %s", conflict)
+	}
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
-	"os"
 
-	v1 "github.com/Dffarhn/bakulenapi/api/v1"
 	"github.com/Dffarhn/bakulenapi/config"
-	"github.com/gin-gonic/gin"
+	"github.com/Dffarhn/bakulenapi/internal/app"
 	"github.com/joho/godotenv"
 )
 
@@ -14,27 +13,22 @@ func main() {
 	// Load environment variables
 	godotenv.Load()
 
-	// Initialize Firebase
-	config.InitFirebase()
-
-	// Setup Gin router
-	router := gin.Default()
-// Create an instance of AuthHandler
-	authHandler := v1.NewAuthHandler()
-	userHandler := v1.NewUserHandler()
+	// Load and validate configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
-	// Register the routes
-	v1Routes := router.Group("/v1")
-	{
-		v1.RegisterAuthRoutes(v1Routes, authHandler)
-		v1.RegisterUserRoutes(v1Routes, userHandler)
+	// Build the application
+	application, err := app.New(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %v", err)
 	}
+	defer application.Close()
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	log.Println("Server running on port", cfg.Port)
+	if err := application.Run(":" + cfg.Port); err != nil {
+		log.Fatalf("Server stopped: %v", err)
 	}
-	log.Println("Server running on port", port)
-	router.Run(":" + port)
 }
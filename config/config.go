@@ -0,0 +1,826 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Storage backends supported by the application
+const (
+	BackendFirestore = "firestore"
+	BackendMemory    = "memory"
+)
+
+// Config holds every setting the API needs at startup
+type Config struct {
+	Port    string `yaml:"port"`
+	Backend string `yaml:"backend"`
+	// TrustedProxies lists the proxies whose X-Forwarded-For header is believed
+	// when determining client IPs; none are trusted by default
+	TrustedProxies []string        `yaml:"trusted_proxies"`
+	Firebase       FirebaseConfig  `yaml:"firebase"`
+	Storage        StorageConfig   `yaml:"storage"`
+	Google         GoogleConfig    `yaml:"google"`
+	OIDC           OIDCConfig      `yaml:"oidc"`
+	JWT            JWTConfig       `yaml:"jwt"`
+	Auth           AuthConfig      `yaml:"auth"`
+	Password       PasswordConfig  `yaml:"password"`
+	Login          LoginConfig     `yaml:"login"`
+	MFA            MFAConfig       `yaml:"mfa"`
+	RateLimit      RateLimitConfig `yaml:"rate_limit"`
+	Mail           MailConfig      `yaml:"mail"`
+}
+
+// FirebaseConfig locates the Firebase project used for Firestore and Auth
+type FirebaseConfig struct {
+	CredentialsFile string `yaml:"credentials_file"`
+	// AcceptIDTokens lets authenticated routes accept Firebase Auth ID tokens
+	// as bearer tokens besides our own access tokens. Each request checks with
+	// Firebase that the token was not revoked.
+	AcceptIDTokens bool `yaml:"accept_id_tokens"`
+}
+
+// Blob stores uploads can be kept in
+const (
+	StorageDriverGCS    = "gcs"
+	StorageDriverDisk   = "disk"
+	StorageDriverMemory = "memory"
+)
+
+// How stored images are turned into URLs for clients
+const (
+	StorageURLsSigned = "signed"
+	StorageURLsPublic = "public"
+)
+
+// StorageConfig locates the blob store used for uploaded images
+type StorageConfig struct {
+	// Driver is "gcs" for the Cloud Storage bucket, "disk" for files under Dir
+	// or "memory". The local drivers serve their signed URLs themselves.
+	Driver          string `yaml:"driver"`
+	CredentialsFile string `yaml:"credentials_file"`
+	Bucket          string `yaml:"bucket"`
+	GoogleAccessID  string `yaml:"google_access_id"`
+	// URLs is "signed" to mint signed URLs when images are read, or "public"
+	// for buckets served publicly or through a CDN
+	URLs string `yaml:"urls"`
+	// SignedURLTTL is how long a signed URL stays valid; URLs are reused for
+	// half of it
+	SignedURLTTL time.Duration `yaml:"signed_url_ttl"`
+	// PublicBaseURL is where objects are served from with public URLs,
+	// https://storage.googleapis.com/<bucket> by default
+	PublicBaseURL string `yaml:"public_base_url"`
+	// Dir holds the files of the disk driver
+	Dir string `yaml:"dir"`
+	// SigningKey is the HMAC secret of the local drivers' signed URLs; without
+	// it a random key is used and URLs stop working on restart
+	SigningKey string `yaml:"signing_key"`
+	// LocalBaseURL is the address of the API's /v1/blobs route the local
+	// drivers' signed URLs point at
+	LocalBaseURL string `yaml:"local_base_url"`
+	// UploadURLTTL is how long clients have to send a file to the presigned
+	// URL of POST /v1/uploads and complete the upload
+	UploadURLTTL time.Duration `yaml:"upload_url_ttl"`
+}
+
+// GoogleConfig holds the Google sign-in settings
+type GoogleConfig struct {
+	// ClientID is the OAuth client ID Google ID tokens must be issued for
+	ClientID string `yaml:"client_id"`
+}
+
+// OIDCConfig lists the OpenID Connect providers users can sign in with
+// besides Google, which is configured by GoogleConfig
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig `yaml:"providers"`
+	// FakeIssuer serves a local issuer under /dev/oidc and registers it as the
+	// "fake" provider; only allowed with the memory backend
+	FakeIssuer bool `yaml:"fake_issuer"`
+}
+
+// OIDCProviderConfig configures one provider. The names google, apple and
+// facebook have built-in issuer and key settings, so only client IDs are needed.
+type OIDCProviderConfig struct {
+	Name      string   `yaml:"name"`
+	Issuer    string   `yaml:"issuer"`
+	ClientIDs []string `yaml:"client_ids"`
+	// DiscoveryURL defaults to <issuer>/.well-known/openid-configuration
+	DiscoveryURL string `yaml:"discovery_url"`
+	// JWKSURL skips discovery when set
+	JWKSURL string `yaml:"jwks_url"`
+}
+
+// JWTConfig holds the settings for the tokens we issue
+type JWTConfig struct {
+	// Keys lists every key accepted for verification; retired keys may be public keys only
+	Keys []SigningKeyConfig `yaml:"keys"`
+	// ActiveKeyID selects the key new tokens are signed with; defaults to the first key
+	ActiveKeyID string `yaml:"active_key_id"`
+	// Issuer and Audience are written to and required in every access token
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// AccessTTL is the lifetime of access tokens; keep it short
+	AccessTTL time.Duration `yaml:"access_ttl"`
+	// RefreshTTL is the lifetime of refresh tokens, renewed on every rotation
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+// AuthConfig holds the account lifecycle settings
+type AuthConfig struct {
+	// EmailVerificationURL is the page linked from verification emails; the token is appended as ?token=
+	EmailVerificationURL string        `yaml:"email_verification_url"`
+	EmailVerificationTTL time.Duration `yaml:"email_verification_ttl"`
+	// PasswordResetURL is the page linked from password reset emails; the token is appended as ?token=
+	PasswordResetURL string        `yaml:"password_reset_url"`
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl"`
+	// RequireVerifiedEmail blocks unverified users from routes that demand a verified email
+	RequireVerifiedEmail bool `yaml:"require_verified_email"`
+	// LinkPolicy decides what a first provider sign-in onto an existing account with the same email needs
+	LinkPolicy string `yaml:"link_policy"`
+}
+
+// Account link policies supported by the application
+const (
+	// LinkPolicyVerifiedEmail links automatically when both the provider and the
+	// account verified the email, and asks for the password otherwise
+	LinkPolicyVerifiedEmail = "verified_email"
+	// LinkPolicyPassword always asks for the account password
+	LinkPolicyPassword = "password"
+)
+
+// PasswordConfig is the policy new passwords must satisfy
+type PasswordConfig struct {
+	MinLength int `yaml:"min_length"`
+	MaxLength int `yaml:"max_length"`
+	// MinCharClasses is how many of lowercase, uppercase, digits and symbols a password must mix
+	MinCharClasses   int  `yaml:"min_char_classes"`
+	RequireLowercase bool `yaml:"require_lowercase"`
+	RequireUppercase bool `yaml:"require_uppercase"`
+	RequireDigit     bool `yaml:"require_digit"`
+	RequireSymbol    bool `yaml:"require_symbol"`
+	// BreachedFile lists known breached passwords, plain or as SHA-1 hex, one per line
+	BreachedFile string `yaml:"breached_file"`
+}
+
+// LoginConfig controls how repeated failed sign-ins are slowed down and locked out
+type LoginConfig struct {
+	// FailureWindow is how long a failure is remembered after the last one
+	FailureWindow time.Duration `yaml:"failure_window"`
+	// FreeAttempts failures per account are allowed before delays start
+	FreeAttempts int `yaml:"free_attempts"`
+	// LockoutThreshold failures lock the account out for LockoutDuration
+	LockoutThreshold int `yaml:"lockout_threshold"`
+	// IPFreeAttempts and IPLockoutThreshold apply per client IP
+	IPFreeAttempts     int `yaml:"ip_free_attempts"`
+	IPLockoutThreshold int `yaml:"ip_lockout_threshold"`
+	// BaseDelay doubles with every failure past the free attempts, up to MaxDelay
+	BaseDelay       time.Duration `yaml:"base_delay"`
+	MaxDelay        time.Duration `yaml:"max_delay"`
+	LockoutDuration time.Duration `yaml:"lockout_duration"`
+}
+
+// MFAConfig holds the two-factor authentication settings
+type MFAConfig struct {
+	// Issuer names the service in authenticator apps
+	Issuer string `yaml:"issuer"`
+	// ChallengeTTL is how long a sign-in waits for its second factor
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"`
+}
+
+// RateLimitConfig limits request rates per route group with token buckets
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Store keeps the buckets: "memory" limits each instance on its own,
+	// "firestore" shares the limits between instances
+	Store string        `yaml:"store"`
+	Auth  RateLimitRule `yaml:"auth"`
+	Users RateLimitRule `yaml:"users"`
+	Admin RateLimitRule `yaml:"admin"`
+}
+
+// RateLimitRule is the token bucket of one route group
+type RateLimitRule struct {
+	// Requests per Period are allowed on average
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	// Burst is how many requests may arrive at once; zero means Requests
+	Burst int `yaml:"burst"`
+	// Key is what gets its own bucket: "ip", "user" or "route"
+	Key string `yaml:"key"`
+}
+
+// Rate limit stores supported by the application
+const (
+	RateLimitStoreMemory    = "memory"
+	RateLimitStoreFirestore = "firestore"
+)
+
+// Rate limit keys supported by the application
+const (
+	RateLimitKeyIP    = "ip"
+	RateLimitKeyUser  = "user"
+	RateLimitKeyRoute = "route"
+)
+
+// rateLimitGroup pairs a route group's rule with its environment variable prefix
+type rateLimitGroup struct {
+	prefix string
+	rule   *RateLimitRule
+}
+
+func (c *RateLimitConfig) groups() []rateLimitGroup {
+	return []rateLimitGroup{
+		{"RATE_LIMIT_AUTH_", &c.Auth},
+		{"RATE_LIMIT_USERS_", &c.Users},
+		{"RATE_LIMIT_ADMIN_", &c.Admin},
+	}
+}
+
+// Mail drivers supported by the application
+const (
+	MailDriverConsole = "console"
+	MailDriverFile    = "file"
+	MailDriverSMTP    = "smtp"
+)
+
+// MailConfig selects and configures how emails are delivered
+type MailConfig struct {
+	Driver string     `yaml:"driver"`
+	From   string     `yaml:"from"`
+	Dir    string     `yaml:"dir"` // Output directory of the file driver
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig holds the settings of the smtp mail driver
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// SigningKeyConfig points at a PEM encoded RSA or Ed25519 key
+type SigningKeyConfig struct {
+	ID   string `yaml:"id"`
+	File string `yaml:"file"`
+}
+
+// Default returns a Config populated with the defaults for optional settings
+func Default() *Config {
+	return &Config{
+		Port:    "8080",
+		Backend: BackendFirestore,
+		Storage: StorageConfig{
+			Driver:       StorageDriverGCS,
+			URLs:         StorageURLsSigned,
+			SignedURLTTL: 24 * time.Hour,
+			Dir:          "data/blobs",
+			LocalBaseURL: "http://localhost:8080/v1/blobs",
+			UploadURLTTL: 15 * time.Minute,
+		},
+		JWT: JWTConfig{
+			Issuer:     "bakulenapi",
+			Audience:   "bakulen",
+			AccessTTL:  15 * time.Minute,
+			RefreshTTL: 30 * 24 * time.Hour,
+		},
+		Auth: AuthConfig{
+			EmailVerificationURL: "http://localhost:8080/verify-email",
+			EmailVerificationTTL: 24 * time.Hour,
+			PasswordResetURL:     "http://localhost:8080/reset-password",
+			PasswordResetTTL:     time.Hour,
+			LinkPolicy:           LinkPolicyVerifiedEmail,
+		},
+		Password: PasswordConfig{
+			MinLength:      8,
+			MaxLength:      72,
+			MinCharClasses: 2,
+		},
+		Login: LoginConfig{
+			FailureWindow:      time.Hour,
+			FreeAttempts:       5,
+			LockoutThreshold:   10,
+			IPFreeAttempts:     20,
+			IPLockoutThreshold: 100,
+			BaseDelay:          time.Second,
+			MaxDelay:           5 * time.Minute,
+			LockoutDuration:    15 * time.Minute,
+		},
+		MFA: MFAConfig{
+			Issuer:       "Bakulen",
+			ChallengeTTL: 5 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   RateLimitStoreMemory,
+			Auth:    RateLimitRule{Requests: 20, Period: time.Minute, Burst: 10, Key: RateLimitKeyIP},
+			Users:   RateLimitRule{Requests: 120, Period: time.Minute, Burst: 30, Key: RateLimitKeyUser},
+			Admin:   RateLimitRule{Requests: 60, Period: time.Minute, Burst: 20, Key: RateLimitKeyUser},
+		},
+		Mail: MailConfig{
+			Driver: MailDriverConsole,
+			From:   "Bakulen <no-reply@bakulen.app>",
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+	}
+}
+
+// Load reads the optional YAML file named by CONFIG_FILE, applies environment
+// variable overrides on top of it and validates the result
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile merges the YAML file at path into the config
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides config values with any environment variables that are set
+func (c *Config) loadEnv() error {
+	setString(&c.Port, "PORT")
+	setString(&c.Backend, "BACKEND")
+	setStringList(&c.TrustedProxies, "TRUSTED_PROXIES")
+	setString(&c.Firebase.CredentialsFile, "FIREBASE_CREDENTIALS_FILE")
+	if err := setBool(&c.Firebase.AcceptIDTokens, "FIREBASE_ACCEPT_ID_TOKENS"); err != nil {
+		return err
+	}
+	setString(&c.Storage.Driver, "STORAGE_DRIVER")
+	setString(&c.Storage.CredentialsFile, "STORAGE_CREDENTIALS_FILE")
+	setString(&c.Storage.Bucket, "STORAGE_BUCKET")
+	setString(&c.Storage.GoogleAccessID, "STORAGE_GOOGLE_ACCESS_ID")
+	setString(&c.Storage.URLs, "STORAGE_URLS")
+	if err := setDuration(&c.Storage.SignedURLTTL, "STORAGE_SIGNED_URL_TTL"); err != nil {
+		return err
+	}
+	setString(&c.Storage.PublicBaseURL, "STORAGE_PUBLIC_BASE_URL")
+	setString(&c.Storage.Dir, "STORAGE_DIR")
+	setString(&c.Storage.SigningKey, "STORAGE_SIGNING_KEY")
+	setString(&c.Storage.LocalBaseURL, "STORAGE_LOCAL_BASE_URL")
+	if err := setDuration(&c.Storage.UploadURLTTL, "STORAGE_UPLOAD_URL_TTL"); err != nil {
+		return err
+	}
+	setString(&c.Google.ClientID, "GOOGLE_CLIENT_ID")
+	setOIDCProviders(&c.OIDC.Providers, "OIDC_PROVIDERS")
+	if err := setBool(&c.OIDC.FakeIssuer, "OIDC_FAKE_ISSUER"); err != nil {
+		return err
+	}
+	setString(&c.JWT.ActiveKeyID, "JWT_ACTIVE_KEY_ID")
+	setString(&c.JWT.Issuer, "JWT_ISSUER")
+	setString(&c.JWT.Audience, "JWT_AUDIENCE")
+	if err := setSigningKeys(&c.JWT.Keys, "JWT_KEYS"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.JWT.AccessTTL, "JWT_ACCESS_TTL"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.JWT.RefreshTTL, "JWT_REFRESH_TTL"); err != nil {
+		return err
+	}
+
+	setString(&c.Auth.EmailVerificationURL, "EMAIL_VERIFICATION_URL")
+	if err := setDuration(&c.Auth.EmailVerificationTTL, "EMAIL_VERIFICATION_TTL"); err != nil {
+		return err
+	}
+	setString(&c.Auth.PasswordResetURL, "PASSWORD_RESET_URL")
+	if err := setDuration(&c.Auth.PasswordResetTTL, "PASSWORD_RESET_TTL"); err != nil {
+		return err
+	}
+	if err := setBool(&c.Auth.RequireVerifiedEmail, "REQUIRE_VERIFIED_EMAIL"); err != nil {
+		return err
+	}
+	setString(&c.Auth.LinkPolicy, "ACCOUNT_LINK_POLICY")
+
+	if err := setInt(&c.Password.MinLength, "PASSWORD_MIN_LENGTH"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Password.MaxLength, "PASSWORD_MAX_LENGTH"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Password.MinCharClasses, "PASSWORD_MIN_CHAR_CLASSES"); err != nil {
+		return err
+	}
+	if err := setBool(&c.Password.RequireLowercase, "PASSWORD_REQUIRE_LOWERCASE"); err != nil {
+		return err
+	}
+	if err := setBool(&c.Password.RequireUppercase, "PASSWORD_REQUIRE_UPPERCASE"); err != nil {
+		return err
+	}
+	if err := setBool(&c.Password.RequireDigit, "PASSWORD_REQUIRE_DIGIT"); err != nil {
+		return err
+	}
+	if err := setBool(&c.Password.RequireSymbol, "PASSWORD_REQUIRE_SYMBOL"); err != nil {
+		return err
+	}
+	setString(&c.Password.BreachedFile, "BREACHED_PASSWORDS_FILE")
+
+	setString(&c.MFA.Issuer, "MFA_ISSUER")
+	if err := setDuration(&c.MFA.ChallengeTTL, "MFA_CHALLENGE_TTL"); err != nil {
+		return err
+	}
+
+	if err := setBool(&c.RateLimit.Enabled, "RATE_LIMIT_ENABLED"); err != nil {
+		return err
+	}
+	setString(&c.RateLimit.Store, "RATE_LIMIT_STORE")
+	for _, group := range c.RateLimit.groups() {
+		prefix, rule := group.prefix, group.rule
+		if err := setInt(&rule.Requests, prefix+"REQUESTS"); err != nil {
+			return err
+		}
+		if err := setDuration(&rule.Period, prefix+"PERIOD"); err != nil {
+			return err
+		}
+		if err := setInt(&rule.Burst, prefix+"BURST"); err != nil {
+			return err
+		}
+		setString(&rule.Key, prefix+"KEY")
+	}
+
+	for env, target := range map[string]*time.Duration{
+		"LOGIN_FAILURE_WINDOW":   &c.Login.FailureWindow,
+		"LOGIN_BASE_DELAY":       &c.Login.BaseDelay,
+		"LOGIN_MAX_DELAY":        &c.Login.MaxDelay,
+		"LOGIN_LOCKOUT_DURATION": &c.Login.LockoutDuration,
+	} {
+		if err := setDuration(target, env); err != nil {
+			return err
+		}
+	}
+	for env, target := range map[string]*int{
+		"LOGIN_FREE_ATTEMPTS":        &c.Login.FreeAttempts,
+		"LOGIN_LOCKOUT_THRESHOLD":    &c.Login.LockoutThreshold,
+		"LOGIN_IP_FREE_ATTEMPTS":     &c.Login.IPFreeAttempts,
+		"LOGIN_IP_LOCKOUT_THRESHOLD": &c.Login.IPLockoutThreshold,
+	} {
+		if err := setInt(target, env); err != nil {
+			return err
+		}
+	}
+
+	setString(&c.Mail.Driver, "MAIL_DRIVER")
+	setString(&c.Mail.From, "MAIL_FROM")
+	setString(&c.Mail.Dir, "MAIL_DIR")
+	setString(&c.Mail.SMTP.Host, "SMTP_HOST")
+	setString(&c.Mail.SMTP.Username, "SMTP_USERNAME")
+	setString(&c.Mail.SMTP.Password, "SMTP_PASSWORD")
+	return setInt(&c.Mail.SMTP.Port, "SMTP_PORT")
+}
+
+// Validate reports every missing or invalid setting at once
+func (c *Config) Validate() error {
+	var problems []string
+	require := func(value, env, key string) {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("%s (%s) is required", env, key))
+		}
+	}
+
+	require(c.Port, "PORT", "port")
+	if len(c.JWT.Keys) > 0 {
+		active := c.JWT.ActiveSigningKeyID()
+		found := false
+		for i, key := range c.JWT.Keys {
+			require(key.ID, "JWT_KEYS", fmt.Sprintf("jwt.keys[%d].id", i))
+			require(key.File, "JWT_KEYS", fmt.Sprintf("jwt.keys[%d].file", i))
+			found = found || key.ID == active
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("JWT_ACTIVE_KEY_ID (jwt.active_key_id) %q does not match any key in jwt.keys", active))
+		}
+	}
+	require(c.JWT.Issuer, "JWT_ISSUER", "jwt.issuer")
+	require(c.JWT.Audience, "JWT_AUDIENCE", "jwt.audience")
+	if c.JWT.AccessTTL <= 0 {
+		problems = append(problems, "JWT_ACCESS_TTL (jwt.access_ttl) must be positive")
+	}
+	if c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		problems = append(problems, "JWT_REFRESH_TTL (jwt.refresh_ttl) must be longer than jwt.access_ttl")
+	}
+
+	require(c.Auth.EmailVerificationURL, "EMAIL_VERIFICATION_URL", "auth.email_verification_url")
+	if c.Auth.EmailVerificationTTL <= 0 {
+		problems = append(problems, "EMAIL_VERIFICATION_TTL (auth.email_verification_ttl) must be positive")
+	}
+	require(c.Auth.PasswordResetURL, "PASSWORD_RESET_URL", "auth.password_reset_url")
+	if c.Auth.PasswordResetTTL <= 0 {
+		problems = append(problems, "PASSWORD_RESET_TTL (auth.password_reset_ttl) must be positive")
+	}
+	if c.Auth.LinkPolicy != LinkPolicyVerifiedEmail && c.Auth.LinkPolicy != LinkPolicyPassword {
+		problems = append(problems, fmt.Sprintf("ACCOUNT_LINK_POLICY (auth.link_policy) must be %q or %q, got %q", LinkPolicyVerifiedEmail, LinkPolicyPassword, c.Auth.LinkPolicy))
+	}
+
+	if c.Password.MinLength < 1 {
+		problems = append(problems, "PASSWORD_MIN_LENGTH (password.min_length) must be positive")
+	}
+	if c.Password.MaxLength < c.Password.MinLength || c.Password.MaxLength > 72 {
+		problems = append(problems, "PASSWORD_MAX_LENGTH (password.max_length) must be between password.min_length and 72")
+	}
+	if c.Password.MinCharClasses < 0 || c.Password.MinCharClasses > 4 {
+		problems = append(problems, "PASSWORD_MIN_CHAR_CLASSES (password.min_char_classes) must be between 0 and 4")
+	}
+
+	if c.Login.FailureWindow <= 0 {
+		problems = append(problems, "LOGIN_FAILURE_WINDOW (login.failure_window) must be positive")
+	}
+	if c.Login.FreeAttempts < 1 || c.Login.IPFreeAttempts < 1 {
+		problems = append(problems, "LOGIN_FREE_ATTEMPTS and LOGIN_IP_FREE_ATTEMPTS (login.free_attempts, login.ip_free_attempts) must be at least 1")
+	}
+	if c.Login.LockoutThreshold < c.Login.FreeAttempts || c.Login.IPLockoutThreshold < c.Login.IPFreeAttempts {
+		problems = append(problems, "LOGIN_LOCKOUT_THRESHOLD and LOGIN_IP_LOCKOUT_THRESHOLD must not be below their free attempts")
+	}
+	if c.Login.BaseDelay <= 0 || c.Login.MaxDelay < c.Login.BaseDelay || c.Login.LockoutDuration <= 0 {
+		problems = append(problems, "LOGIN_BASE_DELAY, LOGIN_MAX_DELAY and LOGIN_LOCKOUT_DURATION must be positive, with the max delay at least the base delay")
+	}
+
+	require(c.MFA.Issuer, "MFA_ISSUER", "mfa.issuer")
+	if c.MFA.ChallengeTTL <= 0 {
+		problems = append(problems, "MFA_CHALLENGE_TTL (mfa.challenge_ttl) must be positive")
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case RateLimitStoreMemory:
+		case RateLimitStoreFirestore:
+			if c.Backend != BackendFirestore {
+				problems = append(problems, "RATE_LIMIT_STORE (rate_limit.store) firestore requires the firestore backend")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("RATE_LIMIT_STORE (rate_limit.store) must be %q or %q, got %q", RateLimitStoreMemory, RateLimitStoreFirestore, c.RateLimit.Store))
+		}
+		for _, group := range c.RateLimit.groups() {
+			prefix, rule := group.prefix, group.rule
+			if rule.Requests < 1 || rule.Period <= 0 || rule.Burst < 0 {
+				problems = append(problems, fmt.Sprintf("%sREQUESTS and %sPERIOD must be positive and %sBURST not negative", prefix, prefix, prefix))
+			}
+			switch rule.Key {
+			case RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyRoute:
+			default:
+				problems = append(problems, fmt.Sprintf("%sKEY must be %q, %q or %q, got %q", prefix, RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyRoute, rule.Key))
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	if c.Google.ClientID != "" {
+		seen["google"] = true
+	}
+	if c.OIDC.FakeIssuer {
+		seen["fake"] = true
+	}
+	for i, provider := range c.OIDC.Providers {
+		key := fmt.Sprintf("oidc.providers[%d]", i)
+		env := oidcEnvPrefix(provider.Name)
+		switch {
+		case !providerName.MatchString(provider.Name):
+			problems = append(problems, fmt.Sprintf("OIDC_PROVIDERS (%s.name) %q must be lowercase letters, digits and dashes", key, provider.Name))
+		case reservedProviderNames[provider.Name]:
+			problems = append(problems, fmt.Sprintf("OIDC_PROVIDERS (%s.name) %q is reserved", key, provider.Name))
+		case seen[provider.Name]:
+			problems = append(problems, fmt.Sprintf("OIDC_PROVIDERS (%s.name) %q is configured twice", key, provider.Name))
+		}
+		seen[provider.Name] = true
+		if !builtinProviders[provider.Name] {
+			require(provider.Issuer, env+"ISSUER", key+".issuer")
+		}
+		if len(provider.ClientIDs) == 0 {
+			problems = append(problems, fmt.Sprintf("%sCLIENT_IDS (%s.client_ids) is required", env, key))
+		}
+	}
+	if c.Firebase.AcceptIDTokens && c.Backend != BackendFirestore && !c.OIDC.FakeIssuer {
+		problems = append(problems, "FIREBASE_ACCEPT_ID_TOKENS (firebase.accept_id_tokens) needs the firestore backend or OIDC_FAKE_ISSUER")
+	}
+	if c.OIDC.FakeIssuer && c.Backend != BackendMemory {
+		problems = append(problems, "OIDC_FAKE_ISSUER (oidc.fake_issuer) is only allowed with the memory backend")
+	}
+
+	switch c.Storage.Driver {
+	case StorageDriverGCS:
+	case StorageDriverDisk:
+		require(c.Storage.Dir, "STORAGE_DIR", "storage.dir")
+		require(c.Storage.LocalBaseURL, "STORAGE_LOCAL_BASE_URL", "storage.local_base_url")
+	case StorageDriverMemory:
+		require(c.Storage.LocalBaseURL, "STORAGE_LOCAL_BASE_URL", "storage.local_base_url")
+	default:
+		problems = append(problems, fmt.Sprintf("STORAGE_DRIVER (storage.driver) must be %q, %q or %q, got %q", StorageDriverGCS, StorageDriverDisk, StorageDriverMemory, c.Storage.Driver))
+	}
+	switch c.Storage.URLs {
+	case StorageURLsSigned:
+		// Signed URLs can be valid for at most 7 days
+		if c.Storage.SignedURLTTL <= 0 || c.Storage.SignedURLTTL > 7*24*time.Hour {
+			problems = append(problems, "STORAGE_SIGNED_URL_TTL (storage.signed_url_ttl) must be positive and at most 168h")
+		}
+	case StorageURLsPublic:
+		// Only Cloud Storage buckets have a default public address
+		if c.Storage.Driver != StorageDriverGCS {
+			require(c.Storage.PublicBaseURL, "STORAGE_PUBLIC_BASE_URL", "storage.public_base_url")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("STORAGE_URLS (storage.urls) must be %q or %q, got %q", StorageURLsSigned, StorageURLsPublic, c.Storage.URLs))
+	}
+	if c.Storage.UploadURLTTL <= 0 || c.Storage.UploadURLTTL > 7*24*time.Hour {
+		problems = append(problems, "STORAGE_UPLOAD_URL_TTL (storage.upload_url_ttl) must be positive and at most 168h")
+	}
+
+	require(c.Mail.From, "MAIL_FROM", "mail.from")
+	switch c.Mail.Driver {
+	case MailDriverConsole:
+	case MailDriverFile:
+		require(c.Mail.Dir, "MAIL_DIR", "mail.dir")
+	case MailDriverSMTP:
+		require(c.Mail.SMTP.Host, "SMTP_HOST", "mail.smtp.host")
+		if c.Mail.SMTP.Port <= 0 {
+			problems = append(problems, "SMTP_PORT (mail.smtp.port) must be positive")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("MAIL_DRIVER (mail.driver) must be %q, %q or %q, got %q", MailDriverConsole, MailDriverFile, MailDriverSMTP, c.Mail.Driver))
+	}
+
+	switch c.Backend {
+	case BackendFirestore:
+		if len(c.JWT.Keys) == 0 {
+			problems = append(problems, "JWT_KEYS (jwt.keys) is required")
+		}
+		require(c.Firebase.CredentialsFile, "FIREBASE_CREDENTIALS_FILE", "firebase.credentials_file")
+		if c.Storage.Driver == StorageDriverGCS {
+			require(c.Storage.CredentialsFile, "STORAGE_CREDENTIALS_FILE", "storage.credentials_file")
+			require(c.Storage.Bucket, "STORAGE_BUCKET", "storage.bucket")
+			require(c.Storage.GoogleAccessID, "STORAGE_GOOGLE_ACCESS_ID", "storage.google_access_id")
+		}
+		require(c.Google.ClientID, "GOOGLE_CLIENT_ID", "google.client_id")
+	case BackendMemory:
+	default:
+		problems = append(problems, fmt.Sprintf("BACKEND (backend) must be %q or %q, got %q", BackendFirestore, BackendMemory, c.Backend))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// builtinProviders have preset issuers and keys in the oidc package
+var builtinProviders = map[string]bool{"google": true, "apple": true, "facebook": true}
+
+// reservedProviderNames would clash with other /v1/auth routes or identities
+var reservedProviderNames = map[string]bool{
+	"password": true, "register": true, "login": true, "refresh": true, "logout": true,
+	"logout-all": true, "verify-email": true, "resend-verification": true,
+	"forgot-password": true, "reset-password": true, "firebase": true, "mfa": true,
+}
+
+func setString(target *string, env string) {
+	if value, ok := os.LookupEnv(env); ok {
+		*target = value
+	}
+}
+
+// setStringList parses a comma separated list
+func setStringList(target *[]string, env string) {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*target = list
+}
+
+// setSigningKeys parses a comma separated list of id=file pairs
+func setSigningKeys(target *[]SigningKeyConfig, env string) error {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+
+	var keys []SigningKeyConfig
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, file, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid %s entry %q, expected id=file", env, entry)
+		}
+		keys = append(keys, SigningKeyConfig{ID: strings.TrimSpace(id), File: strings.TrimSpace(file)})
+	}
+	*target = keys
+	return nil
+}
+
+// setOIDCProviders reads a comma separated list of provider names, each
+// configured by OIDC_<NAME>_ISSUER, _CLIENT_IDS, _DISCOVERY_URL and _JWKS_URL
+func setOIDCProviders(target *[]OIDCProviderConfig, env string) {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return
+	}
+
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := oidcEnvPrefix(name)
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			DiscoveryURL: os.Getenv(prefix + "DISCOVERY_URL"),
+			JWKSURL:      os.Getenv(prefix + "JWKS_URL"),
+		}
+		setStringList(&provider.ClientIDs, prefix+"CLIENT_IDS")
+		providers = append(providers, provider)
+	}
+	*target = providers
+}
+
+// oidcEnvPrefix returns the prefix of the environment variables configuring a provider
+func oidcEnvPrefix(name string) string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+func setBool(target *bool, env string) error {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", env, err)
+	}
+	*target = b
+	return nil
+}
+
+func setInt(target *int, env string) error {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", env, err)
+	}
+	*target = i
+	return nil
+}
+
+func setDuration(target *time.Duration, env string) error {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", env, err)
+	}
+	*target = d
+	return nil
+}
+
+// ActiveSigningKeyID returns the ID of the key new tokens are signed with
+func (c *JWTConfig) ActiveSigningKeyID() string {
+	if c.ActiveKeyID == "" && len(c.Keys) > 0 {
+		return c.Keys[0].ID
+	}
+	return c.ActiveKeyID
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// firestoreConfig returns a valid configuration for the firestore backend
+func firestoreConfig() *Config {
+	cfg := Default()
+	cfg.JWT.Keys = []SigningKeyConfig{{ID: "k1", File: "keys/k1.pem"}}
+	cfg.Firebase.CredentialsFile = "firebase.json"
+	cfg.Storage.CredentialsFile = "storage.json"
+	cfg.Storage.Bucket = "bakulen"
+	cfg.Storage.GoogleAccessID = "storage@bakulen.iam.gserviceaccount.com"
+	cfg.Google.ClientID = "client.apps.googleusercontent.com"
+	return cfg
+}
+
+// memoryConfig returns a valid configuration for the memory backend
+func memoryConfig() *Config {
+	cfg := Default()
+	cfg.Backend = BackendMemory
+	return cfg
+}
+
+// writeConfigFile writes a YAML config file and points CONFIG_FILE at it
+func writeConfigFile(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	writeConfigFile(t, `
+port: "9000"
+backend: memory
+jwt:
+  issuer: file-issuer
+  access_ttl: 10m
+login:
+  free_attempts: 3
+oidc:
+  providers:
+    - name: acme
+      issuer: https://acme.example.com
+      client_ids: [from-file]
+`)
+	t.Setenv("PORT", "9100")
+	t.Setenv("JWT_ACCESS_TTL", "5m")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1, ,10.0.0.2")
+	t.Setenv("JWT_KEYS", "k1=keys/k1.pem, k2 = keys/k2.pem")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+
+	// The environment wins, the file fills in the rest and defaults everything else
+	if cfg.Port != "9100" || cfg.JWT.AccessTTL != 5*time.Minute {
+		t.Errorf("port %q, access TTL %v, want the environment's 9100 and 5m", cfg.Port, cfg.JWT.AccessTTL)
+	}
+	if cfg.Backend != BackendMemory || cfg.JWT.Issuer != "file-issuer" || cfg.Login.FreeAttempts != 3 {
+		t.Errorf("backend %q, issuer %q, free attempts %d, want the file's values", cfg.Backend, cfg.JWT.Issuer, cfg.Login.FreeAttempts)
+	}
+	if len(cfg.OIDC.Providers) != 1 || cfg.OIDC.Providers[0].ClientIDs[0] != "from-file" {
+		t.Errorf("OIDC providers = %+v, want acme from the file", cfg.OIDC.Providers)
+	}
+	if cfg.JWT.Audience != "bakulen" || cfg.Login.LockoutThreshold != 10 {
+		t.Errorf("audience %q, lockout threshold %d, want the defaults", cfg.JWT.Audience, cfg.Login.LockoutThreshold)
+	}
+	if strings.Join(cfg.TrustedProxies, ",") != "10.0.0.1,10.0.0.2" {
+		t.Errorf("trusted proxies = %v", cfg.TrustedProxies)
+	}
+	if len(cfg.JWT.Keys) != 2 || cfg.JWT.Keys[1] != (SigningKeyConfig{ID: "k2", File: "keys/k2.pem"}) {
+		t.Errorf("JWT keys = %+v", cfg.JWT.Keys)
+	}
+}
+
+func TestLoadOIDCProvidersFromEnv(t *testing.T) {
+	writeConfigFile(t, "backend: memory\n")
+	t.Setenv("OIDC_PROVIDERS", "my-idp")
+	t.Setenv("OIDC_MY_IDP_ISSUER", "https://idp.example.com")
+	t.Setenv("OIDC_MY_IDP_CLIENT_IDS", "web,ios")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	want := OIDCProviderConfig{Name: "my-idp", Issuer: "https://idp.example.com", ClientIDs: []string{"web", "ios"}}
+	if len(cfg.OIDC.Providers) != 1 || cfg.OIDC.Providers[0].Issuer != want.Issuer || strings.Join(cfg.OIDC.Providers[0].ClientIDs, ",") != "web,ios" {
+		t.Fatalf("OIDC providers = %+v, want %+v", cfg.OIDC.Providers, want)
+	}
+}
+
+func TestLoadRejectsMalformedValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		file string
+		want string
+	}{
+		{"duration", map[string]string{"JWT_ACCESS_TTL": "soon"}, "", "invalid JWT_ACCESS_TTL"},
+		{"duration without unit", map[string]string{"PASSWORD_RESET_TTL": "60"}, "", "invalid PASSWORD_RESET_TTL"},
+		{"login duration", map[string]string{"LOGIN_LOCKOUT_DURATION": "1 hour"}, "", "invalid LOGIN_LOCKOUT_DURATION"},
+		{"rate limit period", map[string]string{"RATE_LIMIT_USERS_PERIOD": "minute"}, "", "invalid RATE_LIMIT_USERS_PERIOD"},
+		{"integer", map[string]string{"LOGIN_FREE_ATTEMPTS": "five"}, "", "invalid LOGIN_FREE_ATTEMPTS"},
+		{"boolean", map[string]string{"FIREBASE_ACCEPT_ID_TOKENS": "maybe"}, "", "invalid FIREBASE_ACCEPT_ID_TOKENS"},
+		{"signing key", map[string]string{"JWT_KEYS": "keys/k1.pem"}, "", "invalid JWT_KEYS entry"},
+		{"file duration", nil, "jwt:\n  access_ttl: soon\n", "failed to parse config file"},
+		{"missing file", map[string]string{"CONFIG_FILE": "does-not-exist.yaml"}, "", "failed to read config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file != "" {
+				writeConfigFile(t, tt.file)
+			}
+			t.Setenv("BACKEND", BackendMemory)
+			for env, value := range tt.env {
+				t.Setenv(env, value)
+			}
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCollectsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Port = ""
+	cfg.JWT.Issuer = ""
+	cfg.JWT.RefreshTTL = cfg.JWT.AccessTTL
+	cfg.MFA.ChallengeTTL = 0
+	cfg.Mail.From = ""
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want an error")
+	}
+	for _, want := range []string{
+		"PORT (port) is required",
+		"JWT_ISSUER (jwt.issuer) is required",
+		"JWT_REFRESH_TTL (jwt.refresh_ttl) must be longer",
+		"MFA_CHALLENGE_TTL (mfa.challenge_ttl) must be positive",
+		"MAIL_FROM (mail.from) is required",
+		// The default backend is firestore, which needs its own settings
+		"JWT_KEYS (jwt.keys) is required",
+		"FIREBASE_CREDENTIALS_FILE (firebase.credentials_file) is required",
+		"STORAGE_BUCKET (storage.bucket) is required",
+		"GOOGLE_CLIENT_ID (google.client_id) is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v\nwant it to mention %q", err, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func() *Config
+		change func(c *Config)
+		// want is part of the expected error, empty for a valid config
+		want string
+	}{
+		{"memory backend", memoryConfig, nil, ""},
+		{"firestore backend", firestoreConfig, nil, ""},
+		{"firestore without signing keys", firestoreConfig, func(c *Config) { c.JWT.Keys = nil }, "JWT_KEYS (jwt.keys) is required"},
+		{"firestore without Firebase credentials", firestoreConfig, func(c *Config) { c.Firebase.CredentialsFile = "" }, "FIREBASE_CREDENTIALS_FILE"},
+		{"firestore without storage credentials", firestoreConfig, func(c *Config) { c.Storage.CredentialsFile = "" }, "STORAGE_CREDENTIALS_FILE"},
+		{"firestore without a bucket", firestoreConfig, func(c *Config) { c.Storage.Bucket = "" }, "STORAGE_BUCKET"},
+		{"firestore without a signing account", firestoreConfig, func(c *Config) { c.Storage.GoogleAccessID = "" }, "STORAGE_GOOGLE_ACCESS_ID"},
+		{"firestore without a Google client ID", firestoreConfig, func(c *Config) { c.Google.ClientID = "" }, "GOOGLE_CLIENT_ID"},
+		{"firestore with disk storage needs no bucket", firestoreConfig, func(c *Config) {
+			c.Storage.Driver = StorageDriverDisk
+			c.Storage.CredentialsFile, c.Storage.Bucket, c.Storage.GoogleAccessID = "", "", ""
+		}, ""},
+		{"unknown backend", memoryConfig, func(c *Config) { c.Backend = "sqlite" }, "BACKEND (backend) must be"},
+		{"unknown active key", firestoreConfig, func(c *Config) { c.JWT.ActiveKeyID = "k2" }, `JWT_ACTIVE_KEY_ID (jwt.active_key_id) "k2" does not match`},
+		{"negative access TTL", memoryConfig, func(c *Config) { c.JWT.AccessTTL = -time.Minute }, "JWT_ACCESS_TTL (jwt.access_ttl) must be positive"},
+		{"unknown link policy", memoryConfig, func(c *Config) { c.Auth.LinkPolicy = "always" }, "ACCOUNT_LINK_POLICY"},
+		{"password longer than bcrypt allows", memoryConfig, func(c *Config) { c.Password.MaxLength = 100 }, "PASSWORD_MAX_LENGTH"},
+		{"lockout below the free attempts", memoryConfig, func(c *Config) { c.Login.LockoutThreshold = 2 }, "LOGIN_LOCKOUT_THRESHOLD"},
+		{"max delay below the base delay", memoryConfig, func(c *Config) { c.Login.MaxDelay = time.Millisecond }, "LOGIN_MAX_DELAY"},
+		{"firestore rate limits on memory", memoryConfig, func(c *Config) { c.RateLimit.Store = RateLimitStoreFirestore }, "requires the firestore backend"},
+		{"rate limit without a period", memoryConfig, func(c *Config) { c.RateLimit.Auth.Period = 0 }, "RATE_LIMIT_AUTH_REQUESTS and RATE_LIMIT_AUTH_PERIOD"},
+		{"unknown rate limit key", memoryConfig, func(c *Config) { c.RateLimit.Admin.Key = "email" }, "RATE_LIMIT_ADMIN_KEY"},
+		{"disabled rate limits are not checked", memoryConfig, func(c *Config) {
+			c.RateLimit.Enabled = false
+			c.RateLimit.Auth.Period = 0
+		}, ""},
+		{"signed URLs valid too long", memoryConfig, func(c *Config) { c.Storage.SignedURLTTL = 8 * 24 * time.Hour }, "STORAGE_SIGNED_URL_TTL"},
+		{"public URLs of local storage", memoryConfig, func(c *Config) {
+			c.Storage.Driver = StorageDriverDisk
+			c.Storage.URLs = StorageURLsPublic
+		}, "STORAGE_PUBLIC_BASE_URL"},
+		{"SMTP without a host", memoryConfig, func(c *Config) { c.Mail.Driver = MailDriverSMTP }, "SMTP_HOST"},
+		{"fake issuer on firestore", firestoreConfig, func(c *Config) { c.OIDC.FakeIssuer = true }, "OIDC_FAKE_ISSUER"},
+		{"Firebase tokens on memory", memoryConfig, func(c *Config) { c.Firebase.AcceptIDTokens = true }, "FIREBASE_ACCEPT_ID_TOKENS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config()
+			if tt.change != nil {
+				tt.change(cfg)
+			}
+
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOIDCProviderNames(t *testing.T) {
+	tests := []struct {
+		name string
+		// provider is the configured provider's name
+		provider string
+		want     string
+	}{
+		{"custom provider", "acme", ""},
+		{"builtin provider", "apple", ""},
+		{"uppercase", "Acme", "must be lowercase letters, digits and dashes"},
+		{"underscore", "my_idp", "must be lowercase letters, digits and dashes"},
+		{"password identity", "password", "is reserved"},
+		{"login route", "login", "is reserved"},
+		{"Firebase route", "firebase", "is reserved"},
+		{"two-factor route", "mfa", "is reserved"},
+		{"reset route", "reset-password", "is reserved"},
+		{"Google configured by GOOGLE_CLIENT_ID", "google", "is configured twice"},
+		{"fake issuer", "fake", "is configured twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := memoryConfig()
+			cfg.Google.ClientID = "client.apps.googleusercontent.com"
+			cfg.OIDC.FakeIssuer = true
+			cfg.OIDC.Providers = []OIDCProviderConfig{{Name: tt.provider, Issuer: "https://idp.example.com", ClientIDs: []string{"web"}}}
+
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// Providers without presets need an issuer, and every provider client IDs
+	cfg := memoryConfig()
+	cfg.OIDC.Providers = []OIDCProviderConfig{{Name: "acme"}}
+	err := cfg.Validate()
+	for _, want := range []string{"OIDC_ACME_ISSUER (oidc.providers[0].issuer) is required", "OIDC_ACME_CLIENT_IDS (oidc.providers[0].client_ids) is required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want an error containing %q", err, want)
+		}
+	}
+}
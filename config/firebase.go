@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	"cloud.google.com/go/firestore"
@@ -11,55 +12,62 @@ import (
 	"google.golang.org/api/option"
 )
 
-var FirebaseApp *firebase.App
-var FirestoreClient *firestore.Client
-var StorageClient *storage.Client
-var AuthClient       *auth.Client
+// Firebase holds the Firebase app and the clients built from it
+type Firebase struct {
+	App       *firebase.App
+	Firestore *firestore.Client
+	Storage   *storage.Client
+	Auth      *auth.Client
+}
 
-// InitFirebase initializes both Firestore and Storage clients
-func InitFirebase() {
-	ctx := context.Background()
-	// Initialize Firestore client
-	firestoreOpt := option.WithCredentialsFile("bakulendatabase-firebase-adminsdk-fbsvc-c5d1d48f7d.json")
+// NewFirebase initializes the Firestore, Auth and Storage clients
+func NewFirebase(ctx context.Context, cfg *Config) (*Firebase, error) {
+	// Initialize Firebase app
+	firestoreOpt := option.WithCredentialsFile(cfg.Firebase.CredentialsFile)
 	app, err := firebase.NewApp(ctx, nil, firestoreOpt)
 	if err != nil {
-		log.Fatalf("Failed to initialize Firebase Firestore: %v", err)
+		return nil, fmt.Errorf("failed to initialize Firebase app: %v", err)
 	}
-	FirebaseApp = app
-	log.Println("Firebase Firestore initialized successfully")
+	fb := &Firebase{App: app}
 
 	// Initialize Firestore client
-	FirestoreClient, err = app.Firestore(ctx)
+	fb.Firestore, err = app.Firestore(ctx)
 	if err != nil {
-		log.Fatalf("Failed to create Firestore client: %v", err)
+		return nil, fmt.Errorf("failed to create Firestore client: %v", err)
 	}
+	log.Println("Firebase Firestore initialized successfully")
 
-	AuthClient, err = app.Auth(ctx)
+	fb.Auth, err = app.Auth(ctx)
 	if err != nil {
-		log.Fatalf("Failed to initialize Firebase Auth client: %v", err)
+		fb.Close()
+		return nil, fmt.Errorf("failed to initialize Firebase Auth client: %v", err)
 	}
 	log.Println("Firebase Auth initialized successfully")
 
-	// Initialize Firebase Storage client
-	storageOpt := option.WithCredentialsFile("bekaspakaistorage-firebase-adminsdk-hedsy-d41a469e13.json")
-	storageClient, err := storage.NewClient(ctx, storageOpt)
-	if err != nil {
-		log.Fatalf("Failed to initialize Firebase Storage client: %v", err)
+	// Initialize Firebase Storage client, unless uploads are kept elsewhere
+	if cfg.Storage.Driver == StorageDriverGCS {
+		storageOpt := option.WithCredentialsFile(cfg.Storage.CredentialsFile)
+		fb.Storage, err = storage.NewClient(ctx, storageOpt)
+		if err != nil {
+			fb.Close()
+			return nil, fmt.Errorf("failed to initialize Firebase Storage client: %v", err)
+		}
+		log.Println("Firebase Storage initialized successfully")
 	}
-	StorageClient = storageClient
-	log.Println("Firebase Storage initialized successfully")
-}
-
-// GetFirestoreClient returns the Firestore client instance
-func GetFirestoreClient() *firestore.Client {
-	return FirestoreClient
-}
 
-// GetFirebaseStorageClient returns the Firebase Storage client instance
-func GetFirebaseStorageClient() *storage.Client {
-	return StorageClient
+	return fb, nil
 }
 
-func GetFirebaseAuthClient() *auth.Client{
-	return AuthClient
+// Close releases the Firestore and Storage clients
+func (f *Firebase) Close() error {
+	var firstErr error
+	if f.Firestore != nil {
+		firstErr = f.Firestore.Close()
+	}
+	if f.Storage != nil {
+		if err := f.Storage.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
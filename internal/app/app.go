@@ -0,0 +1,223 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/Dffarhn/bakulenapi/api/v1"
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	service "github.com/Dffarhn/bakulenapi/internal/services"
+	"github.com/Dffarhn/bakulenapi/pkg/blob"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
+	"github.com/Dffarhn/bakulenapi/pkg/oidc"
+	"github.com/Dffarhn/bakulenapi/pkg/ratelimit"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// App wires together the clients, services and handlers of one API instance
+type App struct {
+	Config *config.Config
+	Router *gin.Engine
+
+	Firebase      *config.Firebase
+	Users         repository.UserRepository
+	RefreshTokens repository.RefreshTokenRepository
+	Revocations   repository.RevocationStore
+	ActionTokens  repository.ActionTokenRepository
+	Sessions      repository.SessionRepository
+	Uploads       repository.UploadRepository
+	LoginAttempts repository.LoginAttemptRepository
+	Audit         repository.AuditLogRepository
+	RateLimits    ratelimit.Store
+	Mailer        mailer.Mailer
+	Blobs         blob.Store
+	BlobSigner    *blob.URLSigner
+	Uploader      *utils.ImageUploader
+	ImageURLs     utils.ImageURLs
+	Tokens        *utils.JWTManager
+	Providers     *oidc.Registry
+	FakeIssuer    *oidc.FakeIssuer
+	FirebaseAuth  service.FirebaseTokenVerifier
+
+	AuthService   *service.AuthService
+	UserService   *service.UserService
+	UploadService *service.UploadService
+
+	AuthHandler   *v1.AuthHandler
+	UserHandler   *v1.UserHandler
+	JWKSHandler   *v1.JWKSHandler
+	AdminHandler  *v1.AdminHandler
+	BlobHandler   *v1.BlobHandler
+	UploadHandler *v1.UploadHandler
+}
+
+// New builds an App for the configured backend and registers its routes
+func New(ctx context.Context, cfg *config.Config) (*App, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	a := &App{Config: cfg}
+
+	keys, err := buildKeySet(&cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	a.Tokens = utils.NewJWTManager(keys, cfg.JWT.AccessTTL, cfg.JWT.Issuer, cfg.JWT.Audience)
+
+	a.Mailer, err = buildMailer(&cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy, err := buildPasswordPolicy(&cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Providers, a.FakeIssuer, err = buildProviders(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Backend {
+	case config.BackendFirestore:
+		fb, err := config.NewFirebase(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		a.Firebase = fb
+		a.Users = repository.NewFirestoreUserRepository(fb.Firestore)
+		a.RefreshTokens = repository.NewFirestoreRefreshTokenRepository(fb.Firestore)
+		a.Revocations = repository.NewFirestoreRevocationStore(fb.Firestore)
+		a.ActionTokens = repository.NewFirestoreActionTokenRepository(fb.Firestore)
+		a.Sessions = repository.NewFirestoreSessionRepository(fb.Firestore)
+		a.Uploads = repository.NewFirestoreUploadRepository(fb.Firestore)
+		a.LoginAttempts = repository.NewFirestoreLoginAttemptRepository(fb.Firestore)
+		a.Audit = repository.NewFirestoreAuditLogRepository(fb.Firestore)
+		a.FirebaseAuth = fb.Auth
+		if cfg.RateLimit.Enabled && cfg.RateLimit.Store == config.RateLimitStoreFirestore {
+			a.RateLimits = repository.NewFirestoreRateLimitStore(fb.Firestore)
+		}
+	case config.BackendMemory:
+		a.Users = repository.NewMemoryUserRepository()
+		a.RefreshTokens = repository.NewMemoryRefreshTokenRepository()
+		a.Revocations = repository.NewMemoryRevocationStore()
+		a.ActionTokens = repository.NewMemoryActionTokenRepository()
+		a.Sessions = repository.NewMemorySessionRepository()
+		a.Uploads = repository.NewMemoryUploadRepository()
+		a.LoginAttempts = repository.NewMemoryLoginAttemptRepository()
+		a.Audit = repository.NewMemoryAuditLogRepository()
+		if fake, ok := a.Providers.Get("fake"); ok {
+			a.FirebaseAuth = fakeFirebaseVerifier{provider: fake}
+		}
+	default:
+		return nil, fmt.Errorf("unknown backend %q", cfg.Backend)
+	}
+
+	a.Blobs, a.BlobSigner, err = buildBlobStore(&cfg.Storage, a.Firebase)
+	if err != nil {
+		return nil, err
+	}
+	if a.Blobs != nil {
+		a.Uploader = utils.NewImageUploader(a.Blobs)
+		a.ImageURLs = buildImageURLs(&cfg.Storage, a.Uploader)
+	}
+
+	// Create services and handlers
+	a.AuthService = service.NewAuthService(service.AuthDependencies{
+		Users:           a.Users,
+		RefreshTokens:   a.RefreshTokens,
+		Revocations:     a.Revocations,
+		ActionTokens:    a.ActionTokens,
+		Sessions:        a.Sessions,
+		LoginAttempts:   a.LoginAttempts,
+		Audit:           a.Audit,
+		Tokens:          a.Tokens,
+		Mailer:          a.Mailer,
+		PasswordPolicy:  passwordPolicy,
+		TOTPIssuer:      cfg.MFA.Issuer,
+		MFAChallengeTTL: cfg.MFA.ChallengeTTL,
+		LoginThrottle: service.LoginThrottlePolicy{
+			Window:             cfg.Login.FailureWindow,
+			FreeAttempts:       cfg.Login.FreeAttempts,
+			LockoutThreshold:   cfg.Login.LockoutThreshold,
+			IPFreeAttempts:     cfg.Login.IPFreeAttempts,
+			IPLockoutThreshold: cfg.Login.IPLockoutThreshold,
+			BaseDelay:          cfg.Login.BaseDelay,
+			MaxDelay:           cfg.Login.MaxDelay,
+			LockoutDuration:    cfg.Login.LockoutDuration,
+		},
+		RefreshTTL:           cfg.JWT.RefreshTTL,
+		Providers:            a.Providers,
+		FirebaseAuth:         a.FirebaseAuth,
+		AcceptFirebaseTokens: cfg.Firebase.AcceptIDTokens,
+		LinkRequiresPassword: cfg.Auth.LinkPolicy == config.LinkPolicyPassword,
+		EmailVerificationURL: cfg.Auth.EmailVerificationURL,
+		EmailVerificationTTL: cfg.Auth.EmailVerificationTTL,
+		PasswordResetURL:     cfg.Auth.PasswordResetURL,
+		PasswordResetTTL:     cfg.Auth.PasswordResetTTL,
+	})
+	a.UserService = service.NewUserService(a.Users, a.AuthService, a.Uploader, a.ImageURLs)
+	a.UploadService = service.NewUploadService(a.Uploads, a.Blobs, a.UserService, cfg.Storage.UploadURLTTL)
+	a.AuthHandler = v1.NewAuthHandler(a.AuthService)
+	a.UserHandler = v1.NewUserHandler(a.UserService)
+	a.UploadHandler = v1.NewUploadHandler(a.UploadService)
+	a.JWKSHandler = v1.NewJWKSHandler(keys)
+	a.AdminHandler = v1.NewAdminHandler(a.UserService, a.AuthService)
+	if a.BlobSigner != nil {
+		a.BlobHandler = v1.NewBlobHandler(a.Blobs, a.BlobSigner)
+	}
+
+	if cfg.RateLimit.Enabled && a.RateLimits == nil {
+		a.RateLimits = ratelimit.NewMemoryStore()
+	}
+
+	// Setup Gin router
+	validation.RegisterBindings()
+	a.Router = gin.Default()
+	if err := a.Router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %v", err)
+	}
+
+	// Register the routes
+	auth := middleware.AuthMiddleware(a.AuthService)
+	verified := middleware.RequireVerifiedEmail()
+	if !cfg.Auth.RequireVerifiedEmail {
+		verified = func(c *gin.Context) { c.Next() }
+	}
+	v1.RegisterWellKnownRoutes(&a.Router.RouterGroup, a.JWKSHandler)
+	if a.FakeIssuer != nil {
+		a.Router.Any(fakeIssuerPath+"/*path", gin.WrapH(a.FakeIssuer))
+	}
+	v1Routes := a.Router.Group("/v1")
+	{
+		v1.RegisterAuthRoutes(v1Routes.Group("", a.rateLimit("auth", cfg.RateLimit.Auth)), a.AuthHandler, auth)
+		users := v1Routes.Group("", a.rateLimit("users", cfg.RateLimit.Users))
+		v1.RegisterUserRoutes(users, a.UserHandler, auth, verified)
+		v1.RegisterUploadRoutes(users, a.UploadHandler, auth, verified)
+		v1.RegisterAdminRoutes(v1Routes.Group("", a.rateLimit("admin", cfg.RateLimit.Admin)), a.AdminHandler, auth)
+		// Local blob stores serve their signed URLs themselves
+		if a.BlobHandler != nil {
+			v1.RegisterBlobRoutes(v1Routes, a.BlobHandler)
+		}
+	}
+
+	return a, nil
+}
+
+// Run starts the HTTP server on the given address
+func (a *App) Run(addr string) error {
+	return a.Router.Run(addr)
+}
+
+// Close releases the clients held by the App
+func (a *App) Close() error {
+	if a.Firebase != nil {
+		return a.Firebase.Close()
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/gin-gonic/gin"
+)
+
+// testConfig returns a valid configuration for the memory backend and memory blob store
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Backend = config.BackendMemory
+	cfg.Storage.Driver = config.StorageDriverMemory
+	cfg.Storage.SigningKey = "test-signing-key"
+	cfg.RateLimit.Enabled = false
+	return cfg
+}
+
+// newTestApp builds an App on the memory backend
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	a, err := New(context.Background(), testConfig())
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+// testResponse is the envelope every API response comes in
+type testResponse struct {
+	StatusCode int             `json:"statusCode"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data"`
+	Errors     json.RawMessage `json:"errors"`
+}
+
+// do sends a request to the App's router. target may be an absolute URL, such
+// as a signed blob URL, of which only the path and query are used.
+func do(t *testing.T, a *App, method, target, token, contentType string, body []byte) (int, testResponse) {
+	t.Helper()
+	u, err := url.Parse(target)
+	if err != nil {
+		t.Fatalf("url.Parse() = %v", err)
+	}
+	req := httptest.NewRequest(method, u.RequestURI(), bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	a.Router.ServeHTTP(rec, req)
+
+	var resp testResponse
+	if rec.Header().Get("Content-Type") == "application/json; charset=utf-8" {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s returned invalid JSON: %v", method, u.Path, err)
+		}
+	}
+	return rec.Code, resp
+}
+
+// doJSON sends body encoded as JSON
+func doJSON(t *testing.T, a *App, method, target, token string, body interface{}) (int, testResponse) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal() = %v", err)
+	}
+	return do(t, a, method, target, token, "application/json", data)
+}
+
+// decode unmarshals the data of a response
+func decode(t *testing.T, resp testResponse, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(resp.Data, v); err != nil {
+		t.Fatalf("decoding %s = %v", resp.Data, err)
+	}
+}
+
+const testPassword = "correct-Horse-battery-9"
+
+// register signs up a user named username and returns their user ID and access token
+func register(t *testing.T, a *App, username string) (string, string) {
+	t.Helper()
+	code, resp := doJSON(t, a, http.MethodPost, "/v1/auth/register", "", map[string]string{
+		"username":         username,
+		"email":            username + "@example.com",
+		"password":         testPassword,
+		"retyped_password": testPassword,
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("register %s = %d %s", username, code, resp.Message)
+	}
+	var data struct {
+		Token string `json:"token"`
+		User  struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	decode(t, resp, &data)
+	return data.User.ID, data.Token
+}
+
+// login signs in username with testPassword and returns the status
+func login(t *testing.T, a *App, username string) int {
+	t.Helper()
+	code, _ := doJSON(t, a, http.MethodPost, "/v1/auth/login", "", map[string]string{
+		"email":    username + "@example.com",
+		"password": testPassword,
+	})
+	return code
+}
+
+func TestAppsAreIsolated(t *testing.T) {
+	first, second := newTestApp(t), newTestApp(t)
+	_, firstToken := register(t, first, "alice")
+
+	// Users of one App are unknown to the other
+	if code := login(t, first, "alice"); code != http.StatusOK {
+		t.Fatalf("login at the first App = %d, want 200", code)
+	}
+	if code := login(t, second, "alice"); code != http.StatusUnauthorized {
+		t.Fatalf("login at the second App = %d, want 401", code)
+	}
+
+	// The same user can sign up with each App
+	_, secondToken := register(t, second, "alice")
+	if code := login(t, second, "alice"); code != http.StatusOK {
+		t.Fatalf("login at the second App after signing up = %d, want 200", code)
+	}
+
+	// Each App only accepts the tokens it issued
+	tests := []struct {
+		name  string
+		app   *App
+		token string
+		want  int
+	}{
+		{"own token", first, firstToken, http.StatusOK},
+		{"second App's token at the first", first, secondToken, http.StatusUnauthorized},
+		{"first App's token at the second", second, firstToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, resp := do(t, tt.app, http.MethodGet, "/v1/users", tt.token, "", nil); code != tt.want {
+				t.Fatalf("GET /v1/users = %d %s, want %d", code, resp.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewReturnsConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(cfg *config.Config)
+	}{
+		{"unknown backend", func(cfg *config.Config) { cfg.Backend = "postgres" }},
+		{"firestore without credentials", func(cfg *config.Config) { cfg.Backend = config.BackendFirestore }},
+		{"unknown storage driver", func(cfg *config.Config) { cfg.Storage.Driver = "ftp" }},
+		{"missing JWT key file", func(cfg *config.Config) {
+			cfg.JWT.Keys = []config.SigningKeyConfig{{ID: "k1", File: "/nonexistent/key.pem"}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.edit(cfg)
+
+			if a, err := New(context.Background(), cfg); err == nil {
+				a.Close()
+				t.Fatal("New() accepted an invalid configuration")
+			}
+		})
+	}
+}
+
+func TestMissingFieldsGetFieldErrors(t *testing.T) {
+	a := newTestApp(t)
+	_, token := register(t, a, "alice")
+
+	tests := []struct {
+		target string
+		token  string
+		fields []string
+	}{
+		{"/v1/auth/login", "", []string{"email", "password"}},
+		{"/v1/auth/google", "", []string{"idToken"}},
+		{"/v1/auth/refresh", "", []string{"refresh_token"}},
+		{"/v1/auth/verify-email", "", []string{"token"}},
+		{"/v1/auth/forgot-password", "", []string{"email"}},
+		{"/v1/auth/reset-password", "", []string{"token", "password"}},
+		{"/v1/users/2fa/recovery-codes", token, []string{"code"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			code, resp := doJSON(t, a, http.MethodPost, tt.target, tt.token, map[string]string{})
+			if code != http.StatusBadRequest {
+				t.Fatalf("POST %s = %d %s, want 400", tt.target, code, resp.Message)
+			}
+			var fields []struct {
+				Field string `json:"field"`
+			}
+			if err := json.Unmarshal(resp.Errors, &fields); err != nil {
+				t.Fatalf("decoding errors %s = %v", resp.Errors, err)
+			}
+			got := make([]string, len(fields))
+			for i, f := range fields {
+				got[i] = f.Field
+			}
+			if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+				t.Fatalf("field errors for %v, want %v", got, tt.fields)
+			}
+		})
+	}
+
+	// Logout takes an optional body, but not a malformed one
+	code, resp := do(t, a, http.MethodPost, "/v1/auth/logout", token, "application/json", []byte("{"))
+	if code != http.StatusBadRequest || resp.Message != "Invalid request format" {
+		t.Fatalf("POST /v1/auth/logout with malformed JSON = %d %s", code, resp.Message)
+	}
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+
+	"firebase.google.com/go/auth"
+	"github.com/Dffarhn/bakulenapi/pkg/oidc"
+)
+
+// fakeFirebaseVerifier stands in for Firebase Auth on the memory backend by
+// accepting tokens of the fake OIDC issuer as Firebase ID tokens
+type fakeFirebaseVerifier struct {
+	provider *oidc.Provider
+}
+
+func (v fakeFirebaseVerifier) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	token, err := v.provider.Verify(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	iat, _ := token.Claims.GetIssuedAt()
+	var issuedAt int64
+	if iat != nil {
+		issuedAt = iat.Unix()
+	}
+	return &auth.Token{
+		Issuer:   token.Issuer,
+		Audience: fakeClientID,
+		Expires:  token.Expiry.Unix(),
+		IssuedAt: issuedAt,
+		Subject:  token.Subject,
+		UID:      token.Subject,
+		Firebase: auth.FirebaseInfo{SignInProvider: "custom"},
+		Claims:   token.Claims,
+	}, nil
+}
+
+func (v fakeFirebaseVerifier) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error) {
+	return v.VerifyIDToken(ctx, idToken)
+}
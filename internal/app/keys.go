@@ -0,0 +1,36 @@
+package app
+
+import (
+	"log"
+
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+// buildKeySet loads the configured JWT keys. Without any configured keys an
+// ephemeral key is generated, which only the memory backend allows.
+func buildKeySet(cfg *config.JWTConfig) (*utils.KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		log.Println("[WARNING] No JWT keys configured, generating an ephemeral signing key")
+		key, err := utils.GenerateSigningKey("ephemeral")
+		if err != nil {
+			return nil, err
+		}
+		return utils.NewKeySet(key)
+	}
+
+	var active *utils.SigningKey
+	var others []*utils.SigningKey
+	for _, keyCfg := range cfg.Keys {
+		key, err := utils.LoadKey(keyCfg.ID, keyCfg.File)
+		if err != nil {
+			return nil, err
+		}
+		if key.ID == cfg.ActiveSigningKeyID() {
+			active = key
+		} else {
+			others = append(others, key)
+		}
+	}
+	return utils.NewKeySet(active, others...)
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+)
+
+// buildMailer creates the Mailer selected by the mail driver setting
+func buildMailer(cfg *config.MailConfig) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case config.MailDriverConsole:
+		return mailer.NewConsoleMailer(), nil
+	case config.MailDriverFile:
+		return mailer.NewFileMailer(cfg.Dir)
+	case config.MailDriverSMTP:
+		return mailer.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
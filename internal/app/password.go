@@ -0,0 +1,30 @@
+package app
+
+import (
+	"log"
+
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
+)
+
+// buildPasswordPolicy creates the password policy and loads the breached password list if one is configured
+func buildPasswordPolicy(cfg *config.PasswordConfig) (*validation.PasswordPolicy, error) {
+	policy := &validation.PasswordPolicy{
+		MinLength:        cfg.MinLength,
+		MaxLength:        cfg.MaxLength,
+		MinCharClasses:   cfg.MinCharClasses,
+		RequireLowercase: cfg.RequireLowercase,
+		RequireUppercase: cfg.RequireUppercase,
+		RequireDigit:     cfg.RequireDigit,
+		RequireSymbol:    cfg.RequireSymbol,
+	}
+	if cfg.BreachedFile == "" {
+		return policy, nil
+	}
+
+	if err := policy.LoadBreachedPasswords(cfg.BreachedFile); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Loaded breached password list from %s", cfg.BreachedFile)
+	return policy, nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"log"
+
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/pkg/oidc"
+)
+
+// fakeIssuerPath is where the fake OIDC issuer is served when enabled
+const fakeIssuerPath = "/dev/oidc"
+
+// fakeClientID is the audience the fake provider accepts
+const fakeClientID = "bakulen-dev"
+
+// buildProviders registers Google and every configured OIDC provider. The
+// returned FakeIssuer is nil unless the fake issuer is enabled.
+func buildProviders(cfg *config.Config) (*oidc.Registry, *oidc.FakeIssuer, error) {
+	registry := oidc.NewRegistry()
+
+	var configs []oidc.Config
+	if cfg.Google.ClientID != "" {
+		configs = append(configs, oidc.Google(cfg.Google.ClientID))
+	}
+	for _, provider := range cfg.OIDC.Providers {
+		c, ok := oidc.Preset(provider.Name, provider.ClientIDs...)
+		if !ok || provider.Issuer != "" {
+			c = oidc.Config{Name: provider.Name, Issuers: []string{provider.Issuer}, ClientIDs: provider.ClientIDs}
+		}
+		if provider.DiscoveryURL != "" {
+			c.DiscoveryURL = provider.DiscoveryURL
+			c.JWKSURL = ""
+		}
+		if provider.JWKSURL != "" {
+			c.JWKSURL = provider.JWKSURL
+		}
+		configs = append(configs, c)
+	}
+
+	var fake *oidc.FakeIssuer
+	if cfg.OIDC.FakeIssuer {
+		var err error
+		fake, err = oidc.NewFakeIssuer("http://localhost:" + cfg.Port + fakeIssuerPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		configs = append(configs, fake.Config("fake", fakeClientID))
+		log.Printf("[WARNING] Fake OIDC issuer enabled at %s, tokens for audience %q are accepted as provider \"fake\"", fakeIssuerPath, fakeClientID)
+	}
+
+	for _, c := range configs {
+		provider, err := oidc.NewProvider(c, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := registry.Register(provider); err != nil {
+			return nil, nil, err
+		}
+	}
+	return registry, fake, nil
+}
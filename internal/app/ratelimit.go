@@ -0,0 +1,28 @@
+package app
+
+import (
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/pkg/middleware"
+	"github.com/Dffarhn/bakulenapi/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimit returns the rate limiting middleware of the named route group,
+// or one that lets every request through when rate limiting is disabled
+func (a *App) rateLimit(name string, rule config.RateLimitRule) gin.HandlerFunc {
+	if a.RateLimits == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	var key middleware.KeyFunc
+	switch rule.Key {
+	case config.RateLimitKeyUser:
+		key = middleware.KeyByUser(a.AuthService)
+	case config.RateLimitKeyRoute:
+		key = middleware.KeyByRoute
+	default:
+		key = middleware.KeyByIP
+	}
+	limit := ratelimit.PerPeriod(rule.Requests, rule.Period, rule.Burst)
+	return middleware.RateLimit(a.RateLimits, name, limit, key)
+}
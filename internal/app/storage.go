@@ -0,0 +1,62 @@
+package app
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+
+	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/pkg/blob"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+// buildBlobStore creates the store for uploads, or nil when the gcs driver is
+// used without Firebase. The local drivers also return the signer of the
+// URLs served by the /v1/blobs routes.
+func buildBlobStore(cfg *config.StorageConfig, fb *config.Firebase) (blob.Store, *blob.URLSigner, error) {
+	switch cfg.Driver {
+	case config.StorageDriverGCS:
+		if fb == nil || fb.Storage == nil {
+			log.Println("[WARNING] No storage bucket available, image uploads are disabled")
+			return nil, nil, nil
+		}
+		return blob.NewGCSStore(fb.Storage, cfg.Bucket, cfg.GoogleAccessID), nil, nil
+	case config.StorageDriverDisk, config.StorageDriverMemory:
+		signer, err := buildURLSigner(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.Driver == config.StorageDriverMemory {
+			return blob.NewMemoryStore(signer), signer, nil
+		}
+		store, err := blob.NewDiskStore(cfg.Dir, signer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create storage directory: %v", err)
+		}
+		return store, signer, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// buildURLSigner creates the signer of the local drivers' URLs. Without a
+// configured key an ephemeral one is generated.
+func buildURLSigner(cfg *config.StorageConfig) (*blob.URLSigner, error) {
+	secret := []byte(cfg.SigningKey)
+	if len(secret) == 0 {
+		log.Println("[WARNING] No storage signing key configured, generating an ephemeral key")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return blob.NewURLSigner(secret, cfg.LocalBaseURL), nil
+}
+
+// buildImageURLs picks how stored image keys are turned into URLs
+func buildImageURLs(cfg *config.StorageConfig, uploader *utils.ImageUploader) utils.ImageURLs {
+	if cfg.URLs == config.StorageURLsPublic {
+		return utils.NewPublicImageURLs(cfg.PublicBaseURL, cfg.Bucket)
+	}
+	return utils.NewSignedImageURLs(uploader, cfg.Bucket, cfg.SignedURLTTL)
+}
@@ -0,0 +1,293 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+)
+
+// testPNG returns a small PNG image
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		img.Set(x, x%48, color.RGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// startUpload starts a profile picture upload of size bytes and returns its ticket
+func startUpload(t *testing.T, a *App, token string, size int) models.UploadTicket {
+	t.Helper()
+	code, resp := doJSON(t, a, http.MethodPost, "/v1/uploads", token, map[string]interface{}{
+		"purpose":      models.UploadPurposeProfilePicture,
+		"content_type": "image/png",
+		"size":         size,
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("start upload = %d %s", code, resp.Message)
+	}
+	var ticket models.UploadTicket
+	decode(t, resp, &ticket)
+	return ticket
+}
+
+func TestUploadFlow(t *testing.T) {
+	picture := testPNG(t)
+
+	tests := []struct {
+		name string
+		// size is the size declared when starting the upload
+		size int
+		// contentType and body are sent to the upload URL, unless body is nil
+		contentType string
+		body        []byte
+		wantPut     int
+		// wantComplete and wantAgain are the statuses of completing the upload
+		// and of completing it a second time
+		wantComplete int
+		wantAgain    int
+	}{
+		{"picture", len(picture), "image/png", picture, http.StatusOK, http.StatusOK, http.StatusNotFound},
+		{"nothing sent", len(picture), "", nil, 0, http.StatusConflict, http.StatusConflict},
+		{"sent with another content type", len(picture), "image/jpeg", picture, http.StatusForbidden, http.StatusConflict, http.StatusConflict},
+		{"larger than declared", len(picture) - 1, "image/png", picture, http.StatusOK, http.StatusRequestEntityTooLarge, http.StatusNotFound},
+		{"not an image", len(picture), "image/png", bytes.Repeat([]byte("x"), len(picture)), http.StatusOK, http.StatusUnsupportedMediaType, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			_, token := register(t, a, "alice")
+			ticket := startUpload(t, a, token, tt.size)
+			if ticket.Method != http.MethodPut || ticket.Headers["Content-Type"] != "image/png" {
+				t.Fatalf("ticket = %+v", ticket)
+			}
+
+			if tt.body != nil {
+				// The upload URL needs no credentials besides its signature
+				if code, resp := do(t, a, http.MethodPut, ticket.URL, "", tt.contentType, tt.body); code != tt.wantPut {
+					t.Fatalf("PUT upload URL = %d %s, want %d", code, resp.Message, tt.wantPut)
+				}
+			}
+
+			completeURL := "/v1/uploads/" + ticket.UploadID + "/complete"
+			code, resp := do(t, a, http.MethodPost, completeURL, token, "", nil)
+			if code != tt.wantComplete {
+				t.Fatalf("complete = %d %s, want %d", code, resp.Message, tt.wantComplete)
+			}
+			if code == http.StatusOK {
+				var profile models.PrivateProfile
+				decode(t, resp, &profile)
+				if profile.ProfilePicture == "" || len(profile.ProfilePictureVariants) == 0 {
+					t.Fatalf("completed profile = %+v, want a picture", profile)
+				}
+				// The picture is served through its signed URL
+				if code, _ := do(t, a, http.MethodGet, profile.ProfilePicture, "", "", nil); code != http.StatusOK {
+					t.Fatalf("GET picture = %d", code)
+				}
+			}
+
+			if code, resp := do(t, a, http.MethodPost, completeURL, token, "", nil); code != tt.wantAgain {
+				t.Fatalf("complete again = %d %s, want %d", code, resp.Message, tt.wantAgain)
+			}
+		})
+	}
+}
+
+func TestUploadCompleteChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		// upload changes the pending upload before it is completed
+		upload func(u *models.Upload)
+		// byOther completes the upload as another user
+		byOther bool
+		want    int
+	}{
+		{"other user's upload", nil, true, http.StatusNotFound},
+		{"expired", func(u *models.Upload) { u.ExpiresAt = time.Now().Add(-time.Second) }, false, http.StatusGone},
+		{"unknown purpose", func(u *models.Upload) { u.Purpose = "listing_photo" }, false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			_, token := register(t, a, "alice")
+			picture := testPNG(t)
+			ticket := startUpload(t, a, token, len(picture))
+			if code, _ := do(t, a, http.MethodPut, ticket.URL, "", "image/png", picture); code != http.StatusOK {
+				t.Fatalf("PUT upload URL = %d", code)
+			}
+			if tt.upload != nil {
+				ctx := context.Background()
+				upload, err := a.Uploads.Get(ctx, ticket.UploadID)
+				if err != nil {
+					t.Fatalf("Get() = %v", err)
+				}
+				tt.upload(upload)
+				if err := a.Uploads.Create(ctx, upload); err != nil {
+					t.Fatalf("Create() = %v", err)
+				}
+			}
+			if tt.byOther {
+				_, token = register(t, a, "mallory")
+			}
+
+			completeURL := "/v1/uploads/" + ticket.UploadID + "/complete"
+			if code, resp := do(t, a, http.MethodPost, completeURL, token, "", nil); code != tt.want {
+				t.Fatalf("complete = %d %s, want %d", code, resp.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadCompletedOnce(t *testing.T) {
+	a := newTestApp(t)
+	_, token := register(t, a, "alice")
+	picture := testPNG(t)
+	ticket := startUpload(t, a, token, len(picture))
+	if code, _ := do(t, a, http.MethodPut, ticket.URL, "", "image/png", picture); code != http.StatusOK {
+		t.Fatalf("PUT upload URL = %d", code)
+	}
+
+	const callers = 5
+	codes := make(chan int, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _ := do(t, a, http.MethodPost, "/v1/uploads/"+ticket.UploadID+"/complete", token, "", nil)
+			codes <- code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	completed := 0
+	for code := range codes {
+		switch code {
+		case http.StatusOK:
+			completed++
+		case http.StatusConflict, http.StatusNotFound:
+		default:
+			t.Fatalf("complete = %d, want 200, 409 or 404", code)
+		}
+	}
+	if completed != 1 {
+		t.Fatalf("upload completed %d times, want once", completed)
+	}
+}
+
+func TestUploadReplacesPicture(t *testing.T) {
+	a := newTestApp(t)
+	_, token := register(t, a, "alice")
+	picture := testPNG(t)
+
+	var profiles []models.PrivateProfile
+	for i := 0; i < 2; i++ {
+		ticket := startUpload(t, a, token, len(picture))
+		if code, _ := do(t, a, http.MethodPut, ticket.URL, "", "image/png", picture); code != http.StatusOK {
+			t.Fatalf("PUT upload URL = %d", code)
+		}
+		code, resp := do(t, a, http.MethodPost, "/v1/uploads/"+ticket.UploadID+"/complete", token, "", nil)
+		if code != http.StatusOK {
+			t.Fatalf("complete = %d %s", code, resp.Message)
+		}
+		var profile models.PrivateProfile
+		decode(t, resp, &profile)
+		profiles = append(profiles, profile)
+	}
+
+	// The first picture's objects are deleted, the second's are served
+	for i, want := range []int{http.StatusNotFound, http.StatusOK} {
+		urls := []string{profiles[i].ProfilePicture}
+		for _, url := range profiles[i].ProfilePictureVariants {
+			urls = append(urls, url)
+		}
+		for _, url := range urls {
+			if code, _ := do(t, a, http.MethodGet, url, "", "", nil); code != want {
+				t.Fatalf("GET picture %d = %d, want %d", i+1, code, want)
+			}
+		}
+	}
+}
+
+func TestUploadPurgeExpired(t *testing.T) {
+	a := newTestApp(t)
+	_, token := register(t, a, "alice")
+	picture := testPNG(t)
+	ctx := context.Background()
+
+	// sent uploads a picture for a new upload and returns its record after
+	// applying edit
+	sent := func(edit func(u *models.Upload)) *models.Upload {
+		t.Helper()
+		ticket := startUpload(t, a, token, len(picture))
+		if code, _ := do(t, a, http.MethodPut, ticket.URL, "", "image/png", picture); code != http.StatusOK {
+			t.Fatalf("PUT upload URL = %d", code)
+		}
+		upload, err := a.Uploads.Get(ctx, ticket.UploadID)
+		if err != nil {
+			t.Fatalf("Get() = %v", err)
+		}
+		edit(upload)
+		if err := a.Uploads.Create(ctx, upload); err != nil {
+			t.Fatalf("Create() = %v", err)
+		}
+		return upload
+	}
+	expired := time.Now().Add(-time.Second)
+	abandoned := sent(func(u *models.Upload) { u.ExpiresAt = expired })
+	diedClaimed := sent(func(u *models.Upload) {
+		u.ExpiresAt = expired
+		u.ClaimedAt = time.Now().Add(-repository.UploadClaimTTL - time.Second)
+	})
+	completing := sent(func(u *models.Upload) {
+		u.ExpiresAt = expired
+		u.ClaimedAt = time.Now()
+	})
+	pending := sent(func(u *models.Upload) {})
+
+	purged, err := a.UploadService.PurgeExpired(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpired() = %v", err)
+	}
+	if purged != 2 {
+		t.Fatalf("PurgeExpired() = %d, want 2", purged)
+	}
+
+	tests := []struct {
+		name   string
+		upload *models.Upload
+		kept   bool
+	}{
+		{"expired", abandoned, false},
+		{"expired with a lapsed claim", diedClaimed, false},
+		{"expired while being completed", completing, true},
+		{"pending", pending, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.Uploads.Get(ctx, tt.upload.ID)
+			if kept := err == nil; kept != tt.kept {
+				t.Fatalf("upload kept = %v (%v), want %v", kept, err, tt.kept)
+			}
+			_, err = a.Blobs.Stat(ctx, tt.upload.Key)
+			if kept := err == nil; kept != tt.kept {
+				t.Fatalf("file kept = %v (%v), want %v", kept, err, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Types of audit events
+const (
+	AuditAccountLocked = "login.account_locked"
+	AuditIPLocked      = "login.ip_locked"
+	AuditMFALocked     = "mfa.locked"
+	AuditMFAEnabled    = "mfa.enabled"
+	AuditMFADisabled   = "mfa.disabled"
+	AuditMFAReset      = "mfa.reset"
+)
+
+// LoginAttempt counts the recent failed sign-ins of one account or client IP
+type LoginAttempt struct {
+	Key          string    `json:"key" firestore:"key"` // "account:<email>", "ip:<address>" or "mfa:<user ID>"
+	Failures     int       `json:"failures" firestore:"failures"`
+	FirstFailure time.Time `json:"first_failure" firestore:"firstFailure"`
+	LastFailure  time.Time `json:"last_failure" firestore:"lastFailure"`
+	// BlockedUntil refuses further attempts until it passes
+	BlockedUntil time.Time `json:"blocked_until" firestore:"blockedUntil"`
+	// ExpiresAt is when the record can be forgotten
+	ExpiresAt time.Time `json:"expires_at" firestore:"expiresAt"`
+}
+
+// AuditEvent records a security relevant event for later review
+type AuditEvent struct {
+	ID        string            `json:"id" firestore:"id"`
+	Type      string            `json:"type" firestore:"type"`
+	UserID    string            `json:"user_id,omitempty" firestore:"userId,omitempty"`
+	Email     string            `json:"email,omitempty" firestore:"email,omitempty"`
+	IP        string            `json:"ip,omitempty" firestore:"ip,omitempty"`
+	Details   map[string]string `json:"details,omitempty" firestore:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at" firestore:"createdAt"`
+}
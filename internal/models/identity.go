@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Identity providers a user can sign in with
+const (
+	ProviderPassword = "password"
+	ProviderGoogle   = "google"
+	ProviderFirebase = "firebase"
+)
+
+// Identity links a user to one way of signing in
+type Identity struct {
+	Provider string `json:"provider" firestore:"provider"`
+	// Subject is the user's ID at the provider; empty for the password provider
+	Subject  string    `json:"-" firestore:"subject,omitempty"`
+	Email    string    `json:"email,omitempty" firestore:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at" firestore:"linkedAt"`
+}
+
+// Key identifies the identity across all users; empty for the password provider
+func (i Identity) Key() string {
+	if i.Subject == "" {
+		return ""
+	}
+	return i.Provider + ":" + i.Subject
+}
+
+// EffectiveIdentities returns the user's identities. Users stored before
+// identities existed are derived from their password and Google flag; their
+// Google subject is unknown until they next sign in with Google.
+func (u *User) EffectiveIdentities() []Identity {
+	if len(u.Identities) > 0 {
+		return u.Identities
+	}
+
+	var identities []Identity
+	if u.Password != "" {
+		identities = append(identities, Identity{Provider: ProviderPassword, Email: u.Email, LinkedAt: u.CreatedAt})
+	}
+	if u.IsGoogleUser {
+		identities = append(identities, Identity{Provider: ProviderGoogle, Email: u.Email, LinkedAt: u.CreatedAt})
+	}
+	return identities
+}
+
+// Identity returns the user's identity for provider, or nil if it is not linked
+func (u *User) Identity(provider string) *Identity {
+	for _, identity := range u.EffectiveIdentities() {
+		if identity.Provider == provider {
+			return &identity
+		}
+	}
+	return nil
+}
+
+// HasProvider reports whether the user can sign in with provider
+func (u *User) HasProvider(provider string) bool {
+	return u.Identity(provider) != nil
+}
+
+// LinkIdentity adds or replaces the identity for its provider
+func (u *User) LinkIdentity(identity Identity) {
+	identities := u.withoutProvider(identity.Provider)
+	u.Identities = append(identities, identity)
+}
+
+// UnlinkIdentity removes the identity for provider
+func (u *User) UnlinkIdentity(provider string) {
+	u.Identities = u.withoutProvider(provider)
+	switch provider {
+	case ProviderPassword:
+		u.Password = ""
+	case ProviderGoogle:
+		u.IsGoogleUser = false
+	}
+}
+
+// withoutProvider returns a copy of the effective identities minus provider
+func (u *User) withoutProvider(provider string) []Identity {
+	identities := []Identity{}
+	for _, identity := range u.EffectiveIdentities() {
+		if identity.Provider != provider {
+			identities = append(identities, identity)
+		}
+	}
+	return identities
+}
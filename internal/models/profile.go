@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Verification badges shown on profiles
+const (
+	BadgeEmailVerified = "email_verified"
+	BadgePhoneVerified = "phone_verified"
+)
+
+// PublicProfile is what anyone may see of a user. It leaves out the email,
+// the phone number and everything about how the user signs in.
+type PublicProfile struct {
+	ID             string    `json:"id"`
+	Username       string    `json:"username"`
+	Name           string    `json:"name"`
+	ProfilePicture string    `json:"profile_picture,omitempty"`
+	Bio            string    `json:"bio,omitempty"`
+	Location       string    `json:"location,omitempty"`
+	JoinedAt       time.Time `json:"joined_at"`
+	Badges         []string  `json:"badges"`
+
+	// ProfilePictureVariants holds the avatar URL of each size, keyed by edge length in pixels
+	ProfilePictureVariants map[string]string `json:"profile_picture_variants,omitempty"`
+}
+
+// PrivateProfile is the user's own view of their account
+type PrivateProfile struct {
+	PublicProfile
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	Phone         string     `json:"phone,omitempty"`
+	PhoneVerified bool       `json:"phone_verified"`
+	Roles         []string   `json:"roles"`
+	Identities    []Identity `json:"identities"`
+	TwoFactor     *TwoFactor `json:"two_factor,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Badges returns the verification badges the user has earned
+func (u *User) Badges() []string {
+	badges := []string{}
+	if u.EmailVerified {
+		badges = append(badges, BadgeEmailVerified)
+	}
+	if u.PhoneVerified && u.Phone != "" {
+		badges = append(badges, BadgePhoneVerified)
+	}
+	return badges
+}
+
+// PublicProfile returns the user's public view. Its pictures are object keys
+// until the user service turns them into URLs.
+func (u *User) PublicProfile() PublicProfile {
+	return PublicProfile{
+		ID:                     u.ID,
+		Username:               u.Username,
+		Name:                   u.DisplayName(),
+		ProfilePicture:         u.ProfilePicture,
+		ProfilePictureVariants: u.ProfilePictureVariants,
+		Bio:                    u.Bio,
+		Location:               u.Location,
+		JoinedAt:               u.CreatedAt,
+		Badges:                 u.Badges(),
+	}
+}
+
+// PrivateProfile returns the user's own view, which includes contact details
+// and sign-in settings but never secrets or device tokens
+func (u *User) PrivateProfile() PrivateProfile {
+	return PrivateProfile{
+		PublicProfile: u.PublicProfile(),
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Phone:         u.Phone,
+		PhoneVerified: u.PhoneVerified,
+		Roles:         u.EffectiveRoles(),
+		Identities:    u.EffectiveIdentities(),
+		TwoFactor:     u.TwoFactor,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+// Roles a user can hold
+const (
+	RoleUser      = "user"
+	RoleSeller    = "seller"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission names an action guarded by RequirePermission
+type Permission string
+
+// Permissions granted through roles
+const (
+	PermissionProfileRead   Permission = "profile:read"
+	PermissionProfileUpdate Permission = "profile:update"
+	PermissionListingCreate Permission = "listing:create"
+	PermissionListingManage Permission = "listing:manage"
+	PermissionUsersRead     Permission = "users:read"
+	PermissionUsersModerate Permission = "users:moderate"
+	PermissionRolesManage   Permission = "roles:manage"
+	PermissionMFAReset      Permission = "users:mfa_reset"
+)
+
+// rolePermissions is the permission matrix: what each role may do
+var rolePermissions = map[string][]Permission{
+	RoleUser: {
+		PermissionProfileRead,
+		PermissionProfileUpdate,
+	},
+	RoleSeller: {
+		PermissionProfileRead,
+		PermissionProfileUpdate,
+		PermissionListingCreate,
+	},
+	RoleModerator: {
+		PermissionProfileRead,
+		PermissionProfileUpdate,
+		PermissionListingManage,
+		PermissionUsersRead,
+		PermissionUsersModerate,
+	},
+	RoleAdmin: {
+		PermissionProfileRead,
+		PermissionProfileUpdate,
+		PermissionListingCreate,
+		PermissionListingManage,
+		PermissionUsersRead,
+		PermissionUsersModerate,
+		PermissionRolesManage,
+		PermissionMFAReset,
+	},
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RolesHavePermission reports whether any of the roles grants the permission
+func RolesHavePermission(roles []string, permission Permission) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
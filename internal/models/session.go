@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Session is one signed-in device of a user. Its ID is the refresh token
+// family ID, which access tokens carry as their sid claim.
+type Session struct {
+	ID         string `json:"id" firestore:"id"`
+	UserID     string `json:"-" firestore:"userId"`
+	DeviceName string `json:"device_name,omitempty" firestore:"deviceName,omitempty"`
+	Platform   string `json:"platform,omitempty" firestore:"platform,omitempty"`
+	IP         string `json:"ip,omitempty" firestore:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty" firestore:"userAgent,omitempty"`
+	// FCMToken is the push notification token of the device
+	FCMToken   string    `json:"-" firestore:"fcmToken,omitempty"`
+	CreatedAt  time.Time `json:"created_at" firestore:"createdAt"`
+	LastSeenAt time.Time `json:"last_seen_at" firestore:"lastSeenAt"`
+	// ExpiresAt is when the session's latest refresh token expires
+	ExpiresAt time.Time `json:"expires_at" firestore:"expiresAt"`
+	// Current marks the session of the caller in listings
+	Current bool `json:"current" firestore:"-"`
+}
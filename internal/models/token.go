@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// AuthTokens is the token pair returned to clients after a successful sign-in
+type AuthTokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// RefreshToken is the server-side record of an issued refresh token.
+// Only the hash of the token is stored; the raw value is known to the client alone.
+type RefreshToken struct {
+	ID        string    `json:"id" firestore:"id"` // SHA-256 hash of the token
+	UserID    string    `json:"user_id" firestore:"userId"`
+	FamilyID  string    `json:"family_id" firestore:"familyId"` // Shared by every token rotated from the same sign-in
+	Used      bool      `json:"used" firestore:"used"`
+	Revoked   bool      `json:"revoked" firestore:"revoked"`
+	ExpiresAt time.Time `json:"expires_at" firestore:"expiresAt"`
+	CreatedAt time.Time `json:"created_at" firestore:"createdAt"`
+}
+
+// Purposes of single-use action tokens
+const (
+	ActionEmailVerification = "email_verification"
+	ActionPasswordReset     = "password_reset"
+	ActionMFAChallenge      = "mfa_challenge"
+)
+
+// ActionToken records a single-use signed token issued to a user, such as an
+// email verification link. A token is only accepted while its record exists.
+type ActionToken struct {
+	ID        string    `json:"id" firestore:"id"` // The token's jti
+	UserID    string    `json:"user_id" firestore:"userId"`
+	Purpose   string    `json:"purpose" firestore:"purpose"`
+	ExpiresAt time.Time `json:"expires_at" firestore:"expiresAt"`
+	CreatedAt time.Time `json:"created_at" firestore:"createdAt"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Two-factor methods offered in an MFA challenge
+const (
+	MFAMethodTOTP         = "totp"
+	MFAMethodRecoveryCode = "recovery_code"
+)
+
+// TwoFactor is a user's TOTP two-factor setup. It is pending from enrollment
+// until a first code confirms the authenticator app was set up, and only
+// enforced once Enabled.
+type TwoFactor struct {
+	Enabled   bool      `json:"enabled" firestore:"enabled"`
+	EnabledAt time.Time `json:"enabled_at,omitempty" firestore:"enabledAt,omitempty"`
+	// Secret is the base32 TOTP secret shared with the authenticator app
+	Secret string `json:"-" firestore:"secret"`
+	// LastStep is the time step of the last accepted code, so a code cannot be replayed
+	LastStep int64 `json:"-" firestore:"lastStep"`
+	// RecoveryCodes holds the SHA-256 hashes of the unused recovery codes
+	RecoveryCodes []string `json:"-" firestore:"recoveryCodes"`
+}
+
+// RecoveryCodesLeft returns how many recovery codes have not been used
+func (t *TwoFactor) RecoveryCodesLeft() int {
+	return len(t.RecoveryCodes)
+}
+
+// TwoFactorEnabled reports whether signing in requires a second factor
+func (u *User) TwoFactorEnabled() bool {
+	return u.TwoFactor != nil && u.TwoFactor.Enabled
+}
+
+// MFAChallenge is returned instead of AuthTokens when a sign-in needs a second
+// factor; the token is exchanged for AuthTokens together with a code
+type MFAChallenge struct {
+	MFARequired    bool     `json:"mfa_required"`
+	ChallengeToken string   `json:"mfa_token"`
+	Methods        []string `json:"methods"`
+	ExpiresIn      int64    `json:"expires_in"` // Challenge lifetime in seconds
+}
+
+// TOTPEnrollment is what a user needs to add their account to an authenticator app
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	// QRCode is the URI as a PNG QR code in a data: URI
+	QRCode string `json:"qr_code"`
+}
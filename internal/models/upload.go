@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// What an upload is for; each purpose decides where the finished upload goes
+const (
+	UploadPurposeProfilePicture = "profile_picture"
+)
+
+// Upload is a pending direct-to-storage upload. The client PUTs the file to
+// a presigned URL for Key, then completes the upload by ID.
+type Upload struct {
+	ID      string `json:"id" firestore:"id"`
+	UserID  string `json:"user_id" firestore:"userId"`
+	Purpose string `json:"purpose" firestore:"purpose"`
+	// Key is the object key the file is uploaded to
+	Key         string    `json:"key" firestore:"key"`
+	ContentType string    `json:"content_type" firestore:"contentType"`
+	MaxBytes    int64     `json:"max_bytes" firestore:"maxBytes"`
+	ExpiresAt   time.Time `json:"expires_at" firestore:"expiresAt"`
+	CreatedAt   time.Time `json:"created_at" firestore:"createdAt"`
+	// ClaimedAt is set while a completion processes the upload, zero before.
+	// Claims lapse after repository.UploadClaimTTL.
+	ClaimedAt time.Time `json:"claimed_at" firestore:"claimedAt"`
+}
+
+// UploadTicket tells the client where and how to send the file of an upload
+type UploadTicket struct {
+	UploadID string `json:"upload_id"`
+	URL      string `json:"upload_url"`
+	Method   string `json:"method"`
+	// Headers must be sent with the upload request exactly as given
+	Headers   map[string]string `json:"headers"`
+	MaxBytes  int64             `json:"max_bytes"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
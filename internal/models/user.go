@@ -1,14 +1,71 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type User struct {
-	ID        string       `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"` // Exclude password from JSON responses for security
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             string     `json:"id" firestore:"id"`
+	Email          string     `json:"email" firestore:"email"`
+	Username       string     `json:"username" firestore:"username"`
+	Password       string     `json:"-" firestore:"password,omitempty"`     // Exclude password from JSON responses for security
+	FCMToken       string     `json:"-" firestore:"fcmToken,omitempty"`     // Legacy, superseded by Session.FCMToken
+	IsGoogleUser   bool       `json:"-" firestore:"isGoogleUser,omitempty"` // Legacy, superseded by Identities
+	EmailVerified  bool       `json:"email_verified" firestore:"emailVerified"`
+	Roles          []string   `json:"roles" firestore:"roles"`
+	Identities     []Identity `json:"identities" firestore:"identities,omitempty"`
+	TwoFactor      *TwoFactor `json:"two_factor,omitempty" firestore:"twoFactor,omitempty"`
+	Name           string     `json:"name,omitempty" firestore:"name,omitempty"` // Display name
+	ProfilePicture string     `json:"-" firestore:"profile_picture,omitempty"`   // Avatar object key, a URL for avatars uploaded before keys were stored
+	Bio            string     `json:"bio,omitempty" firestore:"bio,omitempty"`
+	Phone          string     `json:"phone,omitempty" firestore:"phone,omitempty"` // E.164, e.g. +628123456789
+	PhoneVerified  bool       `json:"phone_verified" firestore:"phoneVerified"`
+	Location       string     `json:"location,omitempty" firestore:"location,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" firestore:"CreatedAt"`
+	UpdatedAt      time.Time  `json:"updated_at" firestore:"UpdatedAt"`
+
+	// ProfilePictureVariants holds the avatar object key of each size, keyed by edge length in pixels
+	ProfilePictureVariants map[string]string `json:"-" firestore:"profilePictureVariants,omitempty"`
+}
+
+// EffectiveRoles returns the user's roles; users stored before roles existed are plain users
+func (u *User) EffectiveRoles() []string {
+	if len(u.Roles) == 0 {
+		return []string{RoleUser}
+	}
+	return u.Roles
+}
+
+// DisplayName returns the name to show for the user, the username if none is set
+func (u *User) DisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+	return u.Username
+}
+
+// NormalizeEmail returns the canonical form of an email address used for
+// storage and uniqueness checks
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone strips the spaces, dashes, dots and parentheses people write
+// phone numbers with, leaving the form that is validated and stored
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
+
+// UsernameKey returns the key that makes usernames unique regardless of case
+func UsernameKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
 }
 
 type UpdateUserDTO struct {
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// ErrActionTokenNotFound is returned when the token was never issued or was already used
+var ErrActionTokenNotFound = errors.New("action token not found")
+
+// ActionTokenRepository tracks outstanding single-use action tokens
+type ActionTokenRepository interface {
+	// Create records a newly issued token
+	Create(ctx context.Context, token *models.ActionToken) error
+	// Consume atomically deletes and returns the token, failing with
+	// ErrActionTokenNotFound if it does not exist or has another purpose
+	Consume(ctx context.Context, id, purpose string) (*models.ActionToken, error)
+	// DeleteByUser deletes every outstanding token of the user for the purpose
+	DeleteByUser(ctx context.Context, userID, purpose string) error
+}
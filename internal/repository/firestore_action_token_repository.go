@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const actionTokensCollection = "action_tokens"
+
+// FirestoreActionTokenRepository stores action tokens in the Firestore "action_tokens" collection
+type FirestoreActionTokenRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreActionTokenRepository creates an ActionTokenRepository backed by Firestore
+func NewFirestoreActionTokenRepository(client *firestore.Client) *FirestoreActionTokenRepository {
+	return &FirestoreActionTokenRepository{client: client}
+}
+
+func (r *FirestoreActionTokenRepository) Create(ctx context.Context, token *models.ActionToken) error {
+	_, err := r.client.Collection(actionTokensCollection).Doc(token.ID).Create(ctx, token)
+	return err
+}
+
+func (r *FirestoreActionTokenRepository) Consume(ctx context.Context, id, purpose string) (*models.ActionToken, error) {
+	ref := r.client.Collection(actionTokensCollection).Doc(id)
+
+	var token models.ActionToken
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&token); err != nil {
+			return err
+		}
+		if token.Purpose != purpose {
+			return ErrActionTokenNotFound
+		}
+		return tx.Delete(ref)
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrActionTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *FirestoreActionTokenRepository) DeleteByUser(ctx context.Context, userID, purpose string) error {
+	docs, err := r.client.Collection(actionTokensCollection).
+		Where("userId", "==", userID).
+		Where("purpose", "==", purpose).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	loginAttemptsCollection = "login_attempts"
+	auditLogCollection      = "audit_log"
+)
+
+// FirestoreLoginAttemptRepository stores failure counters in the Firestore
+// "login_attempts" collection. Configure a TTL policy on expiresAt to have
+// Firestore delete stale counters.
+type FirestoreLoginAttemptRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreLoginAttemptRepository creates a LoginAttemptRepository backed by Firestore
+func NewFirestoreLoginAttemptRepository(client *firestore.Client) *FirestoreLoginAttemptRepository {
+	return &FirestoreLoginAttemptRepository{client: client}
+}
+
+func (r *FirestoreLoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	doc, err := r.ref(key).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attempt models.LoginAttempt
+	if err := doc.DataTo(&attempt); err != nil {
+		return nil, err
+	}
+	if time.Now().After(attempt.ExpiresAt) {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (r *FirestoreLoginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	ref := r.ref(key)
+
+	var attempt models.LoginAttempt
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var current *models.LoginAttempt
+		doc, err := tx.Get(ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			current = &models.LoginAttempt{}
+			if err := doc.DataTo(current); err != nil {
+				return err
+			}
+		}
+
+		attempt = nextLoginAttempt(current, key, at, window)
+		return tx.Set(ref, attempt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *FirestoreLoginAttemptRepository) Block(ctx context.Context, key string, until time.Time) error {
+	ref := r.ref(key)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		attempt := models.LoginAttempt{Key: key, ExpiresAt: until}
+		doc, err := tx.Get(ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			if err := doc.DataTo(&attempt); err != nil {
+				return err
+			}
+		}
+
+		attempt.BlockedUntil = until
+		if until.After(attempt.ExpiresAt) {
+			attempt.ExpiresAt = until
+		}
+		return tx.Set(ref, attempt)
+	})
+}
+
+func (r *FirestoreLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	_, err := r.ref(key).Delete(ctx)
+	return err
+}
+
+func (r *FirestoreLoginAttemptRepository) ref(key string) *firestore.DocumentRef {
+	return r.client.Collection(loginAttemptsCollection).Doc(reservationID(key))
+}
+
+// FirestoreAuditLogRepository appends events to the Firestore "audit_log" collection
+type FirestoreAuditLogRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreAuditLogRepository creates an AuditLogRepository backed by Firestore
+func NewFirestoreAuditLogRepository(client *firestore.Client) *FirestoreAuditLogRepository {
+	return &FirestoreAuditLogRepository{client: client}
+}
+
+func (r *FirestoreAuditLogRepository) Record(ctx context.Context, event *models.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	_, err := r.client.Collection(auditLogCollection).Doc(event.ID).Create(ctx, event)
+	return err
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/pkg/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const rateLimitsCollection = "rate_limits"
+
+// rateLimitDoc is a token bucket as stored in Firestore
+type rateLimitDoc struct {
+	ratelimit.Bucket
+	// ExpiresAt is when the bucket is full again; configure a TTL policy on it
+	// to have Firestore delete idle buckets
+	ExpiresAt time.Time `firestore:"expiresAt"`
+}
+
+// FirestoreRateLimitStore keeps token buckets in the Firestore "rate_limits"
+// collection, so limits hold across every API instance. Each request costs a
+// transaction, so prefer it for low-volume routes such as sign-in.
+type FirestoreRateLimitStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreRateLimitStore creates a ratelimit.Store backed by Firestore
+func NewFirestoreRateLimitStore(client *firestore.Client) *FirestoreRateLimitStore {
+	return &FirestoreRateLimitStore{client: client}
+}
+
+func (s *FirestoreRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	ref := s.client.Collection(rateLimitsCollection).Doc(reservationID(key))
+
+	var result ratelimit.Result
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var current rateLimitDoc
+		doc, err := tx.Get(ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			if err := doc.DataTo(&current); err != nil {
+				return err
+			}
+		}
+
+		var bucket ratelimit.Bucket
+		bucket, result = limit.Take(current.Bucket, time.Now())
+		return tx.Set(ref, rateLimitDoc{Bucket: bucket, ExpiresAt: limit.FullAt(bucket)})
+	})
+	return result, err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/pkg/ratelimit"
+	"github.com/google/uuid"
+)
+
+// newEmulatorClient connects to the Firestore emulator, skipping the test
+// when FIRESTORE_EMULATOR_HOST is not set
+func newEmulatorClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	client, err := firestore.NewClient(context.Background(), "bakulen-test")
+	if err != nil {
+		t.Fatalf("firestore.NewClient() = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestFirestoreRateLimitStoreTake(t *testing.T) {
+	ctx := context.Background()
+	store := NewFirestoreRateLimitStore(newEmulatorClient(t))
+	limit := ratelimit.Limit{Rate: 1.0 / 3600, Burst: 2}
+	key := "test:" + uuid.NewString()
+
+	for i, want := range []bool{true, true, false} {
+		result, err := store.Take(ctx, key, limit)
+		if err != nil {
+			t.Fatalf("Take() = %v", err)
+		}
+		if result.Allowed != want {
+			t.Fatalf("request %d allowed = %v, want %v", i, result.Allowed, want)
+		}
+	}
+
+	if result, err := store.Take(ctx, key+":other", limit); err != nil || !result.Allowed {
+		t.Fatalf("Take(other key) = %+v, %v, want allowed", result, err)
+	}
+}
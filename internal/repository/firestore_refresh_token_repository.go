@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const refreshTokensCollection = "refresh_tokens"
+
+// FirestoreRefreshTokenRepository stores refresh tokens in the Firestore "refresh_tokens" collection
+type FirestoreRefreshTokenRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreRefreshTokenRepository creates a RefreshTokenRepository backed by Firestore
+func NewFirestoreRefreshTokenRepository(client *firestore.Client) *FirestoreRefreshTokenRepository {
+	return &FirestoreRefreshTokenRepository{client: client}
+}
+
+func (r *FirestoreRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	_, err := r.client.Collection(refreshTokensCollection).Doc(token.ID).Create(ctx, token)
+	return err
+}
+
+func (r *FirestoreRefreshTokenRepository) Get(ctx context.Context, id string) (*models.RefreshToken, error) {
+	doc, err := r.client.Collection(refreshTokensCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token models.RefreshToken
+	if err := doc.DataTo(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *FirestoreRefreshTokenRepository) Consume(ctx context.Context, id string) (*models.RefreshToken, error) {
+	ref := r.client.Collection(refreshTokensCollection).Doc(id)
+
+	var token models.RefreshToken
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&token); err != nil {
+			return err
+		}
+		if token.Used {
+			return nil
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "used", Value: true}})
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token.Used {
+		return &token, ErrRefreshTokenReused
+	}
+	token.Used = true
+	return &token, nil
+}
+
+func (r *FirestoreRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	docs, err := r.client.Collection(refreshTokensCollection).Where("familyId", "==", familyID).Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+	return revokeRefreshTokenDocs(ctx, r.client, docs)
+}
+
+func (r *FirestoreRefreshTokenRepository) RevokeUser(ctx context.Context, userID, exceptFamilyID string) error {
+	docs, err := r.client.Collection(refreshTokensCollection).
+		Where("userId", "==", userID).
+		Where("revoked", "==", false).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+
+	if exceptFamilyID != "" {
+		kept := docs[:0]
+		for _, doc := range docs {
+			if familyID, _ := doc.Data()["familyId"].(string); familyID != exceptFamilyID {
+				kept = append(kept, doc)
+			}
+		}
+		docs = kept
+	}
+	return revokeRefreshTokenDocs(ctx, r.client, docs)
+}
+
+// revokeRefreshTokenDocs marks the given refresh token documents as revoked
+func revokeRefreshTokenDocs(ctx context.Context, client *firestore.Client, docs []*firestore.DocumentSnapshot) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	bulk := client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+	for _, doc := range docs {
+		job, err := bulk.Update(doc.Ref, []firestore.Update{{Path: "revoked", Value: true}})
+		if err != nil {
+			bulk.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bulk.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	revokedTokensCollection   = "revoked_tokens"
+	revokedSessionsCollection = "revoked_sessions"
+	revokedUsersCollection    = "revoked_users"
+)
+
+type revokedTokenDoc struct {
+	ExpiresAt time.Time `firestore:"expiresAt"`
+}
+
+type revokedUserDoc struct {
+	IssuedBefore    time.Time `firestore:"issuedBefore"`
+	ExceptSessionID string    `firestore:"exceptSessionId,omitempty"`
+	ExpiresAt       time.Time `firestore:"expiresAt"`
+}
+
+// FirestoreRevocationStore stores revocations in Firestore. Configure a TTL
+// policy on the expiresAt field of every collection to have them cleaned up.
+type FirestoreRevocationStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreRevocationStore creates a RevocationStore backed by Firestore
+func NewFirestoreRevocationStore(client *firestore.Client) *FirestoreRevocationStore {
+	return &FirestoreRevocationStore{client: client}
+}
+
+func (s *FirestoreRevocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	_, err := s.client.Collection(revokedTokensCollection).Doc(tokenID).Set(ctx, revokedTokenDoc{ExpiresAt: expiresAt})
+	return err
+}
+
+func (s *FirestoreRevocationStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return s.isRevoked(ctx, s.client.Collection(revokedTokensCollection).Doc(tokenID))
+}
+
+func (s *FirestoreRevocationStore) RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	_, err := s.client.Collection(revokedSessionsCollection).Doc(sessionID).Set(ctx, revokedTokenDoc{ExpiresAt: expiresAt})
+	return err
+}
+
+func (s *FirestoreRevocationStore) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	return s.isRevoked(ctx, s.client.Collection(revokedSessionsCollection).Doc(sessionID))
+}
+
+// isRevoked reports whether the revocation document at ref exists and has not expired
+func (s *FirestoreRevocationStore) isRevoked(ctx context.Context, ref *firestore.DocumentRef) (bool, error) {
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var revoked revokedTokenDoc
+	if err := doc.DataTo(&revoked); err != nil {
+		return false, err
+	}
+	return time.Now().Before(revoked.ExpiresAt), nil
+}
+
+func (s *FirestoreRevocationStore) RevokeUserTokens(ctx context.Context, userID string, revocation UserRevocation) error {
+	_, err := s.client.Collection(revokedUsersCollection).Doc(userID).Set(ctx, revokedUserDoc{
+		IssuedBefore:    revocation.IssuedBefore,
+		ExceptSessionID: revocation.ExceptSessionID,
+		ExpiresAt:       revocation.ExpiresAt,
+	})
+	return err
+}
+
+func (s *FirestoreRevocationStore) GetUserRevocation(ctx context.Context, userID string) (*UserRevocation, error) {
+	doc, err := s.client.Collection(revokedUsersCollection).Doc(userID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked revokedUserDoc
+	if err := doc.DataTo(&revoked); err != nil {
+		return nil, err
+	}
+	if time.Now().After(revoked.ExpiresAt) {
+		return nil, nil
+	}
+	return &UserRevocation{
+		IssuedBefore:    revoked.IssuedBefore,
+		ExceptSessionID: revoked.ExceptSessionID,
+		ExpiresAt:       revoked.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const sessionsCollection = "sessions"
+
+// FirestoreSessionRepository stores sessions in the "sessions" subcollection
+// of each user document. Configure a TTL policy on expiresAt for the
+// collection group to have Firestore delete expired sessions.
+type FirestoreSessionRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreSessionRepository creates a SessionRepository backed by Firestore
+func NewFirestoreSessionRepository(client *firestore.Client) *FirestoreSessionRepository {
+	return &FirestoreSessionRepository{client: client}
+}
+
+func (r *FirestoreSessionRepository) Save(ctx context.Context, session *models.Session) error {
+	_, err := r.sessions(session.UserID).Doc(session.ID).Set(ctx, session)
+	return err
+}
+
+func (r *FirestoreSessionRepository) Get(ctx context.Context, userID, sessionID string) (*models.Session, error) {
+	doc, err := r.sessions(userID).Doc(sessionID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session models.Session
+	if err := doc.DataTo(&session); err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (r *FirestoreSessionRepository) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	docs, err := r.sessions(userID).
+		Where("expiresAt", ">", time.Now()).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(docs))
+	for _, doc := range docs {
+		var session models.Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (r *FirestoreSessionRepository) Delete(ctx context.Context, userID, sessionID string) error {
+	_, err := r.sessions(userID).Doc(sessionID).Delete(ctx)
+	return err
+}
+
+func (r *FirestoreSessionRepository) DeleteByUser(ctx context.Context, userID, exceptSessionID string) error {
+	refs, err := r.sessions(userID).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+
+	bulk := r.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(refs))
+	for _, ref := range refs {
+		if ref.ID == exceptSessionID {
+			continue
+		}
+		job, err := bulk.Delete(ref)
+		if err != nil {
+			bulk.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bulk.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sessions returns the sessions subcollection of a user
+func (r *FirestoreSessionRepository) sessions(userID string) *firestore.CollectionRef {
+	return r.client.Collection(usersCollection).Doc(userID).Collection(sessionsCollection)
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const uploadsCollection = "uploads"
+
+// FirestoreUploadRepository stores pending uploads in the Firestore "uploads"
+// collection. UploadService purges expired uploads along with their files;
+// configure a TTL policy on expiresAt to drop any records it misses, whose
+// files are then left to the bucket's lifecycle rule.
+type FirestoreUploadRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreUploadRepository creates an UploadRepository backed by Firestore
+func NewFirestoreUploadRepository(client *firestore.Client) *FirestoreUploadRepository {
+	return &FirestoreUploadRepository{client: client}
+}
+
+func (r *FirestoreUploadRepository) Create(ctx context.Context, upload *models.Upload) error {
+	_, err := r.client.Collection(uploadsCollection).Doc(upload.ID).Create(ctx, upload)
+	return err
+}
+
+func (r *FirestoreUploadRepository) Get(ctx context.Context, id string) (*models.Upload, error) {
+	doc, err := r.client.Collection(uploadsCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var upload models.Upload
+	if err := doc.DataTo(&upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (r *FirestoreUploadRepository) Claim(ctx context.Context, id string) (*models.Upload, error) {
+	ref := r.client.Collection(uploadsCollection).Doc(id)
+	var upload models.Upload
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&upload); err != nil {
+			return err
+		}
+		now := time.Now()
+		if ClaimHeld(&upload, now) {
+			return ErrUploadClaimed
+		}
+		upload.ClaimedAt = now
+		return tx.Update(ref, []firestore.Update{{Path: "claimedAt", Value: upload.ClaimedAt}})
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (r *FirestoreUploadRepository) Release(ctx context.Context, id string) error {
+	_, err := r.client.Collection(uploadsCollection).Doc(id).Update(ctx, []firestore.Update{{Path: "claimedAt", Value: time.Time{}}})
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	return err
+}
+
+func (r *FirestoreUploadRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.client.Collection(uploadsCollection).Doc(id).Delete(ctx)
+	return err
+}
+
+func (r *FirestoreUploadRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]models.Upload, error) {
+	docs, err := r.client.Collection(uploadsCollection).
+		Where("expiresAt", "<", before).
+		OrderBy("expiresAt", firestore.Asc).
+		Limit(limit).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]models.Upload, 0, len(docs))
+	for _, doc := range docs {
+		var upload models.Upload
+		if err := doc.DataTo(&upload); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"google.golang.org/api/iterator"
+)
+
+// BackfillReport sums up what BackfillReservations changed
+type BackfillReport struct {
+	// Users is how many users were checked
+	Users int
+	// Normalized is how many stored emails were rewritten in their normalized form
+	Normalized int
+	// Reserved is how many reservation documents were created
+	Reserved int
+	// Conflicts describes the values held by more than one user, which are
+	// left for an operator to resolve
+	Conflicts []string
+}
+
+// BackfillReservations prepares users stored before emails were normalized
+// and reserved: each user's email is normalized and the reservations of its
+// email, username and identities are created where missing. It is safe to run
+// more than once and while the API is serving.
+func (r *FirestoreUserRepository) BackfillReservations(ctx context.Context) (*BackfillReport, error) {
+	report := &BackfillReport{}
+	refs := r.client.Collection(usersCollection).DocumentRefs(ctx)
+	for {
+		ref, err := refs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		if err := r.backfillUser(ctx, ref, report); err != nil {
+			return report, fmt.Errorf("failed to backfill user %s: %v", ref.ID, err)
+		}
+		report.Users++
+	}
+	return report, nil
+}
+
+// backfillUser normalizes one user's email and claims its missing reservations
+func (r *FirestoreUserRepository) backfillUser(ctx context.Context, ref *firestore.DocumentRef, report *BackfillReport) error {
+	var normalized, reserved int
+	var conflicts []string
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		normalized, reserved, conflicts = 0, 0, nil
+
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		user, err := userFromSnapshot(doc)
+		if err != nil {
+			return err
+		}
+
+		var claims []*firestore.DocumentRef
+		emailTaken := false
+		for _, key := range r.reservedKeys(user) {
+			holder, err := reservationHolder(tx, key.ref)
+			if err != nil {
+				return err
+			}
+			switch holder {
+			case "":
+				claims = append(claims, key.ref)
+			case user.ID:
+			default:
+				emailTaken = emailTaken || key.taken == ErrEmailTaken
+				conflicts = append(conflicts, fmt.Sprintf("user %s: %v (reserved by user %s)", user.ID, key.taken, holder))
+			}
+		}
+
+		// An email held by another user keeps its stored form until resolved
+		email := models.NormalizeEmail(user.Email)
+		if email != user.Email && !emailTaken {
+			if err := tx.Update(ref, []firestore.Update{{Path: "email", Value: email}}); err != nil {
+				return err
+			}
+			normalized++
+		}
+		claim := reservation{UserID: user.ID, CreatedAt: time.Now()}
+		for _, claimRef := range claims {
+			if err := tx.Create(claimRef, claim); err != nil {
+				return err
+			}
+			reserved++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	report.Normalized += normalized
+	report.Reserved += reserved
+	report.Conflicts = append(report.Conflicts, conflicts...)
+	return nil
+}
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	usersCollection      = "users"
+	emailsCollection     = "emails"
+	usernamesCollection  = "usernames"
+	identitiesCollection = "identities"
+)
+
+// reservation claims a unique value for one user. Reservations live in the
+// "emails", "usernames" and "identities" collections keyed by the normalized
+// value, so a transaction can check and claim them atomically, which a query
+// cannot do.
+type reservation struct {
+	UserID    string    `firestore:"userId"`
+	CreatedAt time.Time `firestore:"createdAt"`
+}
+
+// reservedKey is one reservation document a user holds, with the error
+// returned when another user holds it
+type reservedKey struct {
+	ref   *firestore.DocumentRef
+	taken error
+	// legacy finds users stored before reservations existed that have the
+	// value without holding its reservation; nil for identities, which were
+	// always reserved
+	legacy *firestore.Query
+}
+
+// FirestoreUserRepository stores users in the Firestore "users" collection
+type FirestoreUserRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreUserRepository creates a UserRepository backed by Firestore
+func NewFirestoreUserRepository(client *firestore.Client) *FirestoreUserRepository {
+	return &FirestoreUserRepository{client: client}
+}
+
+func (r *FirestoreUserRepository) Create(ctx context.Context, user *models.User) error {
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+
+	ref := r.client.Collection(usersCollection).Doc(user.ID)
+	keys := r.reservedKeys(user)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Firestore requires every read of a transaction to happen before its writes
+		for _, key := range keys {
+			if err := checkReservation(tx, key, user.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(ref, user); err != nil {
+			return err
+		}
+		claim := reservation{UserID: user.ID, CreatedAt: now}
+		for _, key := range keys {
+			if err := tx.Set(key.ref, claim); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrUserExists
+	}
+	return err
+}
+
+func (r *FirestoreUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	doc, err := r.client.Collection(usersCollection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return userFromSnapshot(doc)
+}
+
+func (r *FirestoreUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := r.getByReservation(ctx, r.emailRef(email))
+	if err != ErrUserNotFound {
+		return user, err
+	}
+
+	// Users registered before email reservations existed only have the field,
+	// as typed until BackfillReservations normalizes it
+	docs, err := r.legacyQuery("email", strings.TrimSpace(email), models.NormalizeEmail(email)).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return userFromSnapshot(docs[0])
+}
+
+func (r *FirestoreUserRepository) GetByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	identity := models.Identity{Provider: provider, Subject: subject}
+	return r.getByReservation(ctx, r.identityRef(identity))
+}
+
+// Update runs mutate on the user read inside the transaction, so Firestore
+// retries it when the document changes before the transaction commits
+func (r *FirestoreUserRepository) Update(ctx context.Context, id string, mutate func(user *models.User) error) (*models.User, error) {
+	ref := r.client.Collection(usersCollection).Doc(id)
+	var user *models.User
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		current, err := userFromSnapshot(doc)
+		if err != nil {
+			return err
+		}
+		// Decode a second copy for mutate, so current keeps the stored values
+		user, err = userFromSnapshot(doc)
+		if err != nil {
+			return err
+		}
+		if err := mutate(user); err != nil {
+			return err
+		}
+		now := time.Now()
+		user.ID = id
+		user.UpdatedAt = now
+
+		// Claim the reservations of changed values and release the old ones
+		held := make(map[string]bool)
+		for _, key := range r.reservedKeys(current) {
+			held[key.ref.Path] = true
+		}
+		var claims []reservedKey
+		for _, key := range r.reservedKeys(user) {
+			if held[key.ref.Path] {
+				delete(held, key.ref.Path)
+				continue
+			}
+			if err := checkReservation(tx, key, user.ID); err != nil {
+				return err
+			}
+			claims = append(claims, key)
+		}
+		// Only release reservations that are the user's; legacy users may not
+		// hold theirs, and another user may have claimed it since
+		var releases []*firestore.DocumentRef
+		for _, key := range r.reservedKeys(current) {
+			if !held[key.ref.Path] {
+				continue
+			}
+			holder, err := reservationHolder(tx, key.ref)
+			if err != nil {
+				return err
+			}
+			if holder == id {
+				releases = append(releases, key.ref)
+			}
+		}
+
+		for _, ref := range releases {
+			if err := tx.Delete(ref); err != nil {
+				return err
+			}
+		}
+		claim := reservation{UserID: user.ID, CreatedAt: now}
+		for _, key := range claims {
+			if err := tx.Set(key.ref, claim); err != nil {
+				return err
+			}
+		}
+		return tx.Set(ref, user)
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// getByReservation returns the user holding the reservation at ref
+func (r *FirestoreUserRepository) getByReservation(ctx context.Context, ref *firestore.DocumentRef) (*models.User, error) {
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var claim reservation
+	if err := doc.DataTo(&claim); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, claim.UserID)
+}
+
+// reservedKeys lists the reservation documents the user must hold
+func (r *FirestoreUserRepository) reservedKeys(user *models.User) []reservedKey {
+	var keys []reservedKey
+	if user.Email != "" {
+		keys = append(keys, reservedKey{r.emailRef(user.Email), ErrEmailTaken,
+			r.legacyQuery("email", user.Email, models.NormalizeEmail(user.Email))})
+	}
+	keys = append(keys, reservedKey{r.usernameRef(user.Username), ErrUsernameTaken,
+		r.legacyQuery("username", user.Username, models.UsernameKey(user.Username))})
+	for _, identity := range user.Identities {
+		if identity.Key() != "" {
+			keys = append(keys, reservedKey{r.identityRef(identity), ErrIdentityTaken, nil})
+		}
+	}
+	return keys
+}
+
+// legacyQuery finds users whose field has one of the values. Legacy documents
+// store values as typed, so both the typed and the normalized form are matched;
+// other spellings are only found once BackfillReservations has run.
+func (r *FirestoreUserRepository) legacyQuery(field, typed, normalized string) *firestore.Query {
+	values := []string{normalized}
+	if typed != normalized {
+		values = append(values, typed)
+	}
+	query := r.client.Collection(usersCollection).Where(field, "in", values).Limit(2)
+	return &query
+}
+
+// checkReservation fails with the key's error if another user than userID holds it
+func checkReservation(tx *firestore.Transaction, key reservedKey, userID string) error {
+	holder, err := reservationHolder(tx, key.ref)
+	if err != nil {
+		return err
+	}
+	if holder == "" {
+		return checkLegacyHolder(tx, key, userID)
+	}
+	if holder != userID {
+		return key.taken
+	}
+	return nil
+}
+
+// checkLegacyHolder fails with the key's error if a user stored before
+// reservations existed has the value without holding its reservation
+func checkLegacyHolder(tx *firestore.Transaction, key reservedKey, userID string) error {
+	if key.legacy == nil {
+		return nil
+	}
+	docs, err := tx.Documents(*key.legacy).GetAll()
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if doc.Ref.ID != userID {
+			return key.taken
+		}
+	}
+	return nil
+}
+
+// reservationHolder returns the ID of the user holding the reservation at ref,
+// or an empty string if nobody does
+func reservationHolder(tx *firestore.Transaction, ref *firestore.DocumentRef) (string, error) {
+	doc, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var claim reservation
+	if err := doc.DataTo(&claim); err != nil {
+		return "", err
+	}
+	return claim.UserID, nil
+}
+
+// emailRef returns the reservation document of an email address
+func (r *FirestoreUserRepository) emailRef(email string) *firestore.DocumentRef {
+	return r.client.Collection(emailsCollection).Doc(reservationID(models.NormalizeEmail(email)))
+}
+
+// usernameRef returns the reservation document of a username
+func (r *FirestoreUserRepository) usernameRef(username string) *firestore.DocumentRef {
+	return r.client.Collection(usernamesCollection).Doc(reservationID(models.UsernameKey(username)))
+}
+
+// identityRef returns the reservation document of an external identity
+func (r *FirestoreUserRepository) identityRef(identity models.Identity) *firestore.DocumentRef {
+	return r.client.Collection(identitiesCollection).Doc(reservationID(identity.Key()))
+}
+
+// reservationID escapes a value for use as a document ID, which may not contain slashes
+func reservationID(value string) string {
+	return url.PathEscape(value)
+}
+
+// userFromSnapshot maps a Firestore document onto a User
+func userFromSnapshot(doc *firestore.DocumentSnapshot) (*models.User, error) {
+	var user models.User
+	if err := doc.DataTo(&user); err != nil {
+		return nil, err
+	}
+	// Firestore does not include the document ID in the fields
+	user.ID = doc.Ref.ID
+	return &user, nil
+}
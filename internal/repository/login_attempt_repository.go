@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// LoginAttemptRepository counts failed sign-ins per account and per client IP
+type LoginAttemptRepository interface {
+	// Get returns the record for key, or nil if there is none
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordFailure atomically counts a failed attempt at the given time and
+	// returns the updated record. Failures are counted afresh once the last
+	// one is older than window.
+	RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempt, error)
+	// Block refuses attempts for key until the given time
+	Block(ctx context.Context, key string, until time.Time) error
+	// Reset forgets the failures of key
+	Reset(ctx context.Context, key string) error
+}
+
+// AuditLogRepository stores audit events
+type AuditLogRepository interface {
+	// Record stores an event
+	Record(ctx context.Context, event *models.AuditEvent) error
+}
+
+// nextLoginAttempt applies one failure at the given time to the current record
+func nextLoginAttempt(current *models.LoginAttempt, key string, at time.Time, window time.Duration) models.LoginAttempt {
+	attempt := models.LoginAttempt{Key: key, Failures: 1, FirstFailure: at}
+	if current != nil && at.Sub(current.LastFailure) <= window {
+		attempt = *current
+		attempt.Failures++
+	}
+	attempt.LastFailure = at
+	attempt.ExpiresAt = at.Add(window)
+	if attempt.BlockedUntil.After(attempt.ExpiresAt) {
+		attempt.ExpiresAt = attempt.BlockedUntil
+	}
+	return attempt
+}
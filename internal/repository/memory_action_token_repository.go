@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// MemoryActionTokenRepository keeps action tokens in process memory
+type MemoryActionTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]models.ActionToken
+}
+
+// NewMemoryActionTokenRepository creates an empty in-memory ActionTokenRepository
+func NewMemoryActionTokenRepository() *MemoryActionTokenRepository {
+	return &MemoryActionTokenRepository{tokens: make(map[string]models.ActionToken)}
+}
+
+func (r *MemoryActionTokenRepository) Create(ctx context.Context, token *models.ActionToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *MemoryActionTokenRepository) Consume(ctx context.Context, id, purpose string) (*models.ActionToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.Purpose != purpose {
+		return nil, ErrActionTokenNotFound
+	}
+	delete(r.tokens, id)
+	return &token, nil
+}
+
+func (r *MemoryActionTokenRepository) DeleteByUser(ctx context.Context, userID, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
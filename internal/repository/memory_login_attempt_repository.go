@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/google/uuid"
+)
+
+// MemoryLoginAttemptRepository keeps failure counters in process memory and drops them once they expire
+type MemoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+// NewMemoryLoginAttemptRepository creates an empty in-memory LoginAttemptRepository
+func NewMemoryLoginAttemptRepository() *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{attempts: make(map[string]models.LoginAttempt)}
+}
+
+func (r *MemoryLoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok || time.Now().After(attempt.ExpiresAt) {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (r *MemoryLoginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.purgeExpired(at)
+	var current *models.LoginAttempt
+	if existing, ok := r.attempts[key]; ok {
+		current = &existing
+	}
+	attempt := nextLoginAttempt(current, key, at, window)
+	r.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (r *MemoryLoginAttemptRepository) Block(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	attempt.BlockedUntil = until
+	if until.After(attempt.ExpiresAt) {
+		attempt.ExpiresAt = until
+	}
+	r.attempts[key] = attempt
+	return nil
+}
+
+func (r *MemoryLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// purgeExpired drops expired records; the caller holds r.mu
+func (r *MemoryLoginAttemptRepository) purgeExpired(now time.Time) {
+	for key, attempt := range r.attempts {
+		if now.After(attempt.ExpiresAt) {
+			delete(r.attempts, key)
+		}
+	}
+}
+
+// MemoryAuditLogRepository keeps audit events in process memory and echoes
+// them to the log, leaving out the email and IP
+type MemoryAuditLogRepository struct {
+	mu     sync.Mutex
+	events []models.AuditEvent
+}
+
+// NewMemoryAuditLogRepository creates an empty in-memory AuditLogRepository
+func NewMemoryAuditLogRepository() *MemoryAuditLogRepository {
+	return &MemoryAuditLogRepository{}
+}
+
+func (r *MemoryAuditLogRepository) Record(ctx context.Context, event *models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	r.events = append(r.events, *event)
+	log.Printf("[AUDIT] %s user=%s %v", event.Type, event.UserID, event.Details)
+	return nil
+}
+
+// Events returns a copy of the recorded events
+func (r *MemoryAuditLogRepository) Events() []models.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]models.AuditEvent(nil), r.events...)
+}
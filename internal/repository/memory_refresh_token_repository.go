@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// MemoryRefreshTokenRepository keeps refresh tokens in process memory
+type MemoryRefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]models.RefreshToken
+}
+
+// NewMemoryRefreshTokenRepository creates an empty in-memory RefreshTokenRepository
+func NewMemoryRefreshTokenRepository() *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{tokens: make(map[string]models.RefreshToken)}
+}
+
+func (r *MemoryRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) Get(ctx context.Context, id string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return &token, nil
+}
+
+func (r *MemoryRefreshTokenRepository) Consume(ctx context.Context, id string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if token.Used {
+		return &token, ErrRefreshTokenReused
+	}
+	token.Used = true
+	r.tokens[id] = token
+	return &token, nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeUser(ctx context.Context, userID, exceptFamilyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID && (exceptFamilyID == "" || token.FamilyID != exceptFamilyID) {
+			token.Revoked = true
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryRevocationStore keeps revocations in process memory and drops them once they expire
+type MemoryRevocationStore struct {
+	mu       sync.Mutex
+	tokens   map[string]time.Time
+	sessions map[string]time.Time
+	users    map[string]UserRevocation
+}
+
+// NewMemoryRevocationStore creates an empty in-memory RevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[string]time.Time),
+		users:    make(map[string]UserRevocation),
+	}
+}
+
+func (s *MemoryRevocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeExpired(time.Now())
+	s.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.tokens[tokenID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (s *MemoryRevocationStore) RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeExpired(time.Now())
+	s.sessions[sessionID] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.sessions[sessionID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (s *MemoryRevocationStore) RevokeUserTokens(ctx context.Context, userID string, revocation UserRevocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeExpired(time.Now())
+	s.users[userID] = revocation
+	return nil
+}
+
+func (s *MemoryRevocationStore) GetUserRevocation(ctx context.Context, userID string) (*UserRevocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revocation, ok := s.users[userID]
+	if !ok || time.Now().After(revocation.ExpiresAt) {
+		return nil, nil
+	}
+	return &revocation, nil
+}
+
+// purgeExpired drops entries whose tokens would have expired anyway; callers hold mu
+func (s *MemoryRevocationStore) purgeExpired(now time.Time) {
+	for id, expiresAt := range s.tokens {
+		if now.After(expiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	for id, expiresAt := range s.sessions {
+		if now.After(expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	for id, revocation := range s.users {
+		if now.After(revocation.ExpiresAt) {
+			delete(s.users, id)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// MemorySessionRepository keeps sessions in process memory
+type MemorySessionRepository struct {
+	mu sync.Mutex
+	// sessions maps user IDs to their sessions by ID
+	sessions map[string]map[string]models.Session
+}
+
+// NewMemorySessionRepository creates an empty in-memory SessionRepository
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: make(map[string]map[string]models.Session)}
+}
+
+func (r *MemorySessionRepository) Save(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sessions[session.UserID] == nil {
+		r.sessions[session.UserID] = make(map[string]models.Session)
+	}
+	r.sessions[session.UserID][session.ID] = *session
+	return nil
+}
+
+func (r *MemorySessionRepository) Get(ctx context.Context, userID, sessionID string) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[userID][sessionID]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (r *MemorySessionRepository) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	sessions := make([]models.Session, 0, len(r.sessions[userID]))
+	for id, session := range r.sessions[userID] {
+		if now.After(session.ExpiresAt) {
+			delete(r.sessions[userID], id)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (r *MemorySessionRepository) Delete(ctx context.Context, userID, sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions[userID], sessionID)
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteByUser(ctx context.Context, userID, exceptSessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id := range r.sessions[userID] {
+		if id != exceptSessionID {
+			delete(r.sessions[userID], id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// MemoryUploadRepository keeps pending uploads in process memory
+type MemoryUploadRepository struct {
+	mu      sync.Mutex
+	uploads map[string]models.Upload
+}
+
+// NewMemoryUploadRepository creates an empty in-memory UploadRepository
+func NewMemoryUploadRepository() *MemoryUploadRepository {
+	return &MemoryUploadRepository{uploads: make(map[string]models.Upload)}
+}
+
+func (r *MemoryUploadRepository) Create(ctx context.Context, upload *models.Upload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.uploads[upload.ID] = *upload
+	return nil
+}
+
+func (r *MemoryUploadRepository) Get(ctx context.Context, id string) (*models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload, ok := r.uploads[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	return &upload, nil
+}
+
+func (r *MemoryUploadRepository) Claim(ctx context.Context, id string) (*models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	upload, ok := r.uploads[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	now := time.Now()
+	if ClaimHeld(&upload, now) {
+		return nil, ErrUploadClaimed
+	}
+	upload.ClaimedAt = now
+	r.uploads[id] = upload
+	return &upload, nil
+}
+
+func (r *MemoryUploadRepository) Release(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if upload, ok := r.uploads[id]; ok {
+		upload.ClaimedAt = time.Time{}
+		r.uploads[id] = upload
+	}
+	return nil
+}
+
+func (r *MemoryUploadRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.uploads, id)
+	return nil
+}
+
+func (r *MemoryUploadRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]models.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []models.Upload
+	for _, upload := range r.uploads {
+		if upload.ExpiresAt.Before(before) {
+			expired = append(expired, upload)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
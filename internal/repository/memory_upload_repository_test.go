@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+func TestUploadClaimLapses(t *testing.T) {
+	uploads := NewMemoryUploadRepository()
+	ctx := context.Background()
+	if err := uploads.Create(ctx, &models.Upload{ID: "u1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	if _, err := uploads.Claim(ctx, "u1"); err != nil {
+		t.Fatalf("Claim() = %v", err)
+	}
+	if _, err := uploads.Claim(ctx, "u1"); !errors.Is(err, ErrUploadClaimed) {
+		t.Fatalf("Claim() while claimed = %v, want ErrUploadClaimed", err)
+	}
+
+	// A completion that died holds the upload only until its claim lapses
+	upload, err := uploads.Get(ctx, "u1")
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	upload.ClaimedAt = time.Now().Add(-UploadClaimTTL)
+	if err := uploads.Create(ctx, upload); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	if _, err := uploads.Claim(ctx, "u1"); err != nil {
+		t.Fatalf("Claim() after the claim lapsed = %v", err)
+	}
+}
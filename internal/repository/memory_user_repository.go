@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// MemoryUserRepository keeps users in process memory, for tests and local development
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]models.User
+	// reserved maps the unique values of each user (see reservedKeys) to its ID
+	reserved map[string]string
+}
+
+// NewMemoryUserRepository creates an empty in-memory UserRepository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:    make(map[string]models.User),
+		reserved: make(map[string]string),
+	}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; exists {
+		return ErrUserExists
+	}
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+	r.users[user.ID] = cloneUser(user)
+	r.reserve(user)
+	return nil
+}
+
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user = cloneUser(&user)
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.getByKey("email:" + models.NormalizeEmail(email))
+}
+
+func (r *MemoryUserRepository) GetByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	identity := models.Identity{Provider: provider, Subject: subject}
+	return r.getByKey("identity:" + identity.Key())
+}
+
+// Update holds the lock while mutate runs, so updates of a user happen one at a time
+func (r *MemoryUserRepository) Update(ctx context.Context, id string, mutate func(user *models.User) error) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.users[id]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	user := cloneUser(&current)
+	if err := mutate(&user); err != nil {
+		return nil, err
+	}
+	user.ID = id
+	if err := r.checkUnique(&user); err != nil {
+		return nil, err
+	}
+
+	for _, k := range reservedKeys(&current) {
+		if r.reserved[k.key] == id {
+			delete(r.reserved, k.key)
+		}
+	}
+	user.UpdatedAt = time.Now()
+	r.users[id] = cloneUser(&user)
+	r.reserve(&user)
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) getByKey(key string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.reserved[key]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := r.users[id]
+	user = cloneUser(&user)
+	return &user, nil
+}
+
+// cloneUser copies a user deeply, so callers cannot change stored users
+// without calling Update, just like with a database
+func cloneUser(user *models.User) models.User {
+	clone := *user
+	clone.Roles = append([]string(nil), user.Roles...)
+	clone.Identities = append([]models.Identity(nil), user.Identities...)
+	if user.TwoFactor != nil {
+		twoFactor := *user.TwoFactor
+		twoFactor.RecoveryCodes = append([]string(nil), user.TwoFactor.RecoveryCodes...)
+		clone.TwoFactor = &twoFactor
+	}
+	if user.ProfilePictureVariants != nil {
+		clone.ProfilePictureVariants = make(map[string]string, len(user.ProfilePictureVariants))
+		for size, key := range user.ProfilePictureVariants {
+			clone.ProfilePictureVariants[size] = key
+		}
+	}
+	return clone
+}
+
+// checkUnique fails if another user holds one of the user's unique keys
+func (r *MemoryUserRepository) checkUnique(user *models.User) error {
+	for _, k := range reservedKeys(user) {
+		if id, ok := r.reserved[k.key]; ok && id != user.ID {
+			return k.taken
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) reserve(user *models.User) {
+	for _, k := range reservedKeys(user) {
+		r.reserved[k.key] = user.ID
+	}
+}
+
+// memoryKey is a value that must be unique across users, with the error
+// returned when another user holds it
+type memoryKey struct {
+	key   string
+	taken error
+}
+
+// reservedKeys lists the unique values of the user
+func reservedKeys(user *models.User) []memoryKey {
+	var keys []memoryKey
+	if user.Email != "" {
+		keys = append(keys, memoryKey{"email:" + models.NormalizeEmail(user.Email), ErrEmailTaken})
+	}
+	keys = append(keys, memoryKey{"username:" + models.UsernameKey(user.Username), ErrUsernameTaken})
+	for _, identity := range user.Identities {
+		if identity.Key() != "" {
+			keys = append(keys, memoryKey{"identity:" + identity.Key(), ErrIdentityTaken})
+		}
+	}
+	return keys
+}
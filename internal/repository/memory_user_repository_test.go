@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+func newTestUser(id, email, username string, identities ...models.Identity) *models.User {
+	return &models.User{ID: id, Email: email, Username: username, Identities: identities}
+}
+
+func TestMemoryUserRepositoryCreateUniqueness(t *testing.T) {
+	google := models.Identity{Provider: "google", Subject: "123"}
+
+	tests := []struct {
+		name string
+		user *models.User
+		want error
+	}{
+		{"distinct user", newTestUser("u2", "bob@example.com", "bob"), nil},
+		{"same ID", newTestUser("u1", "carol@example.com", "carol"), ErrUserExists},
+		{"same email", newTestUser("u2", "alice@example.com", "bob"), ErrEmailTaken},
+		{"email differing in case and spaces", newTestUser("u2", " Alice@Example.COM ", "bob"), ErrEmailTaken},
+		{"same username", newTestUser("u2", "bob@example.com", "alice"), ErrUsernameTaken},
+		{"username differing in case", newTestUser("u2", "bob@example.com", "ALICE"), ErrUsernameTaken},
+		{"same identity", newTestUser("u2", "bob@example.com", "bob", google), ErrIdentityTaken},
+		{"same provider, other subject", newTestUser("u2", "bob@example.com", "bob", models.Identity{Provider: "google", Subject: "456"}), nil},
+		{"without email", newTestUser("u2", "", "bob"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryUserRepository()
+			if err := repo.Create(ctx, newTestUser("u1", "alice@example.com", "alice", google)); err != nil {
+				t.Fatalf("Create(alice) = %v", err)
+			}
+
+			err := repo.Create(ctx, tt.user)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Create() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryUserRepositoryLookups(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	google := models.Identity{Provider: "google", Subject: "123"}
+	if err := repo.Create(ctx, newTestUser("u1", "alice@example.com", "alice", google)); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		lookup func() (*models.User, error)
+		wantID string
+	}{
+		{"by ID", func() (*models.User, error) { return repo.GetByID(ctx, "u1") }, "u1"},
+		{"by email", func() (*models.User, error) { return repo.GetByEmail(ctx, "alice@example.com") }, "u1"},
+		{"by email as typed", func() (*models.User, error) { return repo.GetByEmail(ctx, " ALICE@example.com") }, "u1"},
+		{"by identity", func() (*models.User, error) { return repo.GetByIdentity(ctx, "google", "123") }, "u1"},
+		{"unknown ID", func() (*models.User, error) { return repo.GetByID(ctx, "u2") }, ""},
+		{"unknown email", func() (*models.User, error) { return repo.GetByEmail(ctx, "bob@example.com") }, ""},
+		{"unknown identity", func() (*models.User, error) { return repo.GetByIdentity(ctx, "google", "456") }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := tt.lookup()
+			if tt.wantID == "" {
+				if !errors.Is(err, ErrUserNotFound) {
+					t.Fatalf("lookup = %v, %v, want ErrUserNotFound", user, err)
+				}
+				return
+			}
+			if err != nil || user.ID != tt.wantID {
+				t.Fatalf("lookup = %v, %v, want user %s", user, err, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMemoryUserRepositoryUpdateReservations(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(u *models.User)
+		want   error
+		// freed is an email the update must have released for other users
+		freed string
+	}{
+		{"change email", func(u *models.User) { u.Email = "alice2@example.com" }, nil, "alice@example.com"},
+		{"take another user's email", func(u *models.User) { u.Email = "BOB@example.com" }, ErrEmailTaken, ""},
+		{"take another user's username", func(u *models.User) { u.Username = "Bob" }, ErrUsernameTaken, ""},
+		{"take another user's identity", func(u *models.User) {
+			u.LinkIdentity(models.Identity{Provider: "google", Subject: "bob"})
+		}, ErrIdentityTaken, ""},
+		{"change case of own username", func(u *models.User) { u.Username = "ALICE" }, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryUserRepository()
+			bob := newTestUser("u2", "bob@example.com", "bob", models.Identity{Provider: "google", Subject: "bob"})
+			for _, user := range []*models.User{newTestUser("u1", "alice@example.com", "alice"), bob} {
+				if err := repo.Create(ctx, user); err != nil {
+					t.Fatalf("Create() = %v", err)
+				}
+			}
+
+			_, err := repo.Update(ctx, "u1", func(u *models.User) error {
+				tt.mutate(u)
+				return nil
+			})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Update() = %v, want %v", err, tt.want)
+			}
+
+			// A failed update keeps the user's and the other user's values reserved
+			if user, err := repo.GetByEmail(ctx, "bob@example.com"); err != nil || user.ID != "u2" {
+				t.Fatalf("GetByEmail(bob) = %v, %v, want u2", user, err)
+			}
+			if tt.freed != "" {
+				if err := repo.Create(ctx, newTestUser("u3", tt.freed, "carol")); err != nil {
+					t.Fatalf("Create() with released email = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryUserRepositoryUpdateErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	if err := repo.Create(ctx, newTestUser("u1", "alice@example.com", "alice")); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	if _, err := repo.Update(ctx, "missing", func(u *models.User) error { return nil }); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("Update(missing) = %v, want ErrUserNotFound", err)
+	}
+
+	errAbort := errors.New("abort")
+	_, err := repo.Update(ctx, "u1", func(u *models.User) error {
+		u.Name = "changed"
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Update() = %v, want the mutation's error", err)
+	}
+	if user, _ := repo.GetByID(ctx, "u1"); user.Name != "" {
+		t.Fatalf("aborted update stored Name %q", user.Name)
+	}
+}
+
+func TestMemoryUserRepositoryConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	if err := repo.Create(ctx, newTestUser("u1", "alice@example.com", "alice")); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	// Each update appends a role to what it read; a lost update drops one
+	const updates = 50
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Update(ctx, "u1", func(u *models.User) error {
+				u.Roles = append(u.Roles, "role")
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update() = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	user, err := repo.GetByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetByID() = %v", err)
+	}
+	if len(user.Roles) != updates {
+		t.Fatalf("got %d roles after %d concurrent updates", len(user.Roles), updates)
+	}
+}
+
+func TestMemoryUserRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	if err := repo.Create(ctx, newTestUser("u1", "alice@example.com", "alice")); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	user, _ := repo.GetByID(ctx, "u1")
+	user.Email = "mallory@example.com"
+	user.Roles = append(user.Roles, models.RoleAdmin)
+
+	stored, _ := repo.GetByID(ctx, "u1")
+	if stored.Email != "alice@example.com" || len(stored.Roles) != 0 {
+		t.Fatalf("changing a returned user changed the stored one: %+v", stored)
+	}
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+var (
+	// ErrRefreshTokenNotFound is returned when no refresh token matches the hash
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenReused is returned by Consume when the token was already rotated
+	ErrRefreshTokenReused = errors.New("refresh token already used")
+)
+
+// RefreshTokenRepository persists refresh tokens for rotation and reuse detection
+type RefreshTokenRepository interface {
+	// Create stores a newly issued refresh token
+	Create(ctx context.Context, token *models.RefreshToken) error
+	// Get returns the token with the given hash or ErrRefreshTokenNotFound
+	Get(ctx context.Context, id string) (*models.RefreshToken, error)
+	// Consume atomically marks the token as used and returns it. If it was
+	// already used, the token is returned together with ErrRefreshTokenReused.
+	Consume(ctx context.Context, id string) (*models.RefreshToken, error)
+	// RevokeFamily revokes every token rotated from the same sign-in
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeUser revokes every refresh token issued to the user, except those
+	// of the family exceptFamilyID when it is not empty
+	RevokeUser(ctx context.Context, userID, exceptFamilyID string) error
+}
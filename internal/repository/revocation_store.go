@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// UserRevocation revokes every access token of a user issued before a cutoff
+type UserRevocation struct {
+	IssuedBefore time.Time
+	// ExceptSessionID, if set, names a session whose tokens stay valid
+	ExceptSessionID string
+	// ExpiresAt is when every token caught by the cutoff has expired anyway
+	ExpiresAt time.Time
+}
+
+// RevocationStore records access tokens that must no longer be accepted even
+// though they have not expired yet. Entries only need to live until the
+// revoked tokens would have expired on their own, so implementations may
+// discard them after their expiry.
+type RevocationStore interface {
+	// RevokeToken revokes the single token with the given ID (jti)
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// IsTokenRevoked reports whether the token with the given ID was revoked
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// RevokeSession revokes every access token carrying the session ID (sid)
+	RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error
+	// IsSessionRevoked reports whether the session's access tokens were revoked
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+	// RevokeUserTokens stores a cutoff for the user, replacing any previous one
+	RevokeUserTokens(ctx context.Context, userID string, revocation UserRevocation) error
+	// GetUserRevocation returns the user's cutoff, or nil if there is none
+	GetUserRevocation(ctx context.Context, userID string) (*UserRevocation, error)
+}
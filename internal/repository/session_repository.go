@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// ErrSessionNotFound is returned when the user has no session with the given ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository stores the signed-in devices of each user
+type SessionRepository interface {
+	// Save creates or replaces a session
+	Save(ctx context.Context, session *models.Session) error
+	// Get returns the user's session or ErrSessionNotFound
+	Get(ctx context.Context, userID, sessionID string) (*models.Session, error)
+	// ListByUser returns the user's unexpired sessions, most recently seen first
+	ListByUser(ctx context.Context, userID string) ([]models.Session, error)
+	// Delete removes one session of the user; deleting a missing session is not an error
+	Delete(ctx context.Context, userID, sessionID string) error
+	// DeleteByUser removes every session of the user except exceptSessionID when it is not empty
+	DeleteByUser(ctx context.Context, userID, exceptSessionID string) error
+}
+
+// sortSessions orders sessions by last use, most recent first
+func sortSessions(sessions []models.Session) {
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+}
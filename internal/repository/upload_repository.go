@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+// UploadClaimTTL is how long a claim holds an upload. A completion that has
+// neither finished nor released the upload by then is taken to have died, so
+// the upload can be claimed again or purged once it expires.
+const UploadClaimTTL = 5 * time.Minute
+
+var (
+	// ErrUploadNotFound is returned for uploads that were never started or
+	// already completed. Expired uploads are eventually forgotten too.
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadClaimed is returned when claiming an upload another completion is processing
+	ErrUploadClaimed = errors.New("the upload is already being completed")
+)
+
+// UploadRepository tracks pending direct-to-storage uploads
+type UploadRepository interface {
+	// Create records a newly started upload
+	Create(ctx context.Context, upload *models.Upload) error
+	// Get returns the upload, expired or not, or ErrUploadNotFound
+	Get(ctx context.Context, id string) (*models.Upload, error)
+	// Claim atomically marks the upload as being completed, failing with
+	// ErrUploadClaimed while another claim holds, so only one completion processes it
+	Claim(ctx context.Context, id string) (*models.Upload, error)
+	// Release clears the claim of an upload whose completion can be retried
+	Release(ctx context.Context, id string) error
+	// Delete forgets a completed upload
+	Delete(ctx context.Context, id string) error
+	// ListExpired returns up to limit uploads that expired before the given
+	// time, claimed or not, oldest first
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]models.Upload, error)
+}
+
+// ClaimHeld reports whether a completion still holds the upload at the given time
+func ClaimHeld(upload *models.Upload, now time.Time) bool {
+	return !upload.ClaimedAt.IsZero() && now.Sub(upload.ClaimedAt) < UploadClaimTTL
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches the lookup
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists is returned when creating a user whose ID is already taken
+	ErrUserExists = errors.New("user already exists")
+	// ErrEmailTaken is returned when another user already has the email
+	ErrEmailTaken = errors.New("email is already registered")
+	// ErrUsernameTaken is returned when another user already has the username
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrIdentityTaken is returned when an external identity is already linked to another user
+	ErrIdentityTaken = errors.New("this sign-in method is already linked to another account")
+)
+
+// UserRepository persists users independently of the storage backend
+type UserRepository interface {
+	// Create stores a new user, failing with ErrUserExists if the ID is taken
+	// and ErrEmailTaken, ErrUsernameTaken or ErrIdentityTaken if another user
+	// has the email, username or one of the linked identities. Emails are
+	// compared normalized and usernames case-insensitively.
+	Create(ctx context.Context, user *models.User) error
+	// GetByID returns the user with the given ID or ErrUserNotFound
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	// GetByEmail returns the user with the given email or ErrUserNotFound
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	// GetByIdentity returns the user an external identity is linked to or ErrUserNotFound
+	GetByIdentity(ctx context.Context, provider, subject string) (*models.User, error)
+	// Update reads the user, applies mutate to it and stores the result
+	// atomically, so concurrent updates of other fields are not lost, and
+	// returns the updated user. An error from mutate aborts the update and is
+	// returned as is. mutate may run more than once when the update is retried
+	// after a conflict, so it should do nothing but change the user.
+	// Update fails with ErrUserNotFound if the user does not exist and
+	// ErrEmailTaken, ErrUsernameTaken or ErrIdentityTaken if a changed email,
+	// username or identity belongs to another user.
+	Update(ctx context.Context, id string, mutate func(user *models.User) error) (*models.User, error)
+}
@@ -4,28 +4,87 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"log"
 	"math/big"
 	"strings"
+	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+	"github.com/Dffarhn/bakulenapi/pkg/oidc"
 	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
-	"google.golang.org/api/idtoken"
 )
 
-// AuthService provides authentication functions using Firestore
+// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired, revoked or replayed
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// dummyPasswordHash is compared against when there is no real hash to check,
+// so failed sign-ins take the same time whether or not the account exists
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("bakulen-dummy-password"), bcrypt.DefaultCost)
+
+// AuthDependencies groups the collaborators AuthService needs
+type AuthDependencies struct {
+	Users         repository.UserRepository
+	RefreshTokens repository.RefreshTokenRepository
+	Revocations   repository.RevocationStore
+	ActionTokens  repository.ActionTokenRepository
+	Sessions      repository.SessionRepository
+	LoginAttempts repository.LoginAttemptRepository
+	Audit         repository.AuditLogRepository
+	Tokens        *utils.JWTManager
+	Mailer        mailer.Mailer
+	// PasswordPolicy decides which new passwords are accepted; defaults to validation.DefaultPasswordPolicy
+	PasswordPolicy *validation.PasswordPolicy
+	// LoginThrottle slows down and locks out repeated failed sign-ins
+	LoginThrottle LoginThrottlePolicy
+	// TOTPIssuer names the service in authenticator apps
+	TOTPIssuer string
+	// MFAChallengeTTL is how long a sign-in waits for its second factor
+	MFAChallengeTTL time.Duration
+	// RefreshTTL is the lifetime of each issued refresh token
+	RefreshTTL time.Duration
+	// Providers verifies ID tokens of the identity providers users can sign in with
+	Providers *oidc.Registry
+	// FirebaseAuth verifies Firebase Auth ID tokens; Firebase sign-in is disabled when nil
+	FirebaseAuth FirebaseTokenVerifier
+	// AcceptFirebaseTokens lets Authenticate accept Firebase ID tokens in place of our access tokens
+	AcceptFirebaseTokens bool
+	// LinkRequiresPassword makes a first provider sign-in onto an existing
+	// password account always confirm the account password, even when both
+	// sides verified the email
+	LinkRequiresPassword bool
+	// EmailVerificationURL is the page verification links point to
+	EmailVerificationURL string
+	// EmailVerificationTTL is how long a verification link stays valid
+	EmailVerificationTTL time.Duration
+	// PasswordResetURL is the page password reset links point to
+	PasswordResetURL string
+	// PasswordResetTTL is how long a password reset link stays valid
+	PasswordResetTTL time.Duration
+}
+
+// AuthService provides authentication functions on top of a UserRepository
 type AuthService struct {
-	FirestoreClient *firestore.Client
+	AuthDependencies
 }
 
-// NewAuthService initializes AuthService with Firestore client
-func NewAuthService() *AuthService {
-	return &AuthService{
-		FirestoreClient: config.GetFirestoreClient(),
+// NewAuthService initializes AuthService with the given dependencies
+func NewAuthService(deps AuthDependencies) *AuthService {
+	if deps.PasswordPolicy == nil {
+		deps.PasswordPolicy = validation.DefaultPasswordPolicy()
+	}
+	if deps.TOTPIssuer == "" {
+		deps.TOTPIssuer = "Bakulen"
+	}
+	if deps.MFAChallengeTTL <= 0 {
+		deps.MFAChallengeTTL = 5 * time.Minute
 	}
+	return &AuthService{AuthDependencies: deps}
 }
 
 func generateUUID() (string, error) {
@@ -36,176 +95,430 @@ func generateUUID() (string, error) {
 	return newUUID.String(), nil
 }
 
-// Register creates a new user in Firestore
-func (s *AuthService) Register(email, username, password string, fcmToken string) (*firestore.DocumentRef, string, error) {
+// Register creates a new user in the repository and signs it in on the client's device
+func (s *AuthService) Register(email, username, password string, client ClientInfo) (*models.User, *models.AuthTokens, error) {
+	ctx := context.Background()
+	email = models.NormalizeEmail(email)
+	username = strings.TrimSpace(username)
+
+	// Check the input before touching the repository
+	var problems validation.Errors
+	problems.CheckEmail("email", email)
+	problems.CheckUsername("username", username)
+	problems.CheckPassword("password", password, s.PasswordPolicy, username, email)
+	if err := problems.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	userID, err := generateUUID()
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	// Create user in Firestore
-	userRef := s.FirestoreClient.Collection("users").Doc(userID)
-	_, err = userRef.Set(context.Background(), map[string]interface{}{
-		"id":        userRef.ID,
-		"email":     email,
-		"username":  username,
-		"password":  string(hashedPassword),
-		"CreatedAt": firestore.ServerTimestamp,
-		"UpdatedAt": firestore.ServerTimestamp,
-		"fcmToken":  fcmToken,
-	})
+	// Create user in the repository
+	user := &models.User{
+		ID:       userID,
+		Email:    email,
+		Username: username,
+		Password: string(hashedPassword),
+		Roles:    []string{models.RoleUser},
+	}
+	user.LinkIdentity(models.Identity{Provider: models.ProviderPassword, Email: email, LinkedAt: time.Now()})
+	// The repository enforces unique emails and usernames atomically and
+	// fails with repository.ErrEmailTaken or repository.ErrUsernameTaken
+	if err := s.Users.Create(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	// Ask the user to prove they own the email; they can request another link later
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("[WARNING] Failed to send verification email to user %s: %v", user.ID, err)
+	}
+
+	// Generate JWT tokens
+	tokens, err := s.issueTokens(ctx, user, "", client)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// Login signs in with an email and password. Failures are counted per account
+// and per client IP (if known) and answered with ErrInvalidCredentials,
+// or a ThrottledError once the LoginThrottle policy blocks further attempts.
+// Users with two-factor authentication get an MFAChallenge instead of tokens.
+func (s *AuthService) Login(typedEmail, password string, client ClientInfo) (*models.AuthTokens, *models.MFAChallenge, error) {
+	ctx := context.Background()
+	email := models.NormalizeEmail(typedEmail)
+
+	// ✅ Refuse attempts while the account or IP is blocked
+	if err := s.checkLoginThrottle(ctx, email, client.IP); err != nil {
+		return nil, nil, err
+	}
+
+	// Look up user by email; legacy users are stored with the email as typed
+	user, err := s.Users.GetByEmail(ctx, typedEmail)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		log.Println("[ERROR] User lookup failed:", err)
+		return nil, nil, errors.New("internal server error")
+	}
+
+	// ✅ Compare hashed password; unknown emails and accounts without a password
+	// are compared against a dummy hash so they take as long as a real mismatch
+	hasPassword := user != nil && user.HasProvider(models.ProviderPassword) && user.Password != ""
+	hash := dummyPasswordHash
+	if hasPassword {
+		hash = []byte(user.Password)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !hasPassword {
+		if user != nil {
+			log.Println("[WARNING] Failed login for user:", user.ID)
+		} else {
+			log.Println("[WARNING] Failed login for an unknown email")
+		}
+		if err := s.recordLoginFailure(ctx, email, client.IP, user); err != nil {
+			log.Println("[ERROR] Failed to record login failure:", err)
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	// ✅ A successful sign-in clears the account's failures; the IP's stay, so
+	// signing in to one account cannot reset guessing at others
+	if err := s.LoginAttempts.Reset(ctx, accountThrottleKey(email)); err != nil {
+		log.Println("[ERROR] Failed to reset login failures:", err)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(userRef.ID) // Use email or UID as payload
+	// ✅ Generate JWT tokens, or a challenge for the second factor
+	tokens, challenge, err := s.completeLogin(ctx, user, client)
 	if err != nil {
-		return nil, "", err
+		log.Println("[ERROR] JWT token generation failed:", err)
+		return nil, nil, err
 	}
 
-	return userRef, token, nil
+	return tokens, challenge, nil
+}
+
+// LoginWithProvider signs in with an ID token from a registered identity
+// provider, registering a new user if needed. password confirms linking the
+// provider to an existing password account with the same email and may be empty.
+// Users with two-factor authentication get an MFAChallenge instead of tokens.
+func (s *AuthService) LoginWithProvider(provider, idToken, password string, client ClientInfo) (*models.AuthTokens, *models.MFAChallenge, error) {
+	ctx := context.Background()
+
+	identity, err := s.verifyProviderToken(ctx, provider, idToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.loginExternal(ctx, identity, password, client)
 }
 
-func (s *AuthService) Login(email string, password string) (string, error) {
-	log.Printf("[DEBUG] Searching for user: %s", email)
+// verifyProviderToken validates an ID token of a registered provider and
+// returns the identity it asserts
+func (s *AuthService) verifyProviderToken(ctx context.Context, provider, idToken string) (*ExternalIdentity, error) {
+	if s.Providers == nil {
+		return nil, ErrUnknownProvider
+	}
+	p, ok := s.Providers.Get(provider)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
 
-	// Query Firestore for user by email
-	query := s.FirestoreClient.Collection("users").Where("email", "==", email).Limit(1)
-	docs, err := query.Documents(context.Background()).GetAll()
+	// ✅ Validate the ID token against the provider's keys, issuer and our client IDs
+	token, err := p.Verify(ctx, idToken)
 	if err != nil {
-		log.Println("[ERROR] Firestore query failed:", err)
-		return "", errors.New("internal server error")
+		log.Printf("[WARNING] %s ID token rejected: %v", provider, err)
+		return nil, err
 	}
-	if len(docs) == 0 {
-		log.Println("[WARNING] User not found:", email)
-		return "", errors.New("invalid email or password")
+
+	// ✅ Fall back to the email's local part for the display name
+	name := token.Name
+	if name == "" && token.Email != "" {
+		name = strings.Split(token.Email, "@")[0]
 	}
 
-	// Get user document
-	docSnap := docs[0]
-	userData := docSnap.Data()
+	return &ExternalIdentity{
+		Provider:      provider,
+		Subject:       token.Subject,
+		Email:         token.Email,
+		EmailVerified: token.EmailVerified,
+		Name:          name,
+	}, nil
+}
 
-	log.Printf("[DEBUG] User found: %+v", userData)
+// usernameAttempts is how many usernames createWithUsername tries before giving up
+const usernameAttempts = 5
+
+// createWithUsername stores a user that did not choose a username, deriving one
+// from seed and adding a random suffix while the candidate is taken
+func (s *AuthService) createWithUsername(ctx context.Context, user *models.User, seed string) error {
+	base := usernameFromSeed(seed)
+	user.Username = base
+	for attempt := 1; ; attempt++ {
+		err := s.Users.Create(ctx, user)
+		if !errors.Is(err, repository.ErrUsernameTaken) || attempt == usernameAttempts {
+			return err
+		}
 
-	// ✅ Check if user is a Google User
-	if isGoogleUser, exists := userData["isGoogleUser"].(bool); exists && isGoogleUser {
-		log.Println("[WARNING] User attempted to login with password but is a Google user:", email)
-		return "", errors.New("you have previously signed in with Google, please log in using Google")
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return err
+		}
+		user.Username = fmt.Sprintf("%s%04d", base, suffix.Int64())
 	}
+}
 
-	// ✅ Extract stored password
-	storedPassword, ok := userData["password"].(string)
-	if !ok || storedPassword == "" {
-		log.Println("[ERROR] Password field missing in Firestore document")
-		return "", errors.New("password not found")
+// usernameFromSeed turns a display name or email into a valid username base,
+// leaving room for the suffix added by createWithUsername
+func usernameFromSeed(seed string) string {
+	seed, _, _ = strings.Cut(seed, "@")
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(seed) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '_' || r == '.' || r == ' ' || r == '-':
+			if b.Len() > 0 {
+				b.WriteRune('_')
+			}
+		}
 	}
 
-	// ✅ Compare hashed password
-	err = bcrypt.CompareHashAndPassword([]byte(storedPassword), []byte(password))
+	username := strings.TrimRight(b.String(), "_")
+	if len(username) > 26 {
+		username = strings.TrimRight(username[:26], "_")
+	}
+	if len(username) < 3 {
+		username = "user" + username
+	}
+	return username
+}
+
+// Refresh rotates a refresh token: the presented token is consumed and a new
+// access/refresh pair in the same family is issued. Presenting a token that
+// was already rotated revokes the whole family, since it means the token leaked.
+func (s *AuthService) Refresh(refreshToken string, client ClientInfo) (*models.AuthTokens, error) {
+	ctx := context.Background()
+
+	stored, err := s.RefreshTokens.Consume(ctx, utils.HashOpaqueToken(refreshToken))
+	if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		log.Printf("[WARNING] Refresh token reuse detected for user %s, revoking family %s", stored.UserID, stored.FamilyID)
+		if err := s.endSession(ctx, stored.UserID, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
-		log.Println("[WARNING] Password mismatch for user:", email)
-		return "", errors.New("invalid password")
+		return nil, err
 	}
 
-	// ✅ Generate JWT token
-	token, err := utils.GenerateToken(userData["id"].(string))
+	if stored.Revoked || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Reload the user so the new access token reflects its current state
+	user, err := s.Users.GetByID(ctx, stored.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
-		log.Println("[ERROR] JWT token generation failed:", err)
-		return "", err
+		return nil, err
 	}
 
-	log.Println("[INFO] Login successful, token generated")
-	return token, nil
+	return s.issueTokens(ctx, user, stored.FamilyID, client)
 }
 
-func (s *AuthService) VerifyGoogleIDToken(idToken string) (string, error) {
+// Logout revokes the caller's access token and the refresh token family of
+// its session. A refresh token from an older session may be passed as well.
+func (s *AuthService) Logout(principal *utils.Principal, refreshToken string) error {
 	ctx := context.Background()
-	audience := "232341066470-kbpl26tstrov8g6rfsve9ml5babebslo.apps.googleusercontent.com"
 
-	// ✅ Validate Google ID Token
-	payload, err := idtoken.Validate(ctx, idToken, audience)
-	if err != nil {
-		return "", errors.New("invalid ID token")
+	if err := s.Revocations.RevokeToken(ctx, principal.TokenID, principal.ExpiresAt); err != nil {
+		return err
 	}
 
-	// ✅ Extract email
-	email, ok := payload.Claims["email"].(string)
-	if !ok {
-		return "", errors.New("email not found in token")
+	if principal.SessionID != "" {
+		if err := s.endSession(ctx, principal.UserID, principal.SessionID); err != nil {
+			return err
+		}
 	}
 
-	// ✅ Extract display name
-	name, ok := payload.Claims["name"].(string)
-	if !ok {
-		name = strings.Split(email, "@")[0]
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.RefreshTokens.Get(ctx, utils.HashOpaqueToken(refreshToken))
+	if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Never let one user revoke another user's sessions
+	if stored.UserID != principal.UserID || stored.FamilyID == principal.SessionID {
+		return nil
 	}
+	return s.endSession(ctx, stored.UserID, stored.FamilyID)
+}
+
+// LogoutAll revokes every access and refresh token issued to the user so far
+func (s *AuthService) LogoutAll(userID string) error {
+	return s.revokeSessions(context.Background(), userID, "")
+}
 
-	// ✅ Check if user already exists in Firestore
-	query := s.FirestoreClient.Collection("users").Where("email", "==", email).Limit(1)
-	docs, err := query.Documents(ctx).GetAll()
+// revokeSessions revokes the access and refresh tokens issued so far to every
+// session of the user except keepSessionID, if not empty
+func (s *AuthService) revokeSessions(ctx context.Context, userID, keepSessionID string) error {
+	now := time.Now()
+	// Access tokens issued before now expire within one access TTL at the latest
+	expiresAt := now.Add(s.Tokens.TTL())
+
+	err := s.Revocations.RevokeUserTokens(ctx, userID, repository.UserRevocation{
+		IssuedBefore:    now,
+		ExceptSessionID: keepSessionID,
+		ExpiresAt:       expiresAt,
+	})
 	if err != nil {
-		return "", errors.New("internal server error")
+		return err
 	}
 
-	// ✅ If user exists, return JWT
-	if len(docs) > 0 {
-		userData := docs[0].Data()
-		tokenJWT, err := utils.GenerateToken(userData["id"].(string))
+	// Token times are whole seconds, so the cutoff only catches tokens issued
+	// in earlier seconds; revoking the sessions catches the rest
+	sessions, err := s.Sessions.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == keepSessionID {
+			continue
+		}
+		if err := s.Revocations.RevokeSession(ctx, session.ID, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	if err := s.RefreshTokens.RevokeUser(ctx, userID, keepSessionID); err != nil {
+		return err
+	}
+	return s.Sessions.DeleteByUser(ctx, userID, keepSessionID)
+}
+
+// Authenticate validates an access token and returns the caller it was issued
+// to. With AcceptFirebaseTokens, Firebase ID tokens are accepted as well.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*utils.Principal, error) {
+	var principal *utils.Principal
+	claims, err := s.Tokens.ValidateToken(token)
+	switch {
+	case err == nil:
+		principal = claims.Principal()
+	case s.AcceptFirebaseTokens && s.FirebaseAuth != nil:
+		// Not one of ours; it may be a Firebase ID token
+		principal, err = s.authenticateFirebase(ctx, token)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		return tokenJWT, nil
+	default:
+		return nil, fmt.Errorf("%w: %v", utils.ErrInvalidToken, err)
 	}
 
-	// ✅ New User: Generate UUID
-	userID, err := generateUUID()
+	revoked, err := s.isRevoked(ctx, principal)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if revoked {
+		return nil, utils.ErrTokenRevoked
+	}
+	return principal, nil
+}
+
+// isRevoked reports whether the access token was revoked by Logout,
+// RevokeSession or LogoutAll
+func (s *AuthService) isRevoked(ctx context.Context, principal *utils.Principal) (bool, error) {
+	revoked, err := s.Revocations.IsTokenRevoked(ctx, principal.TokenID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	if principal.SessionID != "" {
+		revoked, err := s.Revocations.IsSessionRevoked(ctx, principal.SessionID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	revocation, err := s.Revocations.GetUserRevocation(ctx, principal.UserID)
+	if err != nil || revocation == nil {
+		return false, err
+	}
+	if revocation.ExceptSessionID != "" && revocation.ExceptSessionID == principal.SessionID {
+		return false, nil
+	}
+	// Token times are whole seconds; tokens issued in the second of the cutoff
+	// are left to the session revocations of revokeSessions, so signing in
+	// right after a cutoff works
+	return principal.IssuedAt.Unix() < revocation.IssuedBefore.Unix(), nil
+}
+
+// issueTokens creates an access token and a refresh token for the user.
+// An empty familyID starts a new token family; the family ID doubles as the
+// session ID carried in the access token's sid claim, and the session records
+// the client it was issued to.
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, familyID string, client ClientInfo) (*models.AuthTokens, error) {
+	var err error
+	if familyID == "" {
+		familyID, err = generateUUID()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// ✅ Store new Google user in Firestore
-	userRef := s.FirestoreClient.Collection("users").Doc(userID)
-	_, err = userRef.Set(ctx, map[string]interface{}{
-		"id":           userRef.ID,
-		"email":        email,
-		"username":     name,
-		"isGoogleUser": true, // Mark as Google user
-		"CreatedAt":    firestore.ServerTimestamp,
-		"UpdatedAt":    firestore.ServerTimestamp,
+	accessToken, err := s.Tokens.GenerateToken(utils.TokenSubject{
+		UserID:        user.ID,
+		SessionID:     familyID,
+		Roles:         user.EffectiveRoles(),
+		EmailVerified: user.EmailVerified,
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// ✅ Generate JWT token
-	tokenJWT, err := utils.GenerateToken(userRef.ID)
+	refreshToken, err := utils.GenerateOpaqueToken()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	log.Printf("[INFO] New Google user registered: %s", email)
-	return tokenJWT, nil
-}
-
-// store fcm service
-func (s *AuthService) StoreFCMToken(userID, fcmToken string) error {
-	// Update user document with FCM token
-	userRef := s.FirestoreClient.Collection("users").Doc(userID)
-	_, err := userRef.Set(context.Background(), map[string]interface{}{
-		"fcmToken": fcmToken,
-	}, firestore.MergeAll)
+	now := time.Now()
+	expiresAt := now.Add(s.RefreshTTL)
+	err = s.RefreshTokens.Create(ctx, &models.RefreshToken{
+		ID:        utils.HashOpaqueToken(refreshToken),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
 
+	if err := s.saveSession(ctx, user.ID, familyID, client, expiresAt); err != nil {
+		return nil, err
 	}
-	return nil
+
+	return &models.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.Tokens.TTL().Seconds()),
+	}, nil
 }
 
 // GenerateRandomPassword generates a random password for the user
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+const testPassword = "correct-Horse-battery-9"
+
+// newTestAuthService returns an AuthService on in-memory repositories
+func newTestAuthService(t *testing.T) *AuthService {
+	t.Helper()
+	key, err := utils.GenerateSigningKey("test")
+	if err != nil {
+		t.Fatalf("GenerateSigningKey() = %v", err)
+	}
+	keys, err := utils.NewKeySet(key)
+	if err != nil {
+		t.Fatalf("NewKeySet() = %v", err)
+	}
+	return NewAuthService(AuthDependencies{
+		Users:         repository.NewMemoryUserRepository(),
+		RefreshTokens: repository.NewMemoryRefreshTokenRepository(),
+		Revocations:   repository.NewMemoryRevocationStore(),
+		ActionTokens:  repository.NewMemoryActionTokenRepository(),
+		Sessions:      repository.NewMemorySessionRepository(),
+		LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+		Audit:         repository.NewMemoryAuditLogRepository(),
+		Tokens:        utils.NewJWTManager(keys, 15*time.Minute, "bakulen-test", "bakulen-test"),
+		Mailer:        mailer.NewConsoleMailer(),
+		LoginThrottle: DefaultLoginThrottlePolicy(),
+		RefreshTTL:    time.Hour,
+	})
+}
+
+// registerTestUser registers alice and returns her and her first tokens
+func registerTestUser(t *testing.T, s *AuthService) (*models.User, *models.AuthTokens) {
+	t.Helper()
+	user, tokens, err := s.Register("alice@example.com", "alice", testPassword, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+	return user, tokens
+}
+
+func TestRefreshRotation(t *testing.T) {
+	tests := []struct {
+		name string
+		// replay is refreshed after the first rotation, given the original
+		// and the rotated refresh token
+		replay      func(original, rotated string) string
+		wantReplay  error
+		rotatedLive bool
+	}{
+		{"rotated token refreshes", func(original, rotated string) string { return rotated }, nil, true},
+		{"reused token revokes the family", func(original, rotated string) string { return original }, ErrInvalidRefreshToken, false},
+		{"unknown token", func(original, rotated string) string { return "not-a-token" }, ErrInvalidRefreshToken, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestAuthService(t)
+			_, tokens := registerTestUser(t, s)
+
+			rotated, err := s.Refresh(tokens.RefreshToken, ClientInfo{})
+			if err != nil {
+				t.Fatalf("Refresh() = %v", err)
+			}
+			if rotated.RefreshToken == tokens.RefreshToken {
+				t.Fatal("Refresh() returned the presented refresh token")
+			}
+
+			_, err = s.Refresh(tt.replay(tokens.RefreshToken, rotated.RefreshToken), ClientInfo{})
+			if !errors.Is(err, tt.wantReplay) {
+				t.Fatalf("second Refresh() = %v, want %v", err, tt.wantReplay)
+			}
+
+			// The access token of the rotated pair only survives if its session does
+			_, err = s.Authenticate(context.Background(), rotated.AccessToken)
+			if live := err == nil; live != tt.rotatedLive {
+				t.Fatalf("Authenticate(rotated access token) = %v, want live %v", err, tt.rotatedLive)
+			}
+		})
+	}
+}
+
+func TestRefreshReuseRevokesOnlyThatFamily(t *testing.T) {
+	s := newTestAuthService(t)
+	_, first := registerTestUser(t, s)
+	second, _, err := s.Login("alice@example.com", testPassword, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() = %v", err)
+	}
+
+	rotated, err := s.Refresh(first.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+	if _, err := s.Refresh(first.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh(reused) = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// The reuse revoked the token rotated from the leaked one
+	if _, err := s.Refresh(rotated.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh(rotated) after reuse = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// The other sign-in is a separate family and keeps working
+	if _, err := s.Refresh(second.RefreshToken, ClientInfo{}); err != nil {
+		t.Fatalf("Refresh(other session) = %v", err)
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	s := newTestAuthService(t)
+	s.RefreshTTL = -time.Minute
+	_, tokens := registerTestUser(t, s)
+
+	if _, err := s.Refresh(tokens.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh(expired) = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshAfterLogout(t *testing.T) {
+	s := newTestAuthService(t)
+	_, tokens := registerTestUser(t, s)
+	principal, err := s.Authenticate(context.Background(), tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+
+	if err := s.Logout(principal, ""); err != nil {
+		t.Fatalf("Logout() = %v", err)
+	}
+	if _, err := s.Refresh(tokens.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() after Logout() = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.Authenticate(context.Background(), tokens.AccessToken); err == nil {
+		t.Fatal("Authenticate() accepted a logged out access token")
+	}
+}
+
+func TestRefreshAfterLogoutAll(t *testing.T) {
+	s := newTestAuthService(t)
+	user, tokens := registerTestUser(t, s)
+
+	if err := s.LogoutAll(user.ID); err != nil {
+		t.Fatalf("LogoutAll() = %v", err)
+	}
+	if _, err := s.Refresh(tokens.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() after LogoutAll() = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.Authenticate(context.Background(), tokens.AccessToken); err == nil {
+		t.Fatal("Authenticate() accepted an access token issued before LogoutAll()")
+	}
+}
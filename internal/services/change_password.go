@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrIncorrectPassword is returned when the current password does not match
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrNoPassword is returned for password operations on accounts without a linked password
+	ErrNoPassword = errors.New("this account has no password, link one first")
+	// ErrSamePassword is returned when the new password equals the current one
+	ErrSamePassword = errors.New("new password must be different from the current password")
+)
+
+// ChangePassword replaces the caller's password after checking the current one.
+// Wrong current passwords are throttled per account and client IP like failed
+// sign-ins, and refused with a ThrottledError while either is blocked.
+// Every other session is signed out; the caller's session stays valid.
+func (s *AuthService) ChangePassword(principal *utils.Principal, currentPassword, newPassword string, client ClientInfo) error {
+	ctx := context.Background()
+
+	user, err := s.Users.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+
+	if !user.HasProvider(models.ProviderPassword) || user.Password == "" {
+		return ErrNoPassword
+	}
+
+	// ✅ Guesses here count towards the same limits as sign-ins
+	if err := s.confirmPassword(ctx, user, currentPassword, client.IP); err != nil {
+		return err
+	}
+	if currentPassword == newPassword {
+		return ErrSamePassword
+	}
+	if err := s.checkPassword("new_password", newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = s.Users.Update(ctx, user.ID, func(u *models.User) error {
+		// The current password was checked against what was read above
+		if u.Password != user.Password {
+			return ErrIncorrectPassword
+		}
+		u.Password = string(hashedPassword)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.revokeSessions(ctx, user.ID, principal.SessionID); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Password changed for user %s", user.ID)
+	return nil
+}
+
+// confirmPassword checks password against the user's for operations that ask
+// for it again. Wrong passwords are throttled per account and client IP like
+// failed sign-ins and fail with ErrIncorrectPassword; while either is blocked
+// it fails with a ThrottledError.
+func (s *AuthService) confirmPassword(ctx context.Context, user *models.User, password, clientIP string) error {
+	email := models.NormalizeEmail(user.Email)
+	if err := s.checkLoginThrottle(ctx, email, clientIP); err != nil {
+		return err
+	}
+	if !user.HasProvider(models.ProviderPassword) || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		log.Printf("[WARNING] Incorrect password confirmation for user %s", user.ID)
+		if err := s.recordLoginFailure(ctx, email, clientIP, user); err != nil {
+			log.Println("[ERROR] Failed to record password confirmation failure:", err)
+		}
+		return ErrIncorrectPassword
+	}
+	if err := s.LoginAttempts.Reset(ctx, accountThrottleKey(email)); err != nil {
+		log.Println("[ERROR] Failed to reset login failures:", err)
+	}
+	return nil
+}
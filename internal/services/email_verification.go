@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+)
+
+var (
+	// ErrInvalidVerificationToken is returned for unknown, expired or already used verification tokens
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// ErrEmailAlreadyVerified is returned when requesting verification for a verified email
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+)
+
+// ResendVerificationEmail emails a new verification link to the user
+func (s *AuthService) ResendVerificationEmail(userID string) error {
+	ctx := context.Background()
+
+	user, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// VerifyEmail marks the email of the token's user as verified. Each token works once.
+func (s *AuthService) VerifyEmail(token string) error {
+	ctx := context.Background()
+
+	claims, err := s.Tokens.ValidateActionToken(token, models.ActionEmailVerification)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	stored, err := s.ActionTokens.Consume(ctx, claims.ID, models.ActionEmailVerification)
+	if errors.Is(err, repository.ErrActionTokenNotFound) {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+	if stored.UserID != claims.Subject {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.Users.GetByID(ctx, stored.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	_, err = s.Users.Update(ctx, user.ID, func(u *models.User) error {
+		u.EmailVerified = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Email verified for user %s", user.ID)
+	return nil
+}
+
+// sendVerificationEmail issues a verification token and emails its link to the user
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, claims, err := s.Tokens.GenerateActionToken(user.ID, models.ActionEmailVerification, s.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	err = s.ActionTokens.Create(ctx, &models.ActionToken{
+		ID:        claims.ID,
+		UserID:    user.ID,
+		Purpose:   models.ActionEmailVerification,
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := withToken(s.EmailVerificationURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Bakulen email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not create a Bakulen account, you can ignore this email.\n",
+			user.Username, link, s.EmailVerificationTTL),
+	})
+}
+
+// withToken appends the token as the "token" query parameter of the link
+func withToken(link, token string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid link %q: %v", link, err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+)
+
+// capturingMailer keeps every email it is asked to send
+type capturingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *capturingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// lastToken returns the token of the link in the last email
+func (m *capturingMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		t.Fatal("no email was sent")
+	}
+	for _, line := range strings.Split(m.messages[len(m.messages)-1].Body, "\n") {
+		if !strings.HasPrefix(line, "https://") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) = %v", line, err)
+		}
+		return u.Query().Get("token")
+	}
+	t.Fatalf("email has no link: %s", m.messages[len(m.messages)-1].Body)
+	return ""
+}
+
+// newVerifyingAuthService returns an AuthService that emails verification
+// links to a capturingMailer
+func newVerifyingAuthService(t *testing.T) (*AuthService, *capturingMailer) {
+	t.Helper()
+	s := newTestAuthService(t)
+	mails := &capturingMailer{}
+	s.Mailer = mails
+	s.EmailVerificationURL = "https://bakulen.example.com/verify-email"
+	s.EmailVerificationTTL = time.Hour
+	return s, mails
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s, mails := newVerifyingAuthService(t)
+	user, _ := registerTestUser(t, s)
+	if user.EmailVerified {
+		t.Fatal("registered user is already verified")
+	}
+	if to := mails.messages[0].To; to != "alice@example.com" {
+		t.Fatalf("verification email sent to %q", to)
+	}
+	token := mails.lastToken(t)
+
+	if err := s.VerifyEmail(token); err != nil {
+		t.Fatalf("VerifyEmail() = %v", err)
+	}
+	stored, err := s.Users.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID() = %v", err)
+	}
+	if !stored.EmailVerified {
+		t.Fatal("VerifyEmail() did not mark the email verified")
+	}
+
+	// Each link works once, and verified users get no new ones
+	if err := s.VerifyEmail(token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("VerifyEmail(used token) = %v, want ErrInvalidVerificationToken", err)
+	}
+	if err := s.ResendVerificationEmail(user.ID); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Fatalf("ResendVerificationEmail() = %v, want ErrEmailAlreadyVerified", err)
+	}
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	s, mails := newVerifyingAuthService(t)
+	user, _ := registerTestUser(t, s)
+
+	if err := s.ResendVerificationEmail(user.ID); err != nil {
+		t.Fatalf("ResendVerificationEmail() = %v", err)
+	}
+	if len(mails.messages) != 2 {
+		t.Fatalf("%d emails sent, want 2", len(mails.messages))
+	}
+	if err := s.VerifyEmail(mails.lastToken(t)); err != nil {
+		t.Fatalf("VerifyEmail(resent token) = %v", err)
+	}
+}
+
+func TestVerifyEmailRejectsTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		purpose string
+		ttl     time.Duration
+	}{
+		{"expired", models.ActionEmailVerification, -time.Minute},
+		{"password reset token", models.ActionPasswordReset, time.Hour},
+		{"sign-in challenge", models.ActionMFAChallenge, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newVerifyingAuthService(t)
+			user, _ := registerTestUser(t, s)
+
+			token, claims, err := s.Tokens.GenerateActionToken(user.ID, tt.purpose, tt.ttl)
+			if err != nil {
+				t.Fatalf("GenerateActionToken() = %v", err)
+			}
+			err = s.ActionTokens.Create(context.Background(), &models.ActionToken{
+				ID:        claims.ID,
+				UserID:    user.ID,
+				Purpose:   tt.purpose,
+				ExpiresAt: time.Now().Add(time.Hour),
+				CreatedAt: time.Now(),
+			})
+			if err != nil {
+				t.Fatalf("ActionTokens.Create() = %v", err)
+			}
+
+			if err := s.VerifyEmail(token); !errors.Is(err, ErrInvalidVerificationToken) {
+				t.Fatalf("VerifyEmail() = %v, want ErrInvalidVerificationToken", err)
+			}
+			stored, err := s.Users.GetByID(context.Background(), user.ID)
+			if err != nil {
+				t.Fatalf("GetByID() = %v", err)
+			}
+			if stored.EmailVerified {
+				t.Fatal("VerifyEmail() marked the email verified")
+			}
+		})
+	}
+
+	s, _ := newVerifyingAuthService(t)
+	if err := s.VerifyEmail("not-a-token"); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("VerifyEmail(garbage) = %v, want ErrInvalidVerificationToken", err)
+	}
+}
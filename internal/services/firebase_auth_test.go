@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/auth"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+// fakeFirebaseVerifier accepts any ID token naming a user in tokens; the
+// revocation check also refuses the tokens in revoked
+type fakeFirebaseVerifier struct {
+	tokens  map[string]*auth.Token
+	revoked map[string]bool
+}
+
+func (v *fakeFirebaseVerifier) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	token, ok := v.tokens[idToken]
+	if !ok {
+		return nil, errors.New("unknown ID token")
+	}
+	return token, nil
+}
+
+func (v *fakeFirebaseVerifier) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error) {
+	if v.revoked[idToken] {
+		return nil, errors.New("ID token has been revoked")
+	}
+	return v.VerifyIDToken(ctx, idToken)
+}
+
+func TestAuthenticateFirebaseNeedsLinkedUser(t *testing.T) {
+	s := newTestAuthService(t)
+	now := time.Now()
+	s.FirebaseAuth = &fakeFirebaseVerifier{tokens: map[string]*auth.Token{
+		"bob-token": {
+			UID:      "firebase-bob",
+			IssuedAt: now.Unix(),
+			Expires:  now.Add(time.Hour).Unix(),
+			Claims:   map[string]interface{}{"email": "bob@example.com", "email_verified": true},
+		},
+	}}
+	s.AcceptFirebaseTokens = true
+	ctx := context.Background()
+
+	// Bearer tokens of unknown Firebase users neither sign in nor register
+	if _, err := s.Authenticate(ctx, "bob-token"); !errors.Is(err, utils.ErrInvalidToken) {
+		t.Fatalf("Authenticate(unlinked) = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.Users.GetByEmail(ctx, "bob@example.com"); err == nil {
+		t.Fatal("Authenticate() registered the Firebase user")
+	}
+
+	if _, _, err := s.LoginWithFirebase("bob-token", "", ClientInfo{}); err != nil {
+		t.Fatalf("LoginWithFirebase() = %v", err)
+	}
+	principal, err := s.Authenticate(ctx, "bob-token")
+	if err != nil {
+		t.Fatalf("Authenticate(linked) = %v", err)
+	}
+	user, err := s.Users.GetByEmail(ctx, "bob@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() = %v", err)
+	}
+	if principal.UserID != user.ID {
+		t.Fatalf("principal.UserID = %q, want %q", principal.UserID, user.ID)
+	}
+}
+
+func TestAuthenticateFirebaseChecksRevocation(t *testing.T) {
+	s := newTestAuthService(t)
+	now := time.Now()
+	token := &auth.Token{
+		UID:      "firebase-bob",
+		IssuedAt: now.Unix(),
+		Expires:  now.Add(time.Hour).Unix(),
+		Claims:   map[string]interface{}{"email": "bob@example.com", "email_verified": true},
+	}
+	verifier := &fakeFirebaseVerifier{tokens: map[string]*auth.Token{"bob-token": token, "bob-other-token": token}, revoked: map[string]bool{}}
+	s.FirebaseAuth = verifier
+	s.AcceptFirebaseTokens = true
+	ctx := context.Background()
+
+	if _, _, err := s.LoginWithFirebase("bob-token", "", ClientInfo{}); err != nil {
+		t.Fatalf("LoginWithFirebase() = %v", err)
+	}
+	if _, err := s.Authenticate(ctx, "bob-token"); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+
+	// Revoking the user's tokens at Firebase ends bearer use at once
+	verifier.revoked["bob-token"] = true
+	if _, err := s.Authenticate(ctx, "bob-token"); !errors.Is(err, utils.ErrInvalidToken) {
+		t.Fatalf("Authenticate(revoked) = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.Authenticate(ctx, "bob-other-token"); err != nil {
+		t.Fatalf("Authenticate(other token) = %v", err)
+	}
+}
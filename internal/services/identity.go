@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrLinkConfirmationRequired is returned when a first sign-in through a provider
+	// matches an existing account by email and the account password must confirm the link
+	ErrLinkConfirmationRequired = errors.New("an account with this email already exists, confirm its password to link this sign-in method")
+	// ErrProviderEmailNotVerified is returned when a provider asserts the email of
+	// an existing account without having verified it
+	ErrProviderEmailNotVerified = errors.New("the provider has not verified this email, sign in with your password and link the provider from your account")
+	// ErrProviderAlreadyLinked is returned when linking a provider the account already has
+	ErrProviderAlreadyLinked = errors.New("this sign-in method is already linked to your account")
+	// ErrProviderNotLinked is returned when unlinking a provider the account does not have
+	ErrProviderNotLinked = errors.New("this sign-in method is not linked to your account")
+	// ErrLastProvider is returned when unlinking the only way to sign in to an account
+	ErrLastProvider = errors.New("cannot unlink the only sign-in method of your account")
+	// ErrUnknownProvider is returned for providers the API does not support
+	ErrUnknownProvider = errors.New("unknown sign-in provider")
+)
+
+// ExternalIdentity is what an identity provider asserts about the user signing in
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// loginExternal signs in the user linked to identity, see resolveExternalUser
+func (s *AuthService) loginExternal(ctx context.Context, identity *ExternalIdentity, password string, client ClientInfo) (*models.AuthTokens, *models.MFAChallenge, error) {
+	user, err := s.resolveExternalUser(ctx, identity, password, client.IP)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.completeLogin(ctx, user, client)
+}
+
+// resolveExternalUser returns the user linked to identity. On a first sign-in
+// the identity is linked to the account with the same email, if there is one,
+// or a new account is registered.
+//
+// Linking to an existing account requires the provider to have verified the
+// email. Unless LinkRequiresPassword is set, that is enough when the account
+// has verified the email too; otherwise password must confirm the link, so
+// whoever registered the email first cannot keep a foothold in the account.
+// Password confirmations share the sign-in throttle of the account and clientIP.
+func (s *AuthService) resolveExternalUser(ctx context.Context, identity *ExternalIdentity, password, clientIP string) (*models.User, error) {
+	if identity.Subject == "" {
+		return nil, errors.New("identity provider did not return a subject")
+	}
+
+	// ✅ Returning user
+	user, err := s.Users.GetByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	email := models.NormalizeEmail(identity.Email)
+	user = nil
+	if email != "" {
+		user, err = s.Users.GetByEmail(ctx, email)
+		if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	// ✅ New user
+	if user == nil {
+		return s.registerExternal(ctx, identity)
+	}
+
+	if !identity.EmailVerified {
+		return nil, ErrProviderEmailNotVerified
+	}
+
+	switch existing := user.Identity(identity.Provider); {
+	case existing != nil && existing.Subject == "":
+		// Accounts created before identities were stored are missing the subject
+	case existing != nil:
+		// Linked to a different account at the same provider
+		return nil, ErrLinkConfirmationRequired
+	case password != "":
+		if err := s.confirmPassword(ctx, user, password, clientIP); err != nil {
+			return nil, err
+		}
+	case s.LinkRequiresPassword || !user.EmailVerified:
+		return nil, ErrLinkConfirmationRequired
+	}
+
+	user, err = s.Users.Update(ctx, user.ID, func(u *models.User) error {
+		u.LinkIdentity(models.Identity{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    email,
+			LinkedAt: time.Now(),
+		})
+		// The provider verified the address the account was found by
+		u.EmailVerified = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Linked %s sign-in to user %s", identity.Provider, user.ID)
+	return user, nil
+}
+
+// registerExternal creates a user for a first sign-in through a provider
+func (s *AuthService) registerExternal(ctx context.Context, identity *ExternalIdentity) (*models.User, error) {
+	userID, err := generateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	email := models.NormalizeEmail(identity.Email)
+	user := &models.User{
+		ID:            userID,
+		Email:         email,
+		Name:          identity.Name,
+		EmailVerified: email != "" && identity.EmailVerified,
+		Roles:         []string{models.RoleUser},
+	}
+	user.LinkIdentity(models.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    email,
+		LinkedAt: time.Now(),
+	})
+
+	seed := identity.Name
+	if seed == "" {
+		seed = email
+	}
+	if err := s.createWithUsername(ctx, user, seed); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] New %s user registered: %s", identity.Provider, user.ID)
+	return user, nil
+}
+
+// Identities returns the sign-in methods linked to the user
+func (s *AuthService) Identities(userID string) ([]models.Identity, error) {
+	user, err := s.Users.GetByID(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return user.EffectiveIdentities(), nil
+}
+
+// LinkIdentity adds a sign-in method to the caller's account. For the password
+// provider credential is the new password; for other providers it is an ID
+// token issued to the caller by that provider.
+func (s *AuthService) LinkIdentity(principal *utils.Principal, provider, credential string) ([]models.Identity, error) {
+	ctx := context.Background()
+
+	user, err := s.Users.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// link applies the new sign-in method to the user as stored when it is saved
+	var link func(u *models.User) error
+	switch provider {
+	case models.ProviderPassword:
+		if user.HasProvider(models.ProviderPassword) {
+			return nil, ErrProviderAlreadyLinked
+		}
+		if err := s.checkPassword("password", credential, user.Username, user.Email); err != nil {
+			return nil, err
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(credential), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		link = func(u *models.User) error {
+			if u.HasProvider(models.ProviderPassword) {
+				return ErrProviderAlreadyLinked
+			}
+			u.Password = string(hashedPassword)
+			u.LinkIdentity(models.Identity{Provider: models.ProviderPassword, Email: u.Email, LinkedAt: time.Now()})
+			return nil
+		}
+
+	default:
+		identity, err := s.verifyProviderToken(ctx, provider, credential)
+		if err != nil {
+			return nil, err
+		}
+		link = func(u *models.User) error {
+			if existing := u.Identity(provider); existing != nil && existing.Subject != "" {
+				return ErrProviderAlreadyLinked
+			}
+			u.LinkIdentity(models.Identity{
+				Provider: provider,
+				Subject:  identity.Subject,
+				Email:    models.NormalizeEmail(identity.Email),
+				LinkedAt: time.Now(),
+			})
+			return nil
+		}
+	}
+
+	// Fails with repository.ErrIdentityTaken if another account has the identity
+	user, err = s.Users.Update(ctx, user.ID, link)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Linked %s sign-in to user %s", provider, user.ID)
+	return user.EffectiveIdentities(), nil
+}
+
+// UnlinkIdentity removes a sign-in method from the caller's account, as long as another one remains
+func (s *AuthService) UnlinkIdentity(principal *utils.Principal, provider string) ([]models.Identity, error) {
+	ctx := context.Background()
+
+	user, err := s.Users.Update(ctx, principal.UserID, func(u *models.User) error {
+		if !u.HasProvider(provider) {
+			return ErrProviderNotLinked
+		}
+		if len(u.EffectiveIdentities()) == 1 {
+			return ErrLastProvider
+		}
+		u.UnlinkIdentity(provider)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Unlinked %s sign-in from user %s", provider, user.ID)
+	return user.EffectiveIdentities(), nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/oidc"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+// withFakeProvider registers a provider named acme trusting a FakeIssuer, and returns the issuer
+func withFakeProvider(t *testing.T, s *AuthService) *oidc.FakeIssuer {
+	t.Helper()
+	issuer, err := oidc.NewFakeIssuer("https://acme.example.com")
+	if err != nil {
+		t.Fatalf("NewFakeIssuer() = %v", err)
+	}
+	provider, err := oidc.NewProvider(issuer.Config("acme", "bakulen"), nil)
+	if err != nil {
+		t.Fatalf("NewProvider() = %v", err)
+	}
+	s.Providers = oidc.NewRegistry()
+	if err := s.Providers.Register(provider); err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+	return issuer
+}
+
+func TestResolveExternalUserLinksExistingAccount(t *testing.T) {
+	googleAlice := func(subject string, verified bool) *ExternalIdentity {
+		return &ExternalIdentity{Provider: models.ProviderGoogle, Subject: subject, Email: "Alice@Example.com", EmailVerified: verified}
+	}
+
+	tests := []struct {
+		name     string
+		identity *ExternalIdentity
+		password string
+		// setup changes alice's stored account first
+		setup                func(u *models.User)
+		linkRequiresPassword bool
+		want                 error
+	}{
+		{"unverified provider email", googleAlice("google-1", false), testPassword, nil, false, ErrProviderEmailNotVerified},
+		{"unverified account without password", googleAlice("google-1", true), "", nil, false, ErrLinkConfirmationRequired},
+		{"verified account", googleAlice("google-1", true), "", func(u *models.User) { u.EmailVerified = true }, false, nil},
+		{"verified account when links need the password", googleAlice("google-1", true), "", func(u *models.User) { u.EmailVerified = true }, true, ErrLinkConfirmationRequired},
+		{"password", googleAlice("google-1", true), testPassword, nil, true, nil},
+		{"wrong password", googleAlice("google-1", true), "wrong-password", nil, false, ErrIncorrectPassword},
+		{"password of an account without one", googleAlice("google-1", true), testPassword, func(u *models.User) {
+			u.LinkIdentity(models.Identity{Provider: "acme", Subject: "acme-1", Email: u.Email})
+			u.UnlinkIdentity(models.ProviderPassword)
+		}, false, ErrIncorrectPassword},
+		{"other subject linked at the provider", googleAlice("google-2", true), testPassword, func(u *models.User) {
+			u.EmailVerified = true
+			u.LinkIdentity(models.Identity{Provider: models.ProviderGoogle, Subject: "google-1", Email: u.Email})
+		}, false, ErrLinkConfirmationRequired},
+		{"legacy Google account without a subject", googleAlice("google-1", true), "", func(u *models.User) {
+			u.Identities = nil
+			u.IsGoogleUser = true
+		}, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestAuthService(t)
+			s.LinkRequiresPassword = tt.linkRequiresPassword
+			alice, _ := registerTestUser(t, s)
+			ctx := context.Background()
+			if tt.setup != nil {
+				_, err := s.Users.Update(ctx, alice.ID, func(u *models.User) error {
+					tt.setup(u)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("Users.Update() = %v", err)
+				}
+			}
+
+			user, err := s.resolveExternalUser(ctx, tt.identity, tt.password, "")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("resolveExternalUser() = %v, want %v", err, tt.want)
+			}
+
+			linked, lookupErr := s.Users.GetByIdentity(ctx, tt.identity.Provider, tt.identity.Subject)
+			if tt.want != nil {
+				if lookupErr == nil {
+					t.Fatalf("identity linked to user %s despite %v", linked.ID, err)
+				}
+				return
+			}
+			if user.ID != alice.ID || lookupErr != nil || linked.ID != alice.ID {
+				t.Fatalf("identity linked to %+v, lookup %v, want alice", user, lookupErr)
+			}
+			if !linked.EmailVerified {
+				t.Fatal("linking did not mark the email verified")
+			}
+
+			// The linked identity signs in without confirmation from now on
+			again, err := s.resolveExternalUser(ctx, googleAlice(tt.identity.Subject, false), "", "")
+			if err != nil || again.ID != alice.ID {
+				t.Fatalf("resolveExternalUser(returning) = %v, %v, want alice", again, err)
+			}
+		})
+	}
+}
+
+func TestResolveExternalUserRegistersNewUser(t *testing.T) {
+	s := newTestAuthService(t)
+	ctx := context.Background()
+
+	user, err := s.resolveExternalUser(ctx, &ExternalIdentity{Provider: "acme", Subject: "acme-bob", Email: "bob@example.com", EmailVerified: true, Name: "Bob"}, "", "")
+	if err != nil {
+		t.Fatalf("resolveExternalUser() = %v", err)
+	}
+	if user.HasProvider(models.ProviderPassword) || !user.HasProvider("acme") || !user.EmailVerified {
+		t.Fatalf("registered user = %+v, want a verified acme-only account", user)
+	}
+
+	if _, err := s.resolveExternalUser(ctx, &ExternalIdentity{Provider: "acme", Email: "carol@example.com"}, "", ""); err == nil {
+		t.Fatal("resolveExternalUser(no subject) = nil error, want an error")
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	s := newTestAuthService(t)
+	issuer := withFakeProvider(t, s)
+	alice, _ := registerTestUser(t, s)
+	principal := &utils.Principal{UserID: alice.ID}
+	token := func(subject string) string {
+		t.Helper()
+		token, err := issuer.Issue(oidc.FakeClaims{Subject: subject, Audience: "bakulen", Email: "alice@acme.example.com", EmailVerified: true})
+		if err != nil {
+			t.Fatalf("Issue() = %v", err)
+		}
+		return token
+	}
+
+	identities, err := s.LinkIdentity(principal, "acme", token("acme-alice"))
+	if err != nil {
+		t.Fatalf("LinkIdentity() = %v", err)
+	}
+	if len(identities) != 2 {
+		t.Fatalf("identities = %+v, want password and acme", identities)
+	}
+	if _, err := s.LinkIdentity(principal, "acme", token("acme-other")); !errors.Is(err, ErrProviderAlreadyLinked) {
+		t.Fatalf("LinkIdentity(linked provider) = %v, want ErrProviderAlreadyLinked", err)
+	}
+	if _, err := s.LinkIdentity(principal, models.ProviderPassword, "another-Horse-battery-7"); !errors.Is(err, ErrProviderAlreadyLinked) {
+		t.Fatalf("LinkIdentity(password) = %v, want ErrProviderAlreadyLinked", err)
+	}
+	if _, err := s.LinkIdentity(principal, "unknown", token("acme-alice")); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("LinkIdentity(unknown provider) = %v, want ErrUnknownProvider", err)
+	}
+
+	// An identity belongs to one account
+	bob, _, err := s.Register("bob@example.com", "bob", testPassword, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+	if _, err := s.LinkIdentity(&utils.Principal{UserID: bob.ID}, "acme", token("acme-alice")); !errors.Is(err, repository.ErrIdentityTaken) {
+		t.Fatalf("LinkIdentity(identity of another account) = %v, want ErrIdentityTaken", err)
+	}
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	s := newTestAuthService(t)
+	alice, _ := registerTestUser(t, s)
+	principal := &utils.Principal{UserID: alice.ID}
+
+	if _, err := s.UnlinkIdentity(principal, models.ProviderPassword); !errors.Is(err, ErrLastProvider) {
+		t.Fatalf("UnlinkIdentity(last provider) = %v, want ErrLastProvider", err)
+	}
+	if _, err := s.UnlinkIdentity(principal, models.ProviderGoogle); !errors.Is(err, ErrProviderNotLinked) {
+		t.Fatalf("UnlinkIdentity(unlinked provider) = %v, want ErrProviderNotLinked", err)
+	}
+
+	_, err := s.Users.Update(context.Background(), alice.ID, func(u *models.User) error {
+		u.LinkIdentity(models.Identity{Provider: models.ProviderGoogle, Subject: "google-alice", Email: u.Email})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Users.Update() = %v", err)
+	}
+	identities, err := s.UnlinkIdentity(principal, models.ProviderPassword)
+	if err != nil {
+		t.Fatalf("UnlinkIdentity() = %v", err)
+	}
+	if len(identities) != 1 || identities[0].Provider != models.ProviderGoogle {
+		t.Fatalf("identities = %+v, want only google", identities)
+	}
+
+	// The password no longer signs in
+	if _, _, err := s.Login("alice@example.com", testPassword, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.UnlinkIdentity(principal, models.ProviderGoogle); !errors.Is(err, ErrLastProvider) {
+		t.Fatalf("UnlinkIdentity(last provider) = %v, want ErrLastProvider", err)
+	}
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+var (
+	// ErrInvalidCredentials is the single answer to every failed password sign-in,
+	// so responses do not reveal which emails have accounts
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrTooManyAttempts is matched by ThrottledError
+	ErrTooManyAttempts = errors.New("too many failed sign-in attempts, try again later")
+)
+
+// ThrottledError is returned while sign-ins for an account or client IP are
+// refused after repeated failures
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+// Is makes errors.Is(err, ErrTooManyAttempts) match
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// LoginThrottlePolicy decides how failed password sign-ins slow down further
+// attempts. Failures are counted per account and per client IP. Past the free
+// attempts every failure blocks the key for an exponentially growing delay,
+// and reaching the lockout threshold locks it out for LockoutDuration.
+type LoginThrottlePolicy struct {
+	// Window is how long a failure is remembered after the last one
+	Window           time.Duration
+	FreeAttempts     int
+	LockoutThreshold int
+	// IPFreeAttempts and IPLockoutThreshold apply per client IP, which many users may share
+	IPFreeAttempts     int
+	IPLockoutThreshold int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	LockoutDuration    time.Duration
+}
+
+// DefaultLoginThrottlePolicy returns the policy used when none is configured
+func DefaultLoginThrottlePolicy() LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		Window:             time.Hour,
+		FreeAttempts:       5,
+		LockoutThreshold:   10,
+		IPFreeAttempts:     20,
+		IPLockoutThreshold: 100,
+		BaseDelay:          time.Second,
+		MaxDelay:           5 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+	}
+}
+
+// block returns how long to refuse attempts after the given number of
+// failures, and whether that is a lockout
+func (p LoginThrottlePolicy) block(failures, free, lockout int) (time.Duration, bool) {
+	if lockout > 0 && failures >= lockout {
+		return p.LockoutDuration, true
+	}
+	if failures < free {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := free; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+// throttleTarget is one counter a failed sign-in is recorded against
+type throttleTarget struct {
+	key           string
+	free, lockout int
+	event         string
+}
+
+// logName identifies the target in logs without revealing the email or IP it
+// counts: the user ID when known, otherwise a hash of the key
+func (t throttleTarget) logName(userID string) string {
+	if userID != "" && t.event != models.AuditIPLocked {
+		return "user " + userID
+	}
+	return "key " + utils.HashOpaqueToken(t.key)[:16]
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + email
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func mfaThrottleKey(userID string) string {
+	return "mfa:" + userID
+}
+
+// checkLoginThrottle fails with a ThrottledError while the account or IP is blocked
+func (s *AuthService) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	keys := []string{accountThrottleKey(email)}
+	if ip != "" {
+		keys = append(keys, ipThrottleKey(ip))
+	}
+	return s.checkThrottle(ctx, keys)
+}
+
+// checkThrottle fails with a ThrottledError while any of the keys is blocked
+func (s *AuthService) checkThrottle(ctx context.Context, keys []string) error {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		attempt, err := s.LoginAttempts.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if attempt != nil && attempt.BlockedUntil.After(now) && attempt.BlockedUntil.Sub(now) > wait {
+			wait = attempt.BlockedUntil.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed sign-in for the account and IP, blocks
+// them as the policy demands and audits lockouts. user is nil for unknown emails.
+func (s *AuthService) recordLoginFailure(ctx context.Context, email, ip string, user *models.User) error {
+	policy := s.LoginThrottle
+	targets := []throttleTarget{
+		{accountThrottleKey(email), policy.FreeAttempts, policy.LockoutThreshold, models.AuditAccountLocked},
+	}
+	if ip != "" {
+		targets = append(targets, s.ipThrottleTarget(ip))
+	}
+
+	var userID string
+	if user != nil {
+		userID = user.ID
+	}
+	return s.recordFailures(ctx, targets, userID, email, ip)
+}
+
+// ipThrottleTarget returns the per client IP counter
+func (s *AuthService) ipThrottleTarget(ip string) throttleTarget {
+	return throttleTarget{ipThrottleKey(ip), s.LoginThrottle.IPFreeAttempts, s.LoginThrottle.IPLockoutThreshold, models.AuditIPLocked}
+}
+
+// recordFailures counts a failure against each target, blocks them as the
+// policy demands and audits lockouts. userID is only recorded on lockouts of
+// targets other than the client IP.
+func (s *AuthService) recordFailures(ctx context.Context, targets []throttleTarget, userID, email, ip string) error {
+	now := time.Now()
+	policy := s.LoginThrottle
+
+	for _, target := range targets {
+		attempt, err := s.LoginAttempts.RecordFailure(ctx, target.key, now, policy.Window)
+		if err != nil {
+			return err
+		}
+
+		delay, locked := policy.block(attempt.Failures, target.free, target.lockout)
+		if delay <= 0 {
+			continue
+		}
+		until := now.Add(delay)
+		if err := s.LoginAttempts.Block(ctx, target.key, until); err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+
+		log.Printf("[WARNING] Sign-in locked for %s until %s after %d failures", target.logName(userID), until.Format(time.RFC3339), attempt.Failures)
+		event := &models.AuditEvent{
+			Type:  target.event,
+			Email: email,
+			IP:    ip,
+			Details: map[string]string{
+				"failures":     fmt.Sprint(attempt.Failures),
+				"locked_until": until.UTC().Format(time.RFC3339),
+			},
+			CreatedAt: now,
+		}
+		if target.event != models.AuditIPLocked {
+			event.UserID = userID
+		}
+		if err := s.Audit.Record(ctx, event); err != nil {
+			log.Printf("[ERROR] Failed to record audit event %s: %v", event.Type, err)
+		}
+	}
+	return nil
+}
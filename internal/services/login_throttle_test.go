@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+func TestLoginThrottlePolicyBlock(t *testing.T) {
+	policy := LoginThrottlePolicy{
+		BaseDelay:       time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutDuration: time.Hour,
+	}
+
+	tests := []struct {
+		failures   int
+		wantDelay  time.Duration
+		wantLocked bool
+	}{
+		{0, 0, false},
+		{2, 0, false},
+		{3, time.Second, false},
+		{4, 2 * time.Second, false},
+		{6, 8 * time.Second, false},
+		{7, 10 * time.Second, false},
+		{9, 10 * time.Second, false},
+		{10, time.Hour, true},
+		{15, time.Hour, true},
+	}
+	for _, tt := range tests {
+		delay, locked := policy.block(tt.failures, 3, 10)
+		if delay != tt.wantDelay || locked != tt.wantLocked {
+			t.Errorf("block(%d) = %v, %v, want %v, %v", tt.failures, delay, locked, tt.wantDelay, tt.wantLocked)
+		}
+	}
+}
+
+// newThrottledAuthService returns an AuthService whose throttle blocks after
+// two failures per account and five per IP, with alice registered
+func newThrottledAuthService(t *testing.T) *AuthService {
+	t.Helper()
+	s := newTestAuthService(t)
+	s.LoginThrottle = LoginThrottlePolicy{
+		Window:             time.Hour,
+		FreeAttempts:       2,
+		LockoutThreshold:   4,
+		IPFreeAttempts:     5,
+		IPLockoutThreshold: 10,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+		LockoutDuration:    24 * time.Hour,
+	}
+	registerTestUser(t, s)
+	return s
+}
+
+func TestLoginThrottling(t *testing.T) {
+	tests := []struct {
+		name string
+		// failures are the email and IP of each failed attempt made first
+		failures [][2]string
+		email    string
+		ip       string
+		want     error
+	}{
+		{"below the free attempts", [][2]string{{"alice@example.com", "10.0.0.1"}}, "alice@example.com", "10.0.0.1", nil},
+		{"account blocked", [][2]string{
+			{"alice@example.com", "10.0.0.1"},
+			{"alice@example.com", "10.0.0.2"},
+		}, "alice@example.com", "10.0.0.3", ErrTooManyAttempts},
+		{"account blocked whatever the email's case", [][2]string{
+			{"alice@example.com", "10.0.0.1"},
+			{"ALICE@example.com", "10.0.0.2"},
+		}, " Alice@Example.com", "10.0.0.3", ErrTooManyAttempts},
+		{"failures for other accounts", [][2]string{
+			{"bob@example.com", "10.0.0.1"},
+			{"bob@example.com", "10.0.0.2"},
+		}, "alice@example.com", "10.0.0.3", nil},
+		{"IP blocked across accounts", [][2]string{
+			{"a@example.com", "10.0.0.1"},
+			{"b@example.com", "10.0.0.1"},
+			{"c@example.com", "10.0.0.1"},
+			{"d@example.com", "10.0.0.1"},
+			{"e@example.com", "10.0.0.1"},
+		}, "alice@example.com", "10.0.0.1", ErrTooManyAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newThrottledAuthService(t)
+			for _, failure := range tt.failures {
+				if _, _, err := s.Login(failure[0], "wrong-password", ClientInfo{IP: failure[1]}); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Login(%s, wrong password) = %v, want ErrInvalidCredentials", failure[0], err)
+				}
+			}
+
+			// Even the right password is refused while blocked
+			_, _, err := s.Login(tt.email, testPassword, ClientInfo{IP: tt.ip})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Login() = %v, want %v", err, tt.want)
+			}
+			var throttled *ThrottledError
+			if errors.As(err, &throttled) && throttled.RetryAfter <= 0 {
+				t.Fatalf("ThrottledError.RetryAfter = %v, want positive", throttled.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestLoginSuccessResetsAccountFailures(t *testing.T) {
+	s := newThrottledAuthService(t)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := s.Login("alice@example.com", "wrong-password", ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Login(wrong password) = %v, want ErrInvalidCredentials", err)
+		}
+		if _, _, err := s.Login("alice@example.com", testPassword, ClientInfo{}); err != nil {
+			t.Fatalf("Login() after %d failure = %v", i+1, err)
+		}
+	}
+}
+
+func TestLoginLockoutIsAudited(t *testing.T) {
+	s := newThrottledAuthService(t)
+	attempts := s.LoginAttempts.(*repository.MemoryLoginAttemptRepository)
+
+	// Each failure after the free ones blocks the account; the block is lifted
+	// before every attempt instead of waiting it out
+	for i := 0; i < s.LoginThrottle.LockoutThreshold; i++ {
+		if err := attempts.Block(context.Background(), accountThrottleKey("alice@example.com"), time.Time{}); err != nil {
+			t.Fatalf("Block() = %v", err)
+		}
+		if _, _, err := s.Login("alice@example.com", "wrong-password", ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Login(wrong password) = %v, want ErrInvalidCredentials", err)
+		}
+	}
+
+	_, _, err := s.Login("alice@example.com", testPassword, ClientInfo{})
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter < 23*time.Hour {
+		t.Fatalf("Login() = %v, want a lockout of the account", err)
+	}
+
+	events := s.Audit.(*repository.MemoryAuditLogRepository).Events()
+	if len(events) != 1 || events[0].Type != models.AuditAccountLocked || events[0].UserID == "" {
+		t.Fatalf("audit events = %+v, want one account lockout of alice", events)
+	}
+}
+
+func TestChangePasswordSharesLoginThrottle(t *testing.T) {
+	s := newThrottledAuthService(t)
+	user, err := s.Users.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() = %v", err)
+	}
+	principal := &utils.Principal{UserID: user.ID}
+	const newPassword = "another-Horse-battery-7"
+
+	// Wrong current passwords block the account for sign-ins too
+	for i := 0; i < s.LoginThrottle.FreeAttempts; i++ {
+		if err := s.ChangePassword(principal, "wrong-password", newPassword, ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, ErrIncorrectPassword) {
+			t.Fatalf("ChangePassword(wrong password) = %v, want ErrIncorrectPassword", err)
+		}
+	}
+	if _, _, err := s.Login("alice@example.com", testPassword, ClientInfo{IP: "10.0.0.2"}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Login() = %v, want ErrTooManyAttempts", err)
+	}
+
+	// Even the right password is refused while the account is blocked
+	if err := s.ChangePassword(principal, testPassword, newPassword, ClientInfo{IP: "10.0.0.2"}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("ChangePassword() = %v, want ErrTooManyAttempts", err)
+	}
+}
+
+func TestSecondFactorThrottleCoversEveryPath(t *testing.T) {
+	s := newThrottledAuthService(t)
+	user, err := s.Users.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() = %v", err)
+	}
+	secret, step, recoveryCodes := enableTwoFactor(t, s, user)
+	principal := &utils.Principal{UserID: user.ID}
+
+	// Wrong codes sent to disable two-factor authentication lock it like sign-in codes
+	for i := 0; i < s.LoginThrottle.FreeAttempts; i++ {
+		if err := s.DisableTwoFactor(principal, "000000", "10.0.0.1"); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("DisableTwoFactor(wrong code) = %v, want ErrInvalidMFACode", err)
+		}
+	}
+
+	// Even valid codes are refused while the user is blocked
+	if err := s.DisableTwoFactor(principal, totpCode(t, secret, step+1), "10.0.0.2"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("DisableTwoFactor() = %v, want ErrTooManyAttempts", err)
+	}
+	if _, err := s.RegenerateRecoveryCodes(principal, recoveryCodes[0], "10.0.0.2"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("RegenerateRecoveryCodes() = %v, want ErrTooManyAttempts", err)
+	}
+	stored, err := s.Users.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID() = %v", err)
+	}
+	if !stored.TwoFactorEnabled() || len(stored.TwoFactor.RecoveryCodes) != len(recoveryCodes) {
+		t.Fatal("a throttled code changed the user's two-factor setup")
+	}
+}
+
+func TestLinkConfirmationSharesLoginThrottle(t *testing.T) {
+	s := newThrottledAuthService(t)
+	ctx := context.Background()
+	identity := &ExternalIdentity{Provider: models.ProviderGoogle, Subject: "google-alice", Email: "alice@example.com", EmailVerified: true}
+
+	// Wrong passwords confirming a link block the account for sign-ins too
+	for i := 0; i < s.LoginThrottle.FreeAttempts; i++ {
+		if _, err := s.resolveExternalUser(ctx, identity, "wrong-password", "10.0.0.1"); !errors.Is(err, ErrIncorrectPassword) {
+			t.Fatalf("resolveExternalUser(wrong password) = %v, want ErrIncorrectPassword", err)
+		}
+	}
+	if _, _, err := s.Login("alice@example.com", testPassword, ClientInfo{IP: "10.0.0.2"}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Login() = %v, want ErrTooManyAttempts", err)
+	}
+
+	// Even the right password is refused while the account is blocked
+	if _, err := s.resolveExternalUser(ctx, identity, testPassword, "10.0.0.2"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("resolveExternalUser() = %v, want ErrTooManyAttempts", err)
+	}
+	if _, err := s.Users.GetByIdentity(ctx, models.ProviderGoogle, "google-alice"); err == nil {
+		t.Fatal("a throttled confirmation linked the identity")
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidResetToken is returned for unknown, expired or already used reset tokens
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// ForgotPassword emails a password reset link to the account with the given
+// email. Unknown emails are ignored silently and failures to send are only
+// logged, so the endpoint does not reveal which addresses have accounts.
+func (s *AuthService) ForgotPassword(email string) error {
+	ctx := context.Background()
+	email = models.NormalizeEmail(email)
+
+	user, err := s.Users.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		log.Println("[INFO] Password reset requested for an unknown email")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Accounts that only sign in through a provider have no password to reset
+	if !user.HasProvider(models.ProviderPassword) {
+		log.Printf("[INFO] Password reset requested for user %s without a password", user.ID)
+		return nil
+	}
+
+	// Failing to send is only logged, so the answer does not depend on
+	// whether the email has an account
+	if err := s.sendPasswordResetEmail(ctx, user); err != nil {
+		log.Printf("[ERROR] Failed to send password reset email to user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token, then signs the user
+// out everywhere, invalidates any other outstanding reset links and clears
+// the account's failed sign-ins
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	ctx := context.Background()
+
+	claims, err := s.Tokens.ValidateActionToken(token, models.ActionPasswordReset)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.Users.GetByID(ctx, claims.Subject)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// Check the new password before spending the token, so a rejected
+	// password leaves the link usable for another try
+	if err := s.checkPassword("password", newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	stored, err := s.ActionTokens.Consume(ctx, claims.ID, models.ActionPasswordReset)
+	if errors.Is(err, repository.ErrActionTokenNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if stored.UserID != user.ID {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user, err = s.Users.Update(ctx, user.ID, func(u *models.User) error {
+		// The password sign-in may have been unlinked since the link was sent
+		if !u.HasProvider(models.ProviderPassword) {
+			return ErrInvalidResetToken
+		}
+		u.Password = string(hashedPassword)
+		// Receiving the reset link proves the user owns the email
+		u.EmailVerified = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.ActionTokens.DeleteByUser(ctx, user.ID, models.ActionPasswordReset); err != nil {
+		return err
+	}
+	if err := s.LogoutAll(user.ID); err != nil {
+		return err
+	}
+	// The new password lifts a lockout left by failed sign-ins
+	if err := s.LoginAttempts.Reset(ctx, accountThrottleKey(user.Email)); err != nil {
+		log.Println("[ERROR] Failed to reset login failures:", err)
+	}
+
+	log.Printf("[INFO] Password reset for user %s", user.ID)
+	return nil
+}
+
+// sendPasswordResetEmail issues a reset token and emails its link to the user
+func (s *AuthService) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, claims, err := s.Tokens.GenerateActionToken(user.ID, models.ActionPasswordReset, s.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	err = s.ActionTokens.Create(ctx, &models.ActionToken{
+		ID:        claims.ID,
+		UserID:    user.ID,
+		Purpose:   models.ActionPasswordReset,
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := withToken(s.PasswordResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Bakulen password",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %s and can be used once. If you did not ask to reset your password, you can ignore this email.\n",
+			user.Username, link, s.PasswordResetTTL),
+	})
+}
+
+// checkPassword enforces the password policy for a new password, reporting
+// problems against field. userInputs are values the password must not repeat.
+func (s *AuthService) checkPassword(field, password string, userInputs ...string) error {
+	var problems validation.Errors
+	problems.CheckPassword(field, password, s.PasswordPolicy, userInputs...)
+	return problems.Err()
+}
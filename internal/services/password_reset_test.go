@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/mailer"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
+)
+
+// issueResetToken stores a password reset token for the user and returns it
+func issueResetToken(t *testing.T, s *AuthService, user *models.User) string {
+	t.Helper()
+	token, claims, err := s.Tokens.GenerateActionToken(user.ID, models.ActionPasswordReset, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateActionToken() = %v", err)
+	}
+	err = s.ActionTokens.Create(context.Background(), &models.ActionToken{
+		ID:        claims.ID,
+		UserID:    user.ID,
+		Purpose:   models.ActionPasswordReset,
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("ActionTokens.Create() = %v", err)
+	}
+	return token
+}
+
+// failingMailer fails to send every email
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return errors.New("smtp: connection refused")
+}
+
+func TestForgotPasswordAnswersAlike(t *testing.T) {
+	s := newTestAuthService(t)
+	registerTestUser(t, s)
+	s.PasswordResetURL = "https://bakulen.example.com/reset-password"
+	mails := &capturingMailer{}
+	s.Mailer = mails
+
+	if err := s.ForgotPassword(" Alice@Example.com "); err != nil {
+		t.Fatalf("ForgotPassword() = %v", err)
+	}
+	if mails.lastToken(t) == "" {
+		t.Fatal("password reset email has no token")
+	}
+	if err := s.ForgotPassword("nobody@example.com"); err != nil {
+		t.Fatalf("ForgotPassword(unknown email) = %v", err)
+	}
+	if len(mails.messages) != 1 {
+		t.Fatalf("%d emails sent, want only alice's", len(mails.messages))
+	}
+
+	// A failure to send looks like any other request
+	s.Mailer = failingMailer{}
+	if err := s.ForgotPassword("alice@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() with a failing mailer = %v, want nil", err)
+	}
+}
+
+func TestResetPasswordKeepsTokenOnRejectedPassword(t *testing.T) {
+	s := newTestAuthService(t)
+	user, _ := registerTestUser(t, s)
+	token := issueResetToken(t, s, user)
+
+	// The email passes the policy alone but not the comparison with the account
+	var problems validation.Errors
+	if err := s.ResetPassword(token, "Alice@Example.com"); !errors.As(err, &problems) {
+		t.Fatalf("ResetPassword(email) = %v, want validation errors", err)
+	}
+
+	if err := s.ResetPassword(token, "another-Horse-battery-7"); err != nil {
+		t.Fatalf("ResetPassword() after a rejected password = %v", err)
+	}
+	if err := s.ResetPassword(token, "third-Horse-battery-5"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("ResetPassword(used token) = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestResetPasswordAfterPasswordUnlinked(t *testing.T) {
+	s := newTestAuthService(t)
+	user, _ := registerTestUser(t, s)
+	token := issueResetToken(t, s, user)
+
+	_, err := s.Users.Update(context.Background(), user.ID, func(u *models.User) error {
+		u.LinkIdentity(models.Identity{Provider: models.ProviderGoogle, Subject: "google-alice", Email: u.Email})
+		u.UnlinkIdentity(models.ProviderPassword)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Users.Update() = %v", err)
+	}
+
+	if err := s.ResetPassword(token, "another-Horse-battery-7"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("ResetPassword() = %v, want ErrInvalidResetToken", err)
+	}
+	stored, err := s.Users.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID() = %v", err)
+	}
+	if stored.Password != "" || stored.HasProvider(models.ProviderPassword) {
+		t.Fatal("ResetPassword() set a password on an account without password sign-in")
+	}
+}
+
+func TestResetPasswordLiftsLockout(t *testing.T) {
+	s := newThrottledAuthService(t)
+	user, err := s.Users.GetByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() = %v", err)
+	}
+	const newPassword = "another-Horse-battery-7"
+
+	for i := 0; i < s.LoginThrottle.LockoutThreshold; i++ {
+		if err := s.LoginAttempts.Block(context.Background(), accountThrottleKey(user.Email), time.Time{}); err != nil {
+			t.Fatalf("Block() = %v", err)
+		}
+		if _, _, err := s.Login("alice@example.com", "wrong-password", ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Login(wrong password) = %v, want ErrInvalidCredentials", err)
+		}
+	}
+	if _, _, err := s.Login("alice@example.com", testPassword, ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Login() = %v, want ErrTooManyAttempts", err)
+	}
+
+	if err := s.ResetPassword(issueResetToken(t, s, user), newPassword); err != nil {
+		t.Fatalf("ResetPassword() = %v", err)
+	}
+	if _, _, err := s.Login("alice@example.com", newPassword, ClientInfo{}); err != nil {
+		t.Fatalf("Login(new password) = %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+// ErrNoSession is returned for session operations by callers whose token has no
+// session, such as Firebase ID tokens accepted as access tokens
+var ErrNoSession = errors.New("this token does not belong to a session, sign in to register the device")
+
+// ClientInfo describes the device a request comes from; every field may be empty
+type ClientInfo struct {
+	IP         string
+	UserAgent  string
+	DeviceName string
+	Platform   string
+	// FCMToken is the device's push notification token
+	FCMToken string
+}
+
+// saveSession records the session of a sign-in, or refreshes the one of an
+// existing token family with the client's latest details
+func (s *AuthService) saveSession(ctx context.Context, userID, sessionID string, client ClientInfo, expiresAt time.Time) error {
+	now := time.Now()
+
+	session, err := s.Sessions.Get(ctx, userID, sessionID)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		// Families started before sessions were recorded get one on their next refresh
+		session = &models.Session{ID: sessionID, UserID: userID, CreatedAt: now}
+	} else if err != nil {
+		return err
+	}
+
+	session.IP = client.IP
+	session.UserAgent = client.UserAgent
+	// Apps only send their device details now and then, so keep the known ones
+	if client.DeviceName != "" {
+		session.DeviceName = client.DeviceName
+	}
+	if client.Platform != "" {
+		session.Platform = client.Platform
+	}
+	if client.FCMToken != "" {
+		session.FCMToken = client.FCMToken
+	}
+	session.LastSeenAt = now
+	session.ExpiresAt = expiresAt
+	return s.Sessions.Save(ctx, session)
+}
+
+// ListSessions returns the devices the caller is signed in on, marking the caller's own
+func (s *AuthService) ListSessions(principal *utils.Principal) ([]models.Session, error) {
+	sessions, err := s.Sessions.ListByUser(context.Background(), principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == principal.SessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one of the caller's devices out: its refresh tokens
+// stop working at once and its access tokens are refused from now on
+func (s *AuthService) RevokeSession(principal *utils.Principal, sessionID string) error {
+	ctx := context.Background()
+
+	// Only sessions of the caller can be revoked
+	if _, err := s.Sessions.Get(ctx, principal.UserID, sessionID); err != nil {
+		return err
+	}
+
+	if err := s.endSession(ctx, principal.UserID, sessionID); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Session %s of user %s revoked", sessionID, principal.UserID)
+	return nil
+}
+
+// endSession revokes the refresh and access tokens of a session and forgets the session
+func (s *AuthService) endSession(ctx context.Context, userID, sessionID string) error {
+	if err := s.RefreshTokens.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	// Access tokens issued to the session expire within one access TTL at the latest
+	if err := s.Revocations.RevokeSession(ctx, sessionID, time.Now().Add(s.Tokens.TTL())); err != nil {
+		return err
+	}
+	return s.Sessions.Delete(ctx, userID, sessionID)
+}
+
+// StoreFCMToken records the push notification token of the caller's device
+func (s *AuthService) StoreFCMToken(principal *utils.Principal, fcmToken string) error {
+	ctx := context.Background()
+	if principal.SessionID == "" {
+		return ErrNoSession
+	}
+
+	session, err := s.Sessions.Get(ctx, principal.UserID, principal.SessionID)
+	if err != nil {
+		return err
+	}
+	session.FCMToken = fcmToken
+	return s.Sessions.Save(ctx, session)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+// signIn signs alice in from the device and returns her tokens and principal
+func signIn(t *testing.T, s *AuthService, client ClientInfo) (*models.AuthTokens, *utils.Principal) {
+	t.Helper()
+	tokens, _, err := s.Login("alice@example.com", testPassword, client)
+	if err != nil {
+		t.Fatalf("Login() = %v", err)
+	}
+	principal, err := s.Authenticate(context.Background(), tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	return tokens, principal
+}
+
+func TestListSessionsMarksCurrent(t *testing.T) {
+	s := newTestAuthService(t)
+	registerTestUser(t, s)
+	_, phone := signIn(t, s, ClientInfo{DeviceName: "Pixel", Platform: "android"})
+	_, laptop := signIn(t, s, ClientInfo{UserAgent: "Firefox"})
+
+	sessions, err := s.ListSessions(phone)
+	if err != nil {
+		t.Fatalf("ListSessions() = %v", err)
+	}
+	// Registering signed alice in too
+	if len(sessions) != 3 {
+		t.Fatalf("%d sessions, want 3", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID == phone.SessionID) {
+			t.Errorf("session %s Current = %v, want it only on %s", session.ID, session.Current, phone.SessionID)
+		}
+		if session.ID == phone.SessionID && session.DeviceName != "Pixel" {
+			t.Errorf("phone session = %+v, want its device name", session)
+		}
+		if session.ID == laptop.SessionID && session.UserAgent != "Firefox" {
+			t.Errorf("laptop session = %+v, want its user agent", session)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	s := newTestAuthService(t)
+	registerTestUser(t, s)
+	phoneTokens, phone := signIn(t, s, ClientInfo{DeviceName: "Pixel"})
+	laptopTokens, laptop := signIn(t, s, ClientInfo{DeviceName: "Laptop"})
+	ctx := context.Background()
+
+	if err := s.RevokeSession(laptop, phone.SessionID); err != nil {
+		t.Fatalf("RevokeSession() = %v", err)
+	}
+
+	// The revoked session's tokens stop working at once
+	if _, err := s.Authenticate(ctx, phoneTokens.AccessToken); !errors.Is(err, utils.ErrTokenRevoked) {
+		t.Fatalf("Authenticate(revoked session) = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := s.Refresh(phoneTokens.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh(revoked session) = %v, want ErrInvalidRefreshToken", err)
+	}
+	if err := s.RevokeSession(laptop, phone.SessionID); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Fatalf("RevokeSession() again = %v, want ErrSessionNotFound", err)
+	}
+
+	// Other sessions stay signed in
+	if _, err := s.Authenticate(ctx, laptopTokens.AccessToken); err != nil {
+		t.Fatalf("Authenticate(other session) = %v", err)
+	}
+	if _, err := s.Refresh(laptopTokens.RefreshToken, ClientInfo{}); err != nil {
+		t.Fatalf("Refresh(other session) = %v", err)
+	}
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	s := newTestAuthService(t)
+	registerTestUser(t, s)
+	aliceTokens, alice := signIn(t, s, ClientInfo{})
+	_, bobTokens, err := s.Register("bob@example.com", "bob", testPassword, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+	bob, err := s.Authenticate(context.Background(), bobTokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+
+	if err := s.RevokeSession(bob, alice.SessionID); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Fatalf("RevokeSession(alice's session) = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := s.Authenticate(context.Background(), aliceTokens.AccessToken); err != nil {
+		t.Fatalf("Authenticate() after bob's attempt = %v", err)
+	}
+}
+
+func TestStoreFCMTokenPerSession(t *testing.T) {
+	s := newTestAuthService(t)
+	registerTestUser(t, s)
+	_, phone := signIn(t, s, ClientInfo{})
+	_, tablet := signIn(t, s, ClientInfo{FCMToken: "tablet-fcm"})
+	ctx := context.Background()
+
+	if err := s.StoreFCMToken(phone, "phone-fcm"); err != nil {
+		t.Fatalf("StoreFCMToken() = %v", err)
+	}
+
+	for sessionID, want := range map[string]string{phone.SessionID: "phone-fcm", tablet.SessionID: "tablet-fcm"} {
+		session, err := s.Sessions.Get(ctx, phone.UserID, sessionID)
+		if err != nil {
+			t.Fatalf("Sessions.Get() = %v", err)
+		}
+		if session.FCMToken != want {
+			t.Errorf("session %s FCM token = %q, want %q", sessionID, session.FCMToken, want)
+		}
+	}
+
+	// Tokens without a session, such as Firebase ID tokens, have no device to store it on
+	if err := s.StoreFCMToken(&utils.Principal{UserID: phone.UserID}, "fcm"); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("StoreFCMToken(no session) = %v, want ErrNoSession", err)
+	}
+}
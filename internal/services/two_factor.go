@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/totp"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+var (
+	// ErrTwoFactorEnabled is returned when enrolling while two-factor authentication is already on
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned for operations that need two-factor authentication to be on
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotEnrolled is returned when confirming before enrolling
+	ErrTwoFactorNotEnrolled = errors.New("start two-factor enrollment before confirming it")
+	// ErrInvalidMFACode is returned for wrong, reused or malformed codes
+	ErrInvalidMFACode = errors.New("invalid authentication code")
+	// ErrInvalidMFAChallenge is returned for unknown, expired or already used challenge tokens
+	ErrInvalidMFAChallenge = errors.New("invalid or expired two-factor challenge, sign in again")
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets at a time
+	recoveryCodeCount = 10
+	// recoveryCodeAlphabet leaves out characters that are easily confused
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	// recoveryCodeLength gives codes close to 80 bits of entropy, enough for an unsalted hash
+	recoveryCodeLength = 16
+	// qrCodeSize is the width and height of enrollment QR codes in pixels
+	qrCodeSize = 256
+)
+
+// completeLogin finishes a first-factor sign-in: users with two-factor
+// authentication get a challenge to answer, everyone else their tokens
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, client ClientInfo) (*models.AuthTokens, *models.MFAChallenge, error) {
+	if !user.TwoFactorEnabled() {
+		tokens, err := s.issueTokens(ctx, user, "", client)
+		return tokens, nil, err
+	}
+
+	token, claims, err := s.Tokens.GenerateActionToken(user.ID, models.ActionMFAChallenge, s.MFAChallengeTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = s.ActionTokens.Create(ctx, &models.ActionToken{
+		ID:        claims.ID,
+		UserID:    user.ID,
+		Purpose:   models.ActionMFAChallenge,
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("[INFO] Two-factor challenge issued to user %s", user.ID)
+	return nil, &models.MFAChallenge{
+		MFARequired:    true,
+		ChallengeToken: token,
+		Methods:        []string{models.MFAMethodTOTP, models.MFAMethodRecoveryCode},
+		ExpiresIn:      int64(s.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// VerifyMFA answers a sign-in challenge with a TOTP or recovery code and
+// returns the tokens the sign-in was waiting for. Wrong codes are throttled
+// per user and client IP like failed passwords.
+func (s *AuthService) VerifyMFA(challengeToken, code string, client ClientInfo) (*models.AuthTokens, error) {
+	ctx := context.Background()
+
+	claims, err := s.Tokens.ValidateActionToken(challengeToken, models.ActionMFAChallenge)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	// ✅ Each challenge answers one sign-in; claiming it before the code is
+	// checked keeps concurrent answers from spending more than one code
+	stored, err := s.ActionTokens.Consume(ctx, claims.ID, models.ActionMFAChallenge)
+	if errors.Is(err, repository.ErrActionTokenNotFound) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.UserID != claims.Subject {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.Users.GetByID(ctx, claims.Subject)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Two-factor authentication was reset while the challenge was pending
+	if !user.TwoFactorEnabled() {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	user, err = s.useSecondFactor(ctx, user, code, client.IP, nil)
+	if errors.Is(err, ErrInvalidMFACode) || errors.Is(err, ErrTooManyAttempts) {
+		// A wrong or throttled code leaves the challenge open for another
+		// try; the throttle limits how many
+		if err := s.ActionTokens.Create(ctx, stored); err != nil {
+			log.Println("[ERROR] Failed to reopen two-factor challenge:", err)
+		}
+		return nil, err
+	}
+	if errors.Is(err, ErrTwoFactorNotEnabled) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Two-factor sign-in completed for user %s", user.ID)
+	return s.issueTokens(ctx, user, "", client)
+}
+
+// EnrollTOTP starts two-factor enrollment with a new TOTP secret for the
+// caller's authenticator app. Enrolling again replaces a pending secret.
+func (s *AuthService) EnrollTOTP(principal *utils.Principal) (*models.TOTPEnrollment, error) {
+	ctx := context.Background()
+
+	user, err := s.Users.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	user, err = s.Users.Update(ctx, user.ID, func(u *models.User) error {
+		if u.TwoFactorEnabled() {
+			return ErrTwoFactorEnabled
+		}
+		u.TwoFactor = &models.TwoFactor{Secret: secret}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	uri := totp.URI(s.TOTPIssuer, account, secret)
+	png, err := totp.QRCode(uri, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmTOTP turns two-factor authentication on once code shows the
+// authenticator app was set up, and returns the recovery codes. They are
+// shown this once; only their hashes are kept. Every other session is signed
+// out, since it did not pass the second factor.
+func (s *AuthService) ConfirmTOTP(principal *utils.Principal, code string) ([]string, error) {
+	ctx := context.Background()
+
+	user, err := s.Users.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TwoFactor == nil || user.TwoFactor.Secret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	secret := user.TwoFactor.Secret
+	user, err = s.Users.Update(ctx, user.ID, func(u *models.User) error {
+		if u.TwoFactorEnabled() {
+			return ErrTwoFactorEnabled
+		}
+		// The code was checked against this secret; enrolling again replaced it
+		if u.TwoFactor == nil || u.TwoFactor.Secret != secret {
+			return ErrTwoFactorNotEnrolled
+		}
+		u.TwoFactor.Enabled = true
+		u.TwoFactor.EnabledAt = time.Now()
+		u.TwoFactor.LastStep = step
+		u.TwoFactor.RecoveryCodes = hashes
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revokeSessions(ctx, user.ID, principal.SessionID); err != nil {
+		return nil, err
+	}
+
+	s.auditTwoFactor(ctx, models.AuditMFAEnabled, user, nil)
+	log.Printf("[INFO] Two-factor authentication enabled for user %s", user.ID)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes after checking
+// a current code, and returns the new ones
+func (s *AuthService) RegenerateRecoveryCodes(principal *utils.Principal, code, clientIP string) ([]string, error) {
+	ctx := context.Background()
+
+	user, err := s.Users.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.useSecondFactor(ctx, user, code, clientIP, func(u *models.User) {
+		u.TwoFactor.RecoveryCodes = hashes
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Recovery codes regenerated for user %s", user.ID)
+	return codes, nil
+}
+
+// DisableTwoFactor turns the caller's two-factor authentication off after checking a current code
+func (s *AuthService) DisableTwoFactor(principal *utils.Principal, code, clientIP string) error {
+	ctx := context.Background()
+
+	user, err := s.Users.GetByID(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	_, err = s.useSecondFactor(ctx, user, code, clientIP, func(u *models.User) {
+		u.TwoFactor = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditTwoFactor(ctx, models.AuditMFADisabled, user, nil)
+	log.Printf("[INFO] Two-factor authentication disabled for user %s", user.ID)
+	return nil
+}
+
+// ResetTwoFactor turns a user's two-factor authentication off for an admin,
+// for users who lost both their authenticator and recovery codes. The user is
+// signed out everywhere.
+func (s *AuthService) ResetTwoFactor(adminID, userID string) error {
+	ctx := context.Background()
+
+	user, err := s.Users.Update(ctx, userID, func(u *models.User) error {
+		if u.TwoFactor == nil {
+			return ErrTwoFactorNotEnabled
+		}
+		u.TwoFactor = nil
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.LogoutAll(user.ID); err != nil {
+		return err
+	}
+
+	s.auditTwoFactor(ctx, models.AuditMFAReset, user, map[string]string{"reset_by": adminID})
+	log.Printf("[INFO] Two-factor authentication of user %s reset by %s", user.ID, adminID)
+	return nil
+}
+
+// useSecondFactor accepts a TOTP code or an unused recovery code of the user,
+// marks it used and applies then, if not nil, in one user update, so a code
+// can only be used once even by concurrent requests. It returns the updated
+// user. Codes are refused while the user's or client IP's throttle counters
+// are blocked, and failures are recorded against them.
+func (s *AuthService) useSecondFactor(ctx context.Context, user *models.User, code, clientIP string, then func(u *models.User)) (*models.User, error) {
+	keys := []string{mfaThrottleKey(user.ID)}
+	if clientIP != "" {
+		keys = append(keys, ipThrottleKey(clientIP))
+	}
+	if err := s.checkThrottle(ctx, keys); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updated, err := s.Users.Update(ctx, user.ID, func(u *models.User) error {
+		// Checked against the stored user, as another request may have used the code
+		if !u.TwoFactorEnabled() {
+			return ErrTwoFactorNotEnabled
+		}
+		if !consumeSecondFactor(u.TwoFactor, code, now) {
+			return ErrInvalidMFACode
+		}
+		if then != nil {
+			then(u)
+		}
+		return nil
+	})
+	if err == nil {
+		if err := s.LoginAttempts.Reset(ctx, mfaThrottleKey(user.ID)); err != nil {
+			log.Println("[ERROR] Failed to reset two-factor failures:", err)
+		}
+		return updated, nil
+	}
+	if !errors.Is(err, ErrInvalidMFACode) {
+		return nil, err
+	}
+
+	log.Printf("[WARNING] Invalid two-factor code for user %s", user.ID)
+	targets := []throttleTarget{
+		{mfaThrottleKey(user.ID), s.LoginThrottle.FreeAttempts, s.LoginThrottle.LockoutThreshold, models.AuditMFALocked},
+	}
+	if clientIP != "" {
+		targets = append(targets, s.ipThrottleTarget(clientIP))
+	}
+	if err := s.recordFailures(ctx, targets, user.ID, user.Email, clientIP); err != nil {
+		log.Println("[ERROR] Failed to record two-factor failure:", err)
+	}
+	return nil, ErrInvalidMFACode
+}
+
+// consumeSecondFactor reports whether code is a TOTP code newer than the last
+// one used or one of the recovery codes, and marks it used on tf
+func consumeSecondFactor(tf *models.TwoFactor, code string, now time.Time) bool {
+	if step, ok := totp.Validate(tf.Secret, code, now); ok {
+		if step <= tf.LastStep {
+			return false
+		}
+		tf.LastStep = step
+		return true
+	}
+
+	hash := utils.HashOpaqueToken(normalizeRecoveryCode(code))
+	for i, stored := range tf.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			tf.RecoveryCodes = append(tf.RecoveryCodes[:i:i], tf.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// generateRecoveryCodes returns new recovery codes formatted as xxxx-xxxx-xxxx-xxxx, and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		var b strings.Builder
+		for j := 0; j < recoveryCodeLength; j++ {
+			if j > 0 && j%4 == 0 {
+				b.WriteByte('-')
+			}
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+			if err != nil {
+				return nil, nil, err
+			}
+			b.WriteByte(recoveryCodeAlphabet[n.Int64()])
+		}
+		codes[i] = b.String()
+		hashes[i] = utils.HashOpaqueToken(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes in typed recovery codes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// auditTwoFactor records a change of the user's two-factor setup
+func (s *AuthService) auditTwoFactor(ctx context.Context, eventType string, user *models.User, details map[string]string) {
+	event := &models.AuditEvent{
+		Type:      eventType,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if err := s.Audit.Record(ctx, event); err != nil {
+		log.Printf("[ERROR] Failed to record audit event %s: %v", event.Type, err)
+	}
+}
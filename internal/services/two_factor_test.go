@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/totp"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+// totpCode returns the secret's code for the time step
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatalf("totp.Code() = %v", err)
+	}
+	return code
+}
+
+// enableTwoFactor turns on two-factor authentication for the user with the
+// current TOTP code, and returns the secret, the step of the code it was
+// confirmed with and the recovery codes
+func enableTwoFactor(t *testing.T, s *AuthService, user *models.User) (string, int64, []string) {
+	t.Helper()
+	principal := &utils.Principal{UserID: user.ID}
+	enrollment, err := s.EnrollTOTP(principal)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() = %v", err)
+	}
+	step := totp.Step(time.Now())
+	codes, err := s.ConfirmTOTP(principal, totpCode(t, enrollment.Secret, step))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() = %v", err)
+	}
+	return enrollment.Secret, step, codes
+}
+
+func TestConsumeSecondFactor(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() = %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes() = %v", err)
+	}
+	now := time.Now()
+	step := totp.Step(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		want     bool
+		// wantCodes is how many recovery codes are left
+		wantCodes int
+	}{
+		{"current TOTP code", totpCode(t, secret, step), step - 1, true, len(codes)},
+		{"TOTP code of the last used step", totpCode(t, secret, step), step, false, len(codes)},
+		{"TOTP code older than the last used step", totpCode(t, secret, step-1), step, false, len(codes)},
+		{"TOTP code of the next step", totpCode(t, secret, step+1), step, true, len(codes)},
+		{"TOTP code beyond the allowed drift", totpCode(t, secret, step+2), step - 1, false, len(codes)},
+		{"recovery code", codes[3], step, true, len(codes) - 1},
+		{"recovery code as typed", " " + strings.ToUpper(strings.ReplaceAll(codes[3], "-", " ")), step, true, len(codes) - 1},
+		{"unknown code", "aaaa-bbbb-cccc-dddd", step, false, len(codes)},
+		{"empty code", "", step, false, len(codes)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := &models.TwoFactor{
+				Enabled:       true,
+				Secret:        secret,
+				LastStep:      tt.lastStep,
+				RecoveryCodes: append([]string(nil), hashes...),
+			}
+
+			if got := consumeSecondFactor(tf, tt.code, now); got != tt.want {
+				t.Fatalf("consumeSecondFactor() = %v, want %v", got, tt.want)
+			}
+			if len(tf.RecoveryCodes) != tt.wantCodes {
+				t.Fatalf("%d recovery codes left, want %d", len(tf.RecoveryCodes), tt.wantCodes)
+			}
+			// An accepted code cannot be used again
+			if tt.want && consumeSecondFactor(tf, tt.code, now) {
+				t.Fatal("consumeSecondFactor() accepted the same code twice")
+			}
+		})
+	}
+}
+
+func TestUseSecondFactorConcurrently(t *testing.T) {
+	tests := []struct {
+		name string
+		code func(secret string, step int64, recoveryCodes []string) string
+	}{
+		// The step the enrollment was confirmed with is used, the next one is not
+		{"TOTP code", func(secret string, step int64, recoveryCodes []string) string { return totpCode(t, secret, step+1) }},
+		{"recovery code", func(secret string, step int64, recoveryCodes []string) string { return recoveryCodes[0] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestAuthService(t)
+			user, _ := registerTestUser(t, s)
+			secret, step, recoveryCodes := enableTwoFactor(t, s, user)
+			code := tt.code(secret, step, recoveryCodes)
+
+			const callers = 10
+			errs := make(chan error, callers)
+			var wg sync.WaitGroup
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := s.useSecondFactor(context.Background(), user, code, "", nil)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			accepted := 0
+			for err := range errs {
+				switch {
+				case err == nil:
+					accepted++
+				// Losing callers' failures may block the later ones
+				case !errors.Is(err, ErrInvalidMFACode) && !errors.Is(err, ErrTooManyAttempts):
+					t.Fatalf("useSecondFactor() = %v, want nil, ErrInvalidMFACode or ErrTooManyAttempts", err)
+				}
+			}
+			if accepted != 1 {
+				t.Fatalf("code accepted %d times, want once", accepted)
+			}
+		})
+	}
+}
+
+func TestVerifyMFA(t *testing.T) {
+	s := newTestAuthService(t)
+	user, _ := registerTestUser(t, s)
+	_, _, recoveryCodes := enableTwoFactor(t, s, user)
+
+	login := func() string {
+		t.Helper()
+		tokens, challenge, err := s.Login("alice@example.com", testPassword, ClientInfo{})
+		if err != nil || tokens != nil || challenge == nil {
+			t.Fatalf("Login() = %v, %v, %v, want a challenge", tokens, challenge, err)
+		}
+		return challenge.ChallengeToken
+	}
+
+	challenge := login()
+	if _, err := s.VerifyMFA(challenge, "000000", ClientInfo{}); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("VerifyMFA(wrong code) = %v, want ErrInvalidMFACode", err)
+	}
+	tokens, err := s.VerifyMFA(challenge, recoveryCodes[0], ClientInfo{})
+	if err != nil {
+		t.Fatalf("VerifyMFA() = %v", err)
+	}
+	if _, err := s.Authenticate(context.Background(), tokens.AccessToken); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+
+	// Each challenge answers one sign-in, each recovery code is used once
+	if _, err := s.VerifyMFA(challenge, recoveryCodes[1], ClientInfo{}); !errors.Is(err, ErrInvalidMFAChallenge) {
+		t.Fatalf("VerifyMFA(used challenge) = %v, want ErrInvalidMFAChallenge", err)
+	}
+	if _, err := s.VerifyMFA(login(), recoveryCodes[0], ClientInfo{}); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("VerifyMFA(used recovery code) = %v, want ErrInvalidMFACode", err)
+	}
+	if _, err := s.VerifyMFA("not-a-challenge", recoveryCodes[1], ClientInfo{}); !errors.Is(err, ErrInvalidMFAChallenge) {
+		t.Fatalf("VerifyMFA(unknown challenge) = %v, want ErrInvalidMFAChallenge", err)
+	}
+
+	// Codes sent with a used challenge stay unspent
+	if _, err := s.VerifyMFA(login(), recoveryCodes[1], ClientInfo{}); err != nil {
+		t.Fatalf("VerifyMFA(code sent with a used challenge) = %v", err)
+	}
+}
+
+func TestDisableTwoFactorNeedsFreshCode(t *testing.T) {
+	s := newTestAuthService(t)
+	user, _ := registerTestUser(t, s)
+	secret, step, _ := enableTwoFactor(t, s, user)
+	principal := &utils.Principal{UserID: user.ID}
+
+	// The code the enrollment was confirmed with is spent
+	if err := s.DisableTwoFactor(principal, totpCode(t, secret, step), ""); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("DisableTwoFactor(used code) = %v, want ErrInvalidMFACode", err)
+	}
+	if err := s.DisableTwoFactor(principal, totpCode(t, secret, step+1), ""); err != nil {
+		t.Fatalf("DisableTwoFactor() = %v", err)
+	}
+	if err := s.DisableTwoFactor(principal, totpCode(t, secret, step+1), ""); !errors.Is(err, ErrTwoFactorNotEnabled) {
+		t.Fatalf("DisableTwoFactor() again = %v, want ErrTwoFactorNotEnabled", err)
+	}
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/blob"
+	"github.com/Dffarhn/bakulenapi/pkg/imaging"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
+)
+
+// MaxImageUploadBytes bounds the size of an uploaded image, whether sent with
+// a form or straight to storage
+const MaxImageUploadBytes = 10 << 20
+
+const (
+	// purgeInterval is how often starting an upload purges expired ones
+	purgeInterval = 10 * time.Minute
+	// purgeBatch bounds how many expired uploads one purge deletes
+	purgeBatch = 100
+)
+
+var (
+	// ErrUploadsUnavailable is returned when no blob store is configured
+	ErrUploadsUnavailable = errors.New("image uploads are not available")
+	// ErrUploadTooLarge is returned for files over MaxImageUploadBytes
+	ErrUploadTooLarge = fmt.Errorf("images must be at most %d MB", MaxImageUploadBytes>>20)
+	// ErrUploadIncomplete is returned when completing an upload whose file was never sent
+	ErrUploadIncomplete = errors.New("the file has not been uploaded yet")
+	// ErrUploadExpired is returned when completing an upload after its URL expired
+	ErrUploadExpired = errors.New("the upload has expired, start a new one")
+	// ErrUnknownUploadPurpose is returned for uploads whose purpose has nowhere to go
+	ErrUnknownUploadPurpose = errors.New("unknown upload purpose")
+)
+
+// UploadService lets clients send files straight to the blob store through
+// presigned URLs, then processes and attaches them once they complete
+type UploadService struct {
+	Uploads repository.UploadRepository
+	Blobs   blob.Store
+	Users   *UserService
+	// TTL is how long the presigned URL and the pending upload stay valid
+	TTL time.Duration
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewUploadService creates an UploadService; blobs may be nil when no storage is configured
+func NewUploadService(uploads repository.UploadRepository, blobs blob.Store, users *UserService, ttl time.Duration) *UploadService {
+	return &UploadService{
+		Uploads: uploads,
+		Blobs:   blobs,
+		Users:   users,
+		TTL:     ttl,
+	}
+}
+
+// StartUpload records a pending upload of size bytes and returns the presigned
+// URL the client sends the file to. Invalid requests are reported as
+// validation.Errors.
+func (s *UploadService) StartUpload(userID, purpose, contentType string, size int64) (*models.UploadTicket, error) {
+	if s.Blobs == nil {
+		return nil, ErrUploadsUnavailable
+	}
+
+	var errs validation.Errors
+	// Listings will bring their own purposes; for now uploads only become profile pictures
+	if purpose != models.UploadPurposeProfilePicture {
+		errs.Add("purpose", fmt.Sprintf("must be %q", models.UploadPurposeProfilePicture))
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !imaging.Supported(mediaType) {
+		errs.Add("content_type", imaging.ErrUnsupportedType.Error())
+	}
+	if size <= 0 {
+		errs.Add("size", "must be positive")
+	} else if size > MaxImageUploadBytes {
+		errs.Add("size", ErrUploadTooLarge.Error())
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	s.purgeIfDue(ctx)
+
+	id, err := generateUUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	upload := &models.Upload{
+		ID:          id,
+		UserID:      userID,
+		Purpose:     purpose,
+		Key:         "uploads/" + userID + "/" + id,
+		ContentType: mediaType,
+		MaxBytes:    size,
+		ExpiresAt:   now.Add(s.TTL),
+		CreatedAt:   now,
+	}
+
+	signOpts := blob.SignedURLOptions{
+		Method:      http.MethodPut,
+		Expires:     upload.ExpiresAt,
+		ContentType: upload.ContentType,
+		MaxBytes:    upload.MaxBytes,
+	}
+	url, err := s.Blobs.SignedURL(ctx, upload.Key, signOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign upload URL: %v", err)
+	}
+	if err := s.Uploads.Create(ctx, upload); err != nil {
+		return nil, fmt.Errorf("failed to start upload: %v", err)
+	}
+
+	return &models.UploadTicket{
+		UploadID:  upload.ID,
+		URL:       url,
+		Method:    http.MethodPut,
+		Headers:   s.Blobs.UploadHeaders(signOpts),
+		MaxBytes:  upload.MaxBytes,
+		ExpiresAt: upload.ExpiresAt,
+	}, nil
+}
+
+// CompleteUpload checks the file the client sent for an upload, processes it
+// and attaches it for the upload's purpose, returning the updated profile.
+// Expired uploads, files that are too large and files that are not the
+// declared image type are discarded along with their upload. The upload is
+// claimed first, so concurrent completions fail with
+// repository.ErrUploadClaimed instead of attaching the file twice.
+func (s *UploadService) CompleteUpload(userID, uploadID string) (*models.PrivateProfile, error) {
+	if s.Blobs == nil {
+		return nil, ErrUploadsUnavailable
+	}
+	ctx := context.Background()
+
+	upload, err := s.Uploads.Get(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	// Other users' uploads are none of the caller's business
+	if upload.UserID != userID {
+		return nil, repository.ErrUploadNotFound
+	}
+	if !upload.ExpiresAt.After(time.Now()) {
+		s.discard(ctx, upload)
+		return nil, ErrUploadExpired
+	}
+	if upload.Purpose != models.UploadPurposeProfilePicture {
+		s.discard(ctx, upload)
+		return nil, fmt.Errorf("%w %q", ErrUnknownUploadPurpose, upload.Purpose)
+	}
+
+	upload, err = s.Uploads.Claim(ctx, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.readUpload(ctx, upload)
+	if err == nil {
+		err = s.Users.UpdateUser(userID, nil, data)
+	}
+	switch {
+	case err == nil:
+		s.discard(ctx, upload)
+	case errors.Is(err, ErrUploadTooLarge), errors.Is(err, imaging.ErrUnsupportedType), errors.Is(err, imaging.ErrTooManyPixels):
+		// Sending the same file again cannot fix it
+		s.discard(ctx, upload)
+		return nil, err
+	default:
+		// Let the client send the file or complete the upload again
+		if err := s.Uploads.Release(ctx, upload.ID); err != nil {
+			log.Printf("Error releasing upload %s: %v", upload.ID, err)
+		}
+		return nil, err
+	}
+
+	return s.Users.GetPrivateProfile(userID)
+}
+
+// readUpload returns the file sent for the upload after checking that it is
+// within the upload's size and of its declared image type
+func (s *UploadService) readUpload(ctx context.Context, upload *models.Upload) ([]byte, error) {
+	object, err := s.Blobs.Stat(ctx, upload.Key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrUploadIncomplete
+	}
+	if err != nil {
+		return nil, err
+	}
+	if object.Size > upload.MaxBytes {
+		return nil, ErrUploadTooLarge
+	}
+
+	reader, _, err := s.Blobs.Get(ctx, upload.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, upload.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > upload.MaxBytes {
+		return nil, ErrUploadTooLarge
+	}
+
+	// The signed content type only binds the header, so check the data itself
+	contentType, err := imaging.Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != upload.ContentType {
+		return nil, fmt.Errorf("%w, declared %s but got %s", imaging.ErrUnsupportedType, upload.ContentType, contentType)
+	}
+	return data, nil
+}
+
+// PurgeExpired deletes the files and records of uploads that expired without
+// being completed, leaving those a completion still holds. It returns how
+// many were deleted.
+func (s *UploadService) PurgeExpired(ctx context.Context) (int, error) {
+	if s.Blobs == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	expired, err := s.Uploads.ListExpired(ctx, now, purgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i := range expired {
+		if repository.ClaimHeld(&expired[i], now) {
+			continue
+		}
+		s.discard(ctx, &expired[i])
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeIfDue runs PurgeExpired at most once per purgeInterval; failures are
+// only logged, as the next purge retries
+func (s *UploadService) purgeIfDue(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastPurge) < purgeInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPurge = time.Now()
+	s.mu.Unlock()
+
+	purged, err := s.PurgeExpired(ctx)
+	if err != nil {
+		log.Printf("Error purging expired uploads: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("[INFO] Purged %d expired uploads", purged)
+	}
+}
+
+// discard removes the raw file and the record of an upload; failures are only
+// logged, as abandoned files expire with the bucket's lifecycle rule
+func (s *UploadService) discard(ctx context.Context, upload *models.Upload) {
+	if err := s.Blobs.Delete(ctx, upload.Key); err != nil {
+		log.Printf("Error deleting upload %s: %v", upload.ID, err)
+	}
+	if err := s.Uploads.Delete(ctx, upload.ID); err != nil {
+		log.Printf("Error deleting upload %s: %v", upload.ID, err)
+	}
+}
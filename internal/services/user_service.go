@@ -2,77 +2,304 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
-	"cloud.google.com/go/firestore"
-	"github.com/Dffarhn/bakulenapi/config"
 	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/internal/repository"
+	"github.com/Dffarhn/bakulenapi/pkg/imaging"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/Dffarhn/bakulenapi/pkg/validation"
 )
 
+// SessionRevoker signs a user out of every session; *AuthService implements it
+type SessionRevoker interface {
+	LogoutAll(userID string) error
+}
+
 type UserService struct {
-	FirestoreClient *firestore.Client
+	Users repository.UserRepository
+	// Sessions signs users out when their roles change
+	Sessions SessionRevoker
+	// Uploader and Images store pictures and turn their keys into URLs; both
+	// are nil when no storage is configured
+	Uploader *utils.ImageUploader
+	Images   utils.ImageURLs
 }
 
-func NewUserService() *UserService {
+func NewUserService(users repository.UserRepository, sessions SessionRevoker, uploader *utils.ImageUploader, images utils.ImageURLs) *UserService {
 	return &UserService{
-		FirestoreClient: config.GetFirestoreClient(),
+		Users:    users,
+		Sessions: sessions,
+		Uploader: uploader,
+		Images:   images,
 	}
 }
 
 func (s *UserService) GetUser(id string) (*models.User, error) {
-    userRef := s.FirestoreClient.Collection("users").Doc(id)
-    userDoc, err := userRef.Get(context.Background())
-    if err != nil {
-        return nil, err
-    }
-
-    // Map Firestore document fields to the User struct
-    var user models.User
-    err = userDoc.DataTo(&user)
-    if err != nil {
-        return nil, err
-    }
-
-    // Set the user ID manually since Firestore does not include it in the document fields
-    user.ID = userDoc.Ref.ID
-
-    return &user, nil
+	return s.Users.GetByID(context.Background(), id)
+}
+
+// GetPrivateProfile returns the user's own view of their account
+func (s *UserService) GetPrivateProfile(id string) (*models.PrivateProfile, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := user.PrivateProfile()
+	if err := s.resolvePictures(&profile.PublicProfile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetPublicProfile returns what anyone may see of the user
+func (s *UserService) GetPublicProfile(id string) (*models.PublicProfile, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := user.PublicProfile()
+	if err := s.resolvePictures(&profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
 }
 
-// UpdateUser updates the user's fields in Firestore (e.g., username, profile_picture)
-// UpdateUser updates the user's fields dynamically
-func (s *UserService) UpdateUser(id string, data map[string]interface{}) error {
-	// Reference to the user's document in Firestore
-	userRef := s.FirestoreClient.Collection("users").Doc(id)
+// resolvePictures replaces the object keys of the profile pictures with URLs
+func (s *UserService) resolvePictures(profile *models.PublicProfile) error {
+	if s.Images == nil {
+		return nil
+	}
+
+	picture, err := s.Images.URL(profile.ProfilePicture)
+	if err != nil {
+		return fmt.Errorf("failed to resolve profile picture: %w", err)
+	}
+	profile.ProfilePicture = picture
 
-	// Prepare the updates dynamically
-	var updates []firestore.Update
+	if profile.ProfilePictureVariants == nil {
+		return nil
+	}
+	variants := make(map[string]string, len(profile.ProfilePictureVariants))
+	for size, key := range profile.ProfilePictureVariants {
+		if variants[size], err = s.Images.URL(key); err != nil {
+			return fmt.Errorf("failed to resolve profile picture: %w", err)
+		}
+	}
+	profile.ProfilePictureVariants = variants
+	return nil
+}
 
-	// Check if username is provided and add it to the updates
-	if name, ok := data["name"]; ok {
-		updates = append(updates, firestore.Update{
-			Path:  "name",
-			Value: name,
-		})
+// storeProfilePicture renders an uploaded image into the standard avatar
+// sizes, stores them and returns their object keys keyed by size.
+// Images that cannot be decoded fail with imaging.ErrUnsupportedType.
+func (s *UserService) storeProfilePicture(image []byte) (map[string]string, error) {
+	if s.Uploader == nil {
+		return nil, ErrUploadsUnavailable
 	}
 
-	// Check if profile_picture is provided and add it to the updates
-	if profilePicture, ok := data["profile_picture"]; ok {
-		updates = append(updates, firestore.Update{
-			Path:  "profile_picture",
-			Value: profilePicture,
-		})
+	// Decode whatever was sent and render the standard sizes as WebP
+	variants, err := imaging.Process(image, imaging.AvatarOptions)
+	if err != nil {
+		return nil, err
 	}
 
+	// Upload the webp variants; the user keeps their object keys
+	return s.Uploader.UploadVariants(utils.GenerateUniqueFilename("user"), variants)
+}
+
+// deletePictures removes stored picture variants nobody refers to anymore;
+// failures are only logged. Sizes sharing an object delete it once.
+func (s *UserService) deletePictures(keys map[string]string) {
+	if s.Uploader == nil || len(keys) == 0 {
+		return
+	}
+	seen := make(map[string]bool, len(keys))
+	objects := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		objects = append(objects, key)
+	}
+	if err := s.Uploader.DeleteImages(objects...); err != nil {
+		log.Printf("Error deleting profile picture: %v", err)
+	}
+}
+
+// pictureKeys returns the object keys of the user's profile picture variants.
+// Users stored before variants existed only have the main picture, which is
+// returned if it is an object key; URLs may point anywhere and are left alone.
+func pictureKeys(user *models.User) map[string]string {
+	if len(user.ProfilePictureVariants) > 0 {
+		keys := make(map[string]string, len(user.ProfilePictureVariants))
+		for size, key := range user.ProfilePictureVariants {
+			keys[size] = key
+		}
+		return keys
+	}
+	if user.ProfilePicture == "" || strings.Contains(user.ProfilePicture, "://") {
+		return nil
+	}
+	return map[string]string{"picture": user.ProfilePicture}
+}
+
+// Profile field limits, in characters
+const (
+	maxNameLength     = 50
+	maxBioLength      = 300
+	maxLocationLength = 100
+)
+
+// UpdateUser updates the user's profile fields dynamically (e.g., name, bio,
+// phone, location). Empty strings clear the text fields. A picture that is
+// not nil replaces the profile picture; it is only processed and stored once
+// the fields are valid, and deleted again if the update fails.
+// Invalid fields are reported as validation.Errors.
+func (s *UserService) UpdateUser(id string, data map[string]interface{}, picture []byte) error {
+	ctx := context.Background()
+
 	// Only proceed if there are updates to apply
-	if len(updates) > 0 {
-		_, err := userRef.Update(context.Background(), updates)
+	if !hasProfileFields(data) && picture == nil {
+		if _, err := s.Users.GetByID(ctx, id); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	}
+
+	// Check the fields on a scratch user before storing a picture for them
+	if err := applyProfileFields(&models.User{}, data); err != nil {
+		return err
+	}
+
+	var uploaded map[string]string
+	if picture != nil {
+		keys, err := s.storeProfilePicture(picture)
 		if err != nil {
-			log.Printf("Error updating user: %v", err)
-			return fmt.Errorf("failed to update user: %v", err)
+			return err
 		}
+		uploaded = keys
+
+		fields := make(map[string]interface{}, len(data)+2)
+		for field, value := range data {
+			fields[field] = value
+		}
+		fields["profile_picture"] = keys[strconv.Itoa(imaging.DefaultAvatarSize)]
+		fields["profile_picture_variants"] = keys
+		data = fields
+	}
+
+	var previous map[string]string
+	_, err := s.Users.Update(ctx, id, func(user *models.User) error {
+		previous = pictureKeys(user)
+		return applyProfileFields(user, data)
+	})
+	if err != nil {
+		// Nobody refers to the new picture
+		s.deletePictures(uploaded)
+	} else if uploaded != nil {
+		// Nor to the one it replaced
+		s.deletePictures(previous)
+	}
+	var errs validation.Errors
+	if errors.As(err, &errs) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Error updating user: %v", err)
+		return fmt.Errorf("failed to update user: %w", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// profileFields are the UpdateUser fields applyProfileFields knows
+var profileFields = []string{"name", "bio", "location", "phone", "profile_picture"}
+
+// hasProfileFields reports whether data holds any of the profileFields
+func hasProfileFields(data map[string]interface{}) bool {
+	for _, field := range profileFields {
+		if _, ok := data[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyProfileFields sets the provided profile fields on user, reporting
+// invalid ones as validation.Errors
+func applyProfileFields(user *models.User, data map[string]interface{}) error {
+	var errs validation.Errors
+
+	// Check if name is provided
+	if name, ok := data["name"].(string); ok {
+		user.Name = strings.TrimSpace(name)
+		errs.CheckMaxLength("name", user.Name, maxNameLength)
+	}
+
+	if bio, ok := data["bio"].(string); ok {
+		user.Bio = strings.TrimSpace(bio)
+		errs.CheckMaxLength("bio", user.Bio, maxBioLength)
+	}
+
+	if location, ok := data["location"].(string); ok {
+		user.Location = strings.TrimSpace(location)
+		errs.CheckMaxLength("location", user.Location, maxLocationLength)
+	}
+
+	if phone, ok := data["phone"].(string); ok {
+		phone = models.NormalizePhone(phone)
+		errs.CheckPhone("phone", phone)
+		// A new number has to be verified again
+		if phone != user.Phone {
+			user.Phone = phone
+			user.PhoneVerified = false
+		}
+	}
+
+	// Check if profile_picture is provided
+	if profilePicture, ok := data["profile_picture"].(string); ok {
+		user.ProfilePicture = profilePicture
+		// Variants of an earlier picture must not outlive it
+		user.ProfilePictureVariants, _ = data["profile_picture_variants"].(map[string]string)
+	}
+
+	return errs.Err()
+}
+
+// ErrInvalidRole is returned when assigning a role that does not exist
+var ErrInvalidRole = errors.New("invalid role")
+
+// SetRoles replaces the user's roles and signs the user out of every session,
+// so tokens carrying the old roles stop working right away and the new roles
+// apply from the next sign-in
+func (s *UserService) SetRoles(id string, roles []string) (*models.User, error) {
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%w: at least one role is required", ErrInvalidRole)
+	}
+	for _, role := range roles {
+		if !models.IsValidRole(role) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRole, role)
+		}
+	}
+
+	user, err := s.Users.Update(context.Background(), id, func(user *models.User) error {
+		user.Roles = roles
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Sessions.LogoutAll(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to sign out user %s after changing roles: %w", user.ID, err)
+	}
+	return user, nil
+}
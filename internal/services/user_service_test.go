@@ -0,0 +1,29 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+)
+
+func TestPictureKeys(t *testing.T) {
+	variants := map[string]string{"64": "user_1_64.webp", "256": "user_1_256.webp"}
+	tests := []struct {
+		name string
+		user models.User
+		want map[string]string
+	}{
+		{"no picture", models.User{}, nil},
+		{"variants", models.User{ProfilePicture: "user_1_256.webp", ProfilePictureVariants: variants}, variants},
+		{"object key before variants", models.User{ProfilePicture: "user_1.jpg"}, map[string]string{"picture": "user_1.jpg"}},
+		{"URL before variants", models.User{ProfilePicture: "https://storage.googleapis.com/bucket/user_1.jpg"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pictureKeys(&tt.user); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pictureKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package blob stores uploaded files in Google Cloud Storage, on local disk or
+// in memory behind one interface
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for keys that hold no object
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey is returned for keys that are empty, absolute or climb out of the store
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored object
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	Updated     time.Time
+}
+
+// SignedURLOptions describes the request a signed URL allows
+type SignedURLOptions struct {
+	// Method is http.MethodGet to download or http.MethodPut to upload the object
+	Method  string
+	Expires time.Time
+	// ContentType is the type an upload must be sent with; ignored for downloads
+	ContentType string
+	// MaxBytes is the largest body an upload may send, where the store can
+	// enforce it; 0 leaves the store's own limit. Ignored for downloads.
+	MaxBytes int64
+}
+
+// Store keeps objects under slash separated keys such as images/user/1.webp
+type Store interface {
+	// Put creates or replaces the object
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get opens the object for reading; the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// Stat describes the object without reading it
+	Stat(ctx context.Context, key string) (*Object, error)
+	// SignedURL returns a URL that allows one kind of request on the object
+	// until opts.Expires, without other credentials
+	SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error)
+	// UploadHeaders returns the headers an upload to a URL signed with opts
+	// must be sent with, exactly as given
+	UploadHeaders(opts SignedURLOptions) map[string]string
+}
+
+// ValidKey reports whether key is a clean relative path usable by every store
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	return path.Clean(key) == key && key != "." && !strings.HasPrefix(key, "../") && key != ".."
+}
+
+// checkSignedURLOptions fails for methods signed URLs are not offered for
+func checkSignedURLOptions(opts SignedURLOptions) error {
+	if opts.Method != http.MethodGet && opts.Method != http.MethodPut {
+		return errors.New("signed URLs allow only GET and PUT")
+	}
+	return nil
+}
+
+// contentTypeHeader returns the headers of an upload whose signature only covers its content type
+func contentTypeHeader(opts SignedURLOptions) map[string]string {
+	return map[string]string{"Content-Type": opts.ContentType}
+}
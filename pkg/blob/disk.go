@@ -0,0 +1,161 @@
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultContentType is reported for objects stored without a content type
+const defaultContentType = "application/octet-stream"
+
+// DiskStore keeps objects as files under a directory, for development and
+// single instance deployments. Object data lives under objects/ and each
+// object's content type under meta/.
+type DiskStore struct {
+	root   string
+	signer *URLSigner
+}
+
+// diskMeta is what a DiskStore records next to each object
+type diskMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+// NewDiskStore creates a DiskStore under root, creating the directory if
+// needed; signer may be nil if no signed URLs are needed
+func NewDiskStore(root string, signer *URLSigner) (*DiskStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{root: root, signer: signer}, nil
+}
+
+func (s *DiskStore) objectPath(key string) string {
+	return filepath.Join(s.root, "objects", filepath.FromSlash(key))
+}
+
+func (s *DiskStore) metaPath(key string) string {
+	return filepath.Join(s.root, "meta", filepath.FromSlash(key)+".json")
+}
+
+// Put writes the object to a temporary file first, so readers never see a
+// partial object. The meta file is written last, so a failed upload leaves
+// no meta behind for an object that was never stored.
+func (s *DiskStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	meta, err := json.Marshal(diskMeta{ContentType: contentType})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.objectPath(key), func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	}); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.metaPath(key), func(w io.Writer) error {
+		_, err := w.Write(meta)
+		return err
+	})
+}
+
+func (s *DiskStore) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	object, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(s.objectPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, object, nil
+}
+
+func (s *DiskStore) Delete(ctx context.Context, key string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	for _, path := range []string{s.objectPath(key), s.metaPath(key)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DiskStore) Stat(ctx context.Context, key string) (*Object, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	info, err := os.Stat(s.objectPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	contentType := defaultContentType
+	if data, err := os.ReadFile(s.metaPath(key)); err == nil {
+		var meta diskMeta
+		if json.Unmarshal(data, &meta) == nil && meta.ContentType != "" {
+			contentType = meta.ContentType
+		}
+	}
+
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: contentType,
+		Updated:     info.ModTime(),
+	}, nil
+}
+
+func (s *DiskStore) SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	return signedURL(s.signer, key, opts)
+}
+
+// UploadHeaders leaves opts.MaxBytes to the blob handler's own limit
+func (s *DiskStore) UploadHeaders(opts SignedURLOptions) map[string]string {
+	return contentTypeHeader(opts)
+}
+
+// writeFileAtomic writes a file through a temporary file in the same directory
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,145 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+)
+
+// contentLengthRangeHeader bounds the size of an upload to a signed URL
+const contentLengthRangeHeader = "x-goog-content-length-range"
+
+// GCSStore keeps objects in a Google Cloud Storage bucket
+type GCSStore struct {
+	client         *storage.Client
+	bucket         string
+	googleAccessID string
+}
+
+// NewGCSStore creates a GCSStore for the bucket; googleAccessID is the service
+// account used to sign URLs
+func NewGCSStore(client *storage.Client, bucket, googleAccessID string) *GCSStore {
+	return &GCSStore{
+		client:         client,
+		bucket:         bucket,
+		googleAccessID: googleAccessID,
+	}
+}
+
+// Put uploads the object in a single request
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	writer := s.client.Bucket(s.bucket).Object(key).NewWriter(ctx)
+	writer.ContentType = contentType
+	writer.ChunkSize = 0 // Write the entire file in one go
+
+	if _, err := io.Copy(writer, r); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to upload %s: %v", key, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to upload %s: %v", key, err)
+	}
+	return nil
+}
+
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if !ValidKey(key) {
+		return nil, nil, ErrInvalidKey
+	}
+
+	reader, err := s.client.Bucket(s.bucket).Object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, &Object{
+		Key:         key,
+		Size:        reader.Attrs.Size,
+		ContentType: reader.Attrs.ContentType,
+		Updated:     reader.Attrs.LastModified,
+	}, nil
+}
+
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	err := s.client.Bucket(s.bucket).Object(key).Delete(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *GCSStore) Stat(ctx context.Context, key string) (*Object, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	attrs, err := s.client.Bucket(s.bucket).Object(key).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Key:         key,
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		Updated:     attrs.Updated,
+	}, nil
+}
+
+func (s *GCSStore) SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	if err := checkSignedURLOptions(opts); err != nil {
+		return "", err
+	}
+
+	return s.client.Bucket(s.bucket).SignedURL(key, s.signOptions(opts))
+}
+
+// signOptions returns the GCS signing options for opts
+func (s *GCSStore) signOptions(opts SignedURLOptions) *storage.SignedURLOptions {
+	signOpts := &storage.SignedURLOptions{
+		GoogleAccessID: s.googleAccessID,
+		Method:         opts.Method,
+		Expires:        opts.Expires,
+	}
+	if opts.Method == http.MethodPut {
+		signOpts.ContentType = opts.ContentType
+		if opts.MaxBytes > 0 {
+			signOpts.Headers = []string{contentLengthRangeHeader + ":" + contentLengthRange(opts.MaxBytes)}
+		}
+	}
+	return signOpts
+}
+
+// UploadHeaders includes the signed length range, which makes GCS reject
+// bodies larger than opts.MaxBytes
+func (s *GCSStore) UploadHeaders(opts SignedURLOptions) map[string]string {
+	headers := contentTypeHeader(opts)
+	if opts.MaxBytes > 0 {
+		headers[contentLengthRangeHeader] = contentLengthRange(opts.MaxBytes)
+	}
+	return headers
+}
+
+// contentLengthRange returns the value of contentLengthRangeHeader allowing up to maxBytes
+func contentLengthRange(maxBytes int64) string {
+	return fmt.Sprintf("0,%d", maxBytes)
+}
@@ -0,0 +1,104 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+	updated     time.Time
+}
+
+func (o memoryObject) describe(key string) *Object {
+	return &Object{
+		Key:         key,
+		Size:        int64(len(o.data)),
+		ContentType: o.contentType,
+		Updated:     o.updated,
+	}
+}
+
+// MemoryStore keeps objects in memory, for development and tests
+type MemoryStore struct {
+	signer *URLSigner
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// NewMemoryStore creates an empty MemoryStore; signer may be nil if no signed URLs are needed
+func NewMemoryStore(signer *URLSigner) *MemoryStore {
+	return &MemoryStore{
+		signer:  signer,
+		objects: make(map[string]memoryObject),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = memoryObject{data: data, contentType: contentType, updated: time.Now()}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if !ValidKey(key) {
+		return nil, nil, ErrInvalidKey
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	object, ok := s.objects[key]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	// Stored data is never modified, only replaced
+	return io.NopCloser(bytes.NewReader(object.data)), object.describe(key), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *MemoryStore) Stat(ctx context.Context, key string) (*Object, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	object, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return object.describe(key), nil
+}
+
+func (s *MemoryStore) SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	return signedURL(s.signer, key, opts)
+}
+
+// UploadHeaders leaves opts.MaxBytes to the blob handler's own limit
+func (s *MemoryStore) UploadHeaders(opts SignedURLOptions) map[string]string {
+	return contentTypeHeader(opts)
+}
@@ -0,0 +1,111 @@
+package blob
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBaseURL = "http://localhost:8080/v1/blobs"
+
+// signRequest signs opts for key and returns the key and query a request to
+// the URL would carry
+func signRequest(t *testing.T, s *URLSigner, key string, opts SignedURLOptions) (string, url.Values) {
+	t.Helper()
+	signed, err := s.Sign(key, opts)
+	if err != nil {
+		t.Fatalf("Sign() = %v", err)
+	}
+	if !strings.HasPrefix(signed, testBaseURL+"/") {
+		t.Fatalf("Sign() = %s, want a URL under %s", signed, testBaseURL)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("url.Parse() = %v", err)
+	}
+	return strings.TrimPrefix(u.Path, "/v1/blobs/"), u.Query()
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	signer := NewURLSigner([]byte("secret"), testBaseURL+"/")
+	now := time.Now()
+	const key = "uploads/user 1/picture.png"
+	download := SignedURLOptions{Method: http.MethodGet, Expires: now.Add(time.Minute)}
+	upload := SignedURLOptions{Method: http.MethodPut, Expires: now.Add(time.Minute), ContentType: "image/png"}
+
+	tests := []struct {
+		name string
+		opts SignedURLOptions
+		// request changes the request made to the signed URL
+		request  func(key *string, method *string, query url.Values, contentType *string)
+		verifier *URLSigner
+		at       time.Time
+		want     error
+	}{
+		{"download", download, nil, signer, now, nil},
+		{"upload", upload, nil, signer, now, nil},
+		{"upload with content type parameters", upload, func(key, method *string, query url.Values, contentType *string) {
+			*contentType = "image/png; charset=binary"
+		}, signer, now, nil},
+		{"upload with another content type", upload, func(key, method *string, query url.Values, contentType *string) {
+			*contentType = "text/html"
+		}, signer, now, ErrInvalidSignature},
+		{"upload with a changed signed content type", upload, func(key, method *string, query url.Values, contentType *string) {
+			query.Set("content_type", "text/html")
+			*contentType = "text/html"
+		}, signer, now, ErrInvalidSignature},
+		{"upload without the signed content type", upload, func(key, method *string, query url.Values, contentType *string) {
+			query.Del("content_type")
+		}, signer, now, ErrInvalidSignature},
+		{"download URL used to upload", download, func(key, method *string, query url.Values, contentType *string) {
+			*method = http.MethodPut
+		}, signer, now, ErrInvalidSignature},
+		{"upload URL used to download", upload, func(key, method *string, query url.Values, contentType *string) {
+			*method = http.MethodGet
+		}, signer, now, ErrInvalidSignature},
+		{"other key", download, func(key, method *string, query url.Values, contentType *string) {
+			*key = "uploads/user 2/picture.png"
+		}, signer, now, ErrInvalidSignature},
+		{"extended expiry", download, func(key, method *string, query url.Values, contentType *string) {
+			query.Set("expires", query.Get("expires")+"0")
+		}, signer, now, ErrInvalidSignature},
+		{"missing expiry", download, func(key, method *string, query url.Values, contentType *string) {
+			query.Del("expires")
+		}, signer, now, ErrInvalidSignature},
+		{"changed signature", download, func(key, method *string, query url.Values, contentType *string) {
+			query.Set("signature", strings.ToUpper(query.Get("signature")))
+		}, signer, now, ErrInvalidSignature},
+		{"missing signature", download, func(key, method *string, query url.Values, contentType *string) {
+			query.Del("signature")
+		}, signer, now, ErrInvalidSignature},
+		{"other secret", download, nil, NewURLSigner([]byte("other"), testBaseURL), now, ErrInvalidSignature},
+		{"at expiry", download, nil, signer, download.Expires, nil},
+		{"expired", download, nil, signer, now.Add(2 * time.Minute), ErrExpiredSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, query := signRequest(t, signer, key, tt.opts)
+			method, contentType := tt.opts.Method, tt.opts.ContentType
+			if tt.request != nil {
+				tt.request(&key, &method, query, &contentType)
+			}
+
+			err := tt.verifier.Verify(method, key, query, contentType, tt.at)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLSignerRejectsMethods(t *testing.T) {
+	signer := NewURLSigner([]byte("secret"), testBaseURL)
+	for _, method := range []string{http.MethodPost, http.MethodDelete, ""} {
+		if _, err := signer.Sign("a.png", SignedURLOptions{Method: method, Expires: time.Now().Add(time.Minute)}); err == nil {
+			t.Errorf("Sign() signed a %q URL", method)
+		}
+	}
+}
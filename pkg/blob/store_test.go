@@ -0,0 +1,185 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// testStores returns every local store, signing with signer
+func testStores(t *testing.T, signer *URLSigner) map[string]Store {
+	t.Helper()
+	disk, err := NewDiskStore(t.TempDir(), signer)
+	if err != nil {
+		t.Fatalf("NewDiskStore() = %v", err)
+	}
+	return map[string]Store{
+		"memory": NewMemoryStore(signer),
+		"disk":   disk,
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"picture.png", true},
+		{"images/user/1.webp", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secret", false},
+		{"images/../../secret", false},
+		{"..", false},
+		{".", false},
+		{"images//1.webp", false},
+		{"images/1.webp/", false},
+		{`images\1.webp`, false},
+		{"https://example.com/1.webp", false},
+	}
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for name, store := range testStores(t, nil) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			const key = "images/user/1.webp"
+			data := []byte("image data")
+
+			if err := store.Put(ctx, key, bytes.NewReader(data), "image/webp"); err != nil {
+				t.Fatalf("Put() = %v", err)
+			}
+			reader, object, err := store.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get() = %v", err)
+			}
+			got, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("Get() read %q, %v, want %q", got, err, data)
+			}
+			if object.Key != key || object.Size != int64(len(data)) || object.ContentType != "image/webp" {
+				t.Fatalf("Get() object = %+v", object)
+			}
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete() = %v", err)
+			}
+			if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Stat() after Delete() = %v, want ErrNotFound", err)
+			}
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete() of a missing object = %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreRejectsInvalidKeys(t *testing.T) {
+	for name, store := range testStores(t, NewURLSigner([]byte("secret"), testBaseURL)) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			const key = "../escape.png"
+			download := SignedURLOptions{Method: http.MethodGet, Expires: time.Now().Add(time.Minute)}
+
+			calls := map[string]func() error{
+				"Put": func() error { return store.Put(ctx, key, bytes.NewReader(nil), "image/png") },
+				"Get": func() error {
+					_, _, err := store.Get(ctx, key)
+					return err
+				},
+				"Stat": func() error {
+					_, err := store.Stat(ctx, key)
+					return err
+				},
+				"Delete": func() error { return store.Delete(ctx, key) },
+				"SignedURL": func() error {
+					_, err := store.SignedURL(ctx, key, download)
+					return err
+				},
+			}
+			for call, fn := range calls {
+				if err := fn(); !errors.Is(err, ErrInvalidKey) {
+					t.Errorf("%s() = %v, want ErrInvalidKey", call, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreSignedURLNeedsSigner(t *testing.T) {
+	for name, store := range testStores(t, nil) {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.SignedURL(context.Background(), "a.png", SignedURLOptions{Method: http.MethodGet, Expires: time.Now().Add(time.Minute)})
+			if !errors.Is(err, ErrSigningUnavailable) {
+				t.Fatalf("SignedURL() = %v, want ErrSigningUnavailable", err)
+			}
+		})
+	}
+}
+
+func TestGCSStoreSignsUploadSize(t *testing.T) {
+	store := NewGCSStore(nil, "bucket", "signer@example.iam.gserviceaccount.com")
+	upload := SignedURLOptions{Method: http.MethodPut, Expires: time.Now().Add(time.Minute), ContentType: "image/png", MaxBytes: 1024}
+
+	signOpts := store.signOptions(upload)
+	if signOpts.ContentType != "image/png" || len(signOpts.Headers) != 1 || signOpts.Headers[0] != "x-goog-content-length-range:0,1024" {
+		t.Fatalf("signOptions() = %+v, want the content type and length range signed", signOpts)
+	}
+	headers := store.UploadHeaders(upload)
+	if len(headers) != 2 || headers["Content-Type"] != "image/png" || headers["x-goog-content-length-range"] != "0,1024" {
+		t.Fatalf("UploadHeaders() = %v, want the content type and length range", headers)
+	}
+
+	// Downloads and unbounded uploads sign no length range
+	download := SignedURLOptions{Method: http.MethodGet, Expires: upload.Expires, MaxBytes: 1024}
+	if signOpts := store.signOptions(download); signOpts.ContentType != "" || len(signOpts.Headers) != 0 {
+		t.Fatalf("signOptions(download) = %+v, want no upload headers", signOpts)
+	}
+	upload.MaxBytes = 0
+	if headers := store.UploadHeaders(upload); len(headers) != 1 {
+		t.Fatalf("UploadHeaders(no limit) = %v, want only the content type", headers)
+	}
+}
+
+// failingReader fails every read after the first
+type failingReader struct {
+	read bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errors.New("connection reset")
+	}
+	r.read = true
+	return copy(p, "partial"), nil
+}
+
+func TestDiskStoreFailedPutLeavesNothing(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewDiskStore() = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "a.png", &failingReader{}, "image/png"); err == nil {
+		t.Fatal("Put(failing reader) = nil, want an error")
+	}
+	if _, err := store.Stat(ctx, "a.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat() = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(store.metaPath("a.png")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("meta file after a failed Put: %v", err)
+	}
+}
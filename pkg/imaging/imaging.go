@@ -0,0 +1,172 @@
+// Package imaging turns uploaded pictures into WebP variants of standard sizes
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"github.com/chai2010/webp"
+	xdraw "golang.org/x/image/draw"
+)
+
+// ContentType is the type of every encoded variant
+const ContentType = "image/webp"
+
+// MaxPixels bounds the decoded size of an upload, so a small file cannot
+// expand into gigabytes of pixels
+const MaxPixels = 40_000_000
+
+var (
+	// ErrUnsupportedType is returned for uploads that are not JPEG, PNG, GIF or WebP images
+	ErrUnsupportedType = errors.New("file must be a JPEG, PNG, GIF or WebP image")
+	// ErrTooManyPixels is returned for images larger than MaxPixels
+	ErrTooManyPixels = errors.New("image dimensions are too large")
+)
+
+// supportedTypes are the sniffed content types that can be decoded
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AvatarSizes are the edge lengths of the square variants made of profile pictures
+var AvatarSizes = []int{64, 256, 1024}
+
+// DefaultAvatarSize is the variant used where a single profile picture is shown
+const DefaultAvatarSize = 256
+
+// Options controls how Process renders variants
+type Options struct {
+	// Sizes are the edge lengths of the variants; images are never upscaled,
+	// so a variant may be smaller than its size
+	Sizes []int
+	// Square crops the image to its centre square before resizing
+	Square bool
+	// Quality is the lossy WebP quality from 0 to 100
+	Quality float32
+}
+
+// AvatarOptions renders square profile pictures
+var AvatarOptions = Options{Sizes: AvatarSizes, Square: true, Quality: 80}
+
+// Variant is one encoded size of a processed image
+type Variant struct {
+	Size   int
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Supported reports whether images of the content type can be processed
+func Supported(contentType string) bool {
+	return supportedTypes[contentType]
+}
+
+// Sniff returns the content type detected from the data itself, failing with
+// ErrUnsupportedType for anything that is not a supported image
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !supportedTypes[contentType] {
+		return "", fmt.Errorf("%w, got %s", ErrUnsupportedType, contentType)
+	}
+	return contentType, nil
+}
+
+// Process decodes an uploaded image, applies its EXIF orientation and encodes
+// one WebP variant per size. Metadata such as EXIF is not carried over.
+func Process(data []byte, opts Options) ([]Variant, error) {
+	contentType, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+
+	img := toNRGBA(decoded)
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	if opts.Square {
+		img = cropSquare(img)
+	}
+
+	variants := make([]Variant, 0, len(opts.Sizes))
+	for _, size := range opts.Sizes {
+		resized := fit(img, size)
+		encoded, err := encode(resized, opts.Quality)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %dpx variant: %v", size, err)
+		}
+		variants = append(variants, Variant{
+			Size:   size,
+			Width:  resized.Rect.Dx(),
+			Height: resized.Rect.Dy(),
+			Data:   encoded,
+		})
+	}
+	return variants, nil
+}
+
+// toNRGBA copies img into an NRGBA image with its origin at 0,0
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Rect, img, b.Min, draw.Src)
+	return out
+}
+
+// cropSquare returns the centre square of img
+func cropSquare(img *image.NRGBA) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w == h {
+		return img
+	}
+	side := min(w, h)
+	x, y := (w-side)/2, (h-side)/2
+	return img.SubImage(image.Rect(x, y, x+side, y+side)).(*image.NRGBA)
+}
+
+// fit scales img down so its longer edge is at most size
+func fit(img *image.NRGBA, size int) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w <= size && h <= size {
+		return toNRGBA(img)
+	}
+
+	dw, dh := size, size
+	if w > h {
+		dh = max(1, h*size/w)
+	} else {
+		dw = max(1, w*size/h)
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	xdraw.CatmullRom.Scale(out, out.Rect, img, img.Rect, xdraw.Src, nil)
+	return out
+}
+
+// encode encodes img as lossy WebP
+func encode(img *image.NRGBA, quality float32) ([]byte, error) {
+	// libwebp takes straight alpha, which is what NRGBA holds, but the
+	// package only passes *image.RGBA pixels through unconverted
+	return webp.EncodeRGBA(&image.RGBA{Pix: img.Pix, Stride: img.Stride, Rect: img.Rect}, quality)
+}
@@ -0,0 +1,155 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/chai2010/webp"
+)
+
+// testImage returns a w x h gradient, so encoders have something to compress
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// encodeAs encodes img in the given format
+func encodeAs(t *testing.T, format string, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	case "webp":
+		err = webp.Encode(&buf, img, &webp.Options{Lossless: true})
+	default:
+		t.Fatalf("unknown format %s", format)
+	}
+	if err != nil {
+		t.Fatalf("encoding %s = %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+// checkVariants fails unless every variant is a WebP image of the wanted size
+func checkVariants(t *testing.T, variants []Variant, want [][3]int) {
+	t.Helper()
+	if len(variants) != len(want) {
+		t.Fatalf("got %d variants, want %d", len(variants), len(want))
+	}
+	for i, v := range variants {
+		if v.Size != want[i][0] || v.Width != want[i][1] || v.Height != want[i][2] {
+			t.Errorf("variant %d = %dpx %dx%d, want %dpx %dx%d", i, v.Size, v.Width, v.Height, want[i][0], want[i][1], want[i][2])
+		}
+		if contentType, err := Sniff(v.Data); err != nil || contentType != ContentType {
+			t.Errorf("variant %d is %q, %v, want %s", i, contentType, err, ContentType)
+		}
+		config, err := webp.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil {
+			t.Fatalf("variant %d does not decode: %v", i, err)
+		}
+		if config.Width != v.Width || config.Height != v.Height {
+			t.Errorf("variant %d decodes to %dx%d, want %dx%d", i, config.Width, config.Height, v.Width, v.Height)
+		}
+	}
+}
+
+func TestProcessAvatar(t *testing.T) {
+	for _, format := range []string{"png", "jpeg", "gif", "webp"} {
+		t.Run(format, func(t *testing.T) {
+			variants, err := Process(encodeAs(t, format, testImage(600, 400)), AvatarOptions)
+			if err != nil {
+				t.Fatalf("Process() = %v", err)
+			}
+			// Cropped to the centre 400x400 and never upscaled
+			checkVariants(t, variants, [][3]int{{64, 64, 64}, {256, 256, 256}, {1024, 400, 400}})
+		})
+	}
+}
+
+func TestProcessKeepsAspectRatio(t *testing.T) {
+	tests := []struct {
+		name   string
+		w, h   int
+		sizes  []int
+		wanted [][3]int
+	}{
+		{"landscape", 400, 200, []int{100, 1000}, [][3]int{{100, 100, 50}, {1000, 400, 200}}},
+		{"portrait", 200, 400, []int{100}, [][3]int{{100, 50, 100}}},
+		{"thin strip", 1000, 2, []int{100}, [][3]int{{100, 100, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encodeAs(t, "png", testImage(tt.w, tt.h))
+			variants, err := Process(data, Options{Sizes: tt.sizes, Quality: 80})
+			if err != nil {
+				t.Fatalf("Process() = %v", err)
+			}
+			checkVariants(t, variants, tt.wanted)
+		})
+	}
+}
+
+func TestProcessAppliesJPEGOrientation(t *testing.T) {
+	data := withOrientation(t, encodeAs(t, "jpeg", testImage(200, 100)), orientRotate90, binary.BigEndian)
+
+	variants, err := Process(data, Options{Sizes: []int{1000}, Quality: 80})
+	if err != nil {
+		t.Fatalf("Process() = %v", err)
+	}
+	checkVariants(t, variants, [][3]int{{1000, 100, 200}})
+}
+
+// withDimensions rewrites the size in a PNG's header, fixing up its checksum
+func withDimensions(data []byte, w, h uint32) []byte {
+	out := append([]byte{}, data...)
+	// The IHDR chunk follows the 8 byte signature: length, type, data, CRC
+	ihdr := out[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	binary.BigEndian.PutUint32(out[8+8+13:], crc32.ChecksumIEEE(out[8+4:8+8+13]))
+	return out
+}
+
+func TestProcessRejects(t *testing.T) {
+	small := encodeAs(t, "png", testImage(10, 10))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrUnsupportedType},
+		{"garbage", []byte("definitely not an image"), ErrUnsupportedType},
+		{"PDF", []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"), ErrUnsupportedType},
+		{"truncated PNG", small[:len(small)/2], ErrUnsupportedType},
+		{"PNG header only", small[:8], ErrUnsupportedType},
+		{"too many pixels", withDimensions(small, 10_000, 10_000), ErrTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := Process(tt.data, AvatarOptions)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Process() = %d variants, %v, want %v", len(variants), err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// EXIF orientation values, see the TIFF/EXIF specification
+const (
+	orientNormal     = 1
+	orientFlipH      = 2
+	orientRotate180  = 3
+	orientFlipV      = 4
+	orientTranspose  = 5
+	orientRotate90   = 6
+	orientTransverse = 7
+	orientRotate270  = 8
+)
+
+const exifOrientationTag = 0x0112
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or orientNormal if
+// it has none or the metadata cannot be read
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return orientNormal
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return orientNormal
+		}
+		marker := data[pos+1]
+		// Start of scan: metadata segments come before the image data
+		if marker == 0xDA || marker == 0xD9 {
+			return orientNormal
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return orientNormal
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return orientNormal
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of a TIFF header
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientNormal
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientNormal
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return orientNormal
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		value := int(order.Uint16(tiff[entry+8:]))
+		if value < orientNormal || value > orientRotate270 {
+			return orientNormal
+		}
+		return value
+	}
+	return orientNormal
+}
+
+// orient returns img turned upright according to an EXIF orientation
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= orientNormal || orientation > orientRotate270 {
+		return img
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= orientTranspose {
+		dw, dh = h, w
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			// The source pixel that lands on x, y
+			var sx, sy int
+			switch orientation {
+			case orientFlipH:
+				sx, sy = w-1-x, y
+			case orientRotate180:
+				sx, sy = w-1-x, h-1-y
+			case orientFlipV:
+				sx, sy = x, h-1-y
+			case orientTranspose:
+				sx, sy = y, x
+			case orientRotate90:
+				sx, sy = y, h-1-x
+			case orientTransverse:
+				sx, sy = w-1-y, h-1-x
+			case orientRotate270:
+				sx, sy = w-1-y, x
+			}
+			src := img.PixOffset(img.Rect.Min.X+sx, img.Rect.Min.Y+sy)
+			dst := out.PixOffset(x, y)
+			copy(out.Pix[dst:dst+4], img.Pix[src:src+4])
+		}
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// withOrientation inserts an EXIF segment with the orientation into a JPEG
+func withOrientation(t *testing.T, data []byte, orientation int, order binary.ByteOrder) []byte {
+	t.Helper()
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	// One IFD entry: the orientation as a SHORT
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatalf("jpeg.Encode() = %v", err)
+	}
+	plain := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", plain, orientNormal},
+		{"little endian", withOrientation(t, plain, orientRotate90, binary.LittleEndian), orientRotate90},
+		{"big endian", withOrientation(t, plain, orientRotate270, binary.BigEndian), orientRotate270},
+		{"out of range", withOrientation(t, plain, 9, binary.LittleEndian), orientNormal},
+		{"truncated", withOrientation(t, plain, orientRotate90, binary.LittleEndian)[:20], orientNormal},
+		{"not a JPEG", []byte("GIF89a"), orientNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Fatalf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image with its top-left pixel marked
+	const w, h = 3, 2
+	marker := color.NRGBA{R: 255, A: 255}
+
+	tests := []struct {
+		orientation int
+		// wantX and wantY are where the marked pixel ends up
+		wantX, wantY int
+	}{
+		{orientNormal, 0, 0},
+		{orientFlipH, w - 1, 0},
+		{orientRotate180, w - 1, h - 1},
+		{orientFlipV, 0, h - 1},
+		{orientTranspose, 0, 0},
+		{orientRotate90, h - 1, 0},
+		{orientTransverse, h - 1, w - 1},
+		{orientRotate270, 0, w - 1},
+	}
+	for _, tt := range tests {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		img.SetNRGBA(0, 0, marker)
+
+		out := orient(img, tt.orientation)
+		wantW, wantH := w, h
+		if tt.orientation >= orientTranspose {
+			wantW, wantH = h, w
+		}
+		if out.Rect.Dx() != wantW || out.Rect.Dy() != wantH {
+			t.Errorf("orient(%d) is %dx%d, want %dx%d", tt.orientation, out.Rect.Dx(), out.Rect.Dy(), wantW, wantH)
+			continue
+		}
+		if got := out.NRGBAAt(tt.wantX, tt.wantY); got != marker {
+			t.Errorf("orient(%d) moved the marked pixel away from %d,%d", tt.orientation, tt.wantX, tt.wantY)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ConsoleMailer writes emails to the log instead of sending them, for local development
+type ConsoleMailer struct{}
+
+// NewConsoleMailer creates a ConsoleMailer
+func NewConsoleMailer() *ConsoleMailer {
+	return &ConsoleMailer{}
+}
+
+func (m *ConsoleMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("[MAIL] To: %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer stores every email as a file in a directory, for local development and tests
+type FileMailer struct {
+	dir string
+}
+
+// NewFileMailer creates a FileMailer writing into dir, creating it if needed
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %v", err)
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatalf("NewFileMailer() = %v", err)
+	}
+
+	msg := Message{To: "alice/../x@example.com", Subject: "Verify your email", Body: "Open https://example.com/verify?token=abc"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	if err := m.Send(context.Background(), Message{To: "bob@example.com", Subject: "Hi", Body: "Hello"}); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d files written, want one per email", len(entries))
+	}
+
+	var found bool
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, "-alice_.._x_at_example.com.eml") {
+			continue
+		}
+		found = true
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile() = %v", err)
+		}
+		want := "To: alice/../x@example.com\nSubject: Verify your email\n\nOpen https://example.com/verify?token=abc\n"
+		if string(data) != want {
+			t.Fatalf("email file = %q, want %q", data, want)
+		}
+	}
+	if !found {
+		t.Fatalf("no file named after the recipient in %v", entries)
+	}
+}
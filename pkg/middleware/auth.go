@@ -1,17 +1,34 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/Dffarhn/bakulenapi/pkg/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+// principalKey is the gin context key the authenticated Principal is stored under
+const principalKey = "principal"
+
+// Authenticator turns a bearer token into the Principal it was issued to.
+// It returns utils.ErrInvalidToken or utils.ErrTokenRevoked for rejected tokens.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*utils.Principal, error)
+}
+
+// AuthMiddleware validates bearer tokens and stores the caller's Principal in the context
+func AuthMiddleware(authenticator Authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// A user keyed rate limit may have authenticated the caller already
+		if _, ok := GetPrincipal(c); ok {
+			c.Next()
+			return
+		}
+
 		tokenHeader := c.GetHeader("Authorization")
 		if tokenHeader == "" {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "Authorization header is required")
@@ -26,33 +43,36 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := tokenParts[1]
-		token, err := utils.ValidateToken(tokenString)
-		if err != nil || !token.Valid {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
+		principal, err := authenticator.Authenticate(c.Request.Context(), tokenParts[1])
+		switch {
+		case errors.Is(err, utils.ErrTokenRevoked):
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Token has been revoked")
 			c.Abort()
 			return
-		}
-
-		// Extract user ID from token claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid token claims")
+		case errors.Is(err, utils.ErrInvalidToken):
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
 			c.Abort()
 			return
-		}
-
-		// Store the user ID in the context
-		userID, ok := claims["uid"].(string)
-		if !ok {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "User ID not found in token")
+		case err != nil:
+			log.Println("[ERROR] Token verification failed:", err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify token")
 			c.Abort()
 			return
 		}
 
-		// Pass userID to the context
-		c.Set("userId", userID)
+		// Pass the principal to the context
+		c.Set(principalKey, principal)
 
 		c.Next()
 	}
 }
+
+// GetPrincipal returns the Principal stored by AuthMiddleware
+func GetPrincipal(c *gin.Context) (*utils.Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*utils.Principal)
+	return principal, ok
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Dffarhn/bakulenapi/pkg/ratelimit"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc picks the rate limit bucket a request counts against
+type KeyFunc func(c *gin.Context) string
+
+// KeyByIP gives every client IP its own bucket
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByRoute gives every route one bucket shared by all callers
+func KeyByRoute(c *gin.Context) string {
+	return "route:" + c.Request.Method + " " + c.FullPath()
+}
+
+// KeyByUser gives every authenticated user their own bucket and falls back to
+// the client IP for anonymous requests. The rate limit runs before the
+// per-route AuthMiddleware, so the caller is authenticated here and the
+// Principal is kept for AuthMiddleware to reuse; rejected tokens count
+// against the IP and are left for AuthMiddleware to refuse.
+func KeyByUser(authenticator Authenticator) KeyFunc {
+	return func(c *gin.Context) string {
+		if principal, ok := GetPrincipal(c); ok {
+			return "user:" + principal.UserID
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok && token != "" {
+			principal, err := authenticator.Authenticate(c.Request.Context(), token)
+			if err == nil {
+				c.Set(principalKey, principal)
+				return "user:" + principal.UserID
+			}
+		}
+		return KeyByIP(c)
+	}
+}
+
+// RateLimit refuses requests with 429 Too Many Requests once the bucket picked
+// by key runs out of tokens. name separates the buckets of different route
+// groups. Requests are let through if the store fails, so an outage of a
+// shared store does not take the API down with it.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := store.Take(c.Request.Context(), name+":"+key(c), limit)
+		if err != nil {
+			log.Printf("[ERROR] Rate limit check for %s failed: %v", name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			utils.TooManyRequestsResponse(c, result.RetryAfter, "Too many requests, try again later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/pkg/ratelimit"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// failingStore is a ratelimit.Store that is down
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+// stubAuthenticator accepts the token "alice-token" as alice
+type stubAuthenticator struct{}
+
+func (stubAuthenticator) Authenticate(ctx context.Context, token string) (*utils.Principal, error) {
+	if token != "alice-token" {
+		return nil, utils.ErrInvalidToken
+	}
+	return &utils.Principal{UserID: "alice"}, nil
+}
+
+// newRateLimitedRouter serves GET / behind the rate limit
+func newRateLimitedRouter(store ratelimit.Store, key KeyFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	limit := ratelimit.Limit{Rate: 1.0 / 3600, Burst: 2}
+	router.GET("/", RateLimit(store, "test", limit, key), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+// get sends GET / from the client IP with an optional bearer token
+func get(router *gin.Engine, ip, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = ip + ":1234"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	router := newRateLimitedRouter(ratelimit.NewMemoryStore(), KeyByIP)
+
+	for i, want := range []string{"1", "0"} {
+		rec := get(router, "10.0.0.1", "")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d = %d, want %d", i, rec.Code, http.StatusNoContent)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != want {
+			t.Fatalf("request %d X-RateLimit-Remaining = %q, want %q", i, got, want)
+		}
+	}
+
+	rec := get(router, "10.0.0.1", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	// A token comes back after an hour
+	if got := rec.Header().Get("Retry-After"); got != "3600" {
+		t.Fatalf("Retry-After = %q, want 3600", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Fatalf("X-RateLimit-Limit = %q, want 2", got)
+	}
+
+	// Other clients are not affected
+	if rec := get(router, "10.0.0.2", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("request from another IP = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestRateLimitLetsRequestsThroughWhenStoreFails(t *testing.T) {
+	router := newRateLimitedRouter(failingStore{}, KeyByIP)
+
+	for i := 0; i < 3; i++ {
+		if rec := get(router, "10.0.0.1", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d = %d, want %d", i, rec.Code, http.StatusNoContent)
+		}
+	}
+}
+
+func TestKeyByUser(t *testing.T) {
+	tests := []struct {
+		name  string
+		ip    string
+		token string
+		want  string
+	}{
+		{"authenticated", "10.0.0.1", "alice-token", "user:alice"},
+		{"anonymous", "10.0.0.1", "", "ip:10.0.0.1"},
+		{"rejected token", "10.0.0.1", "bad-token", "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			var key string
+			var principal *utils.Principal
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				key = KeyByUser(stubAuthenticator{})(c)
+				principal, _ = GetPrincipal(c)
+			})
+
+			get(router, tt.ip, tt.token)
+			if key != tt.want {
+				t.Fatalf("KeyByUser() = %q, want %q", key, tt.want)
+			}
+			// The Principal is kept for AuthMiddleware only when the token was accepted
+			if (principal != nil) != (tt.want == "user:alice") {
+				t.Fatalf("stored principal = %+v", principal)
+			}
+		})
+	}
+}
+
+func TestRateLimitByUserSharesBucketAcrossIPs(t *testing.T) {
+	router := newRateLimitedRouter(ratelimit.NewMemoryStore(), KeyByUser(stubAuthenticator{}))
+
+	get(router, "10.0.0.1", "alice-token")
+	get(router, "10.0.0.2", "alice-token")
+	if rec := get(router, "10.0.0.3", "alice-token"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request of alice = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec := get(router, "10.0.0.3", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("anonymous request from a new IP = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole allows the request if the caller holds at least one of the roles.
+// It must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+
+		utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions")
+		c.Abort()
+	}
+}
+
+// RequirePermission allows the request if the caller's roles grant every one
+// of the permissions. It must run after AuthMiddleware.
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !models.RolesHavePermission(principal.Roles, permission) {
+				utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions")
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
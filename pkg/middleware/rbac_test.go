@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/internal/models"
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// serveAs runs guard for a caller holding roles; nil roles means no caller
+// was authenticated. It returns the response status code.
+func serveAs(t *testing.T, roles []string, guard gin.HandlerFunc) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		if roles != nil {
+			c.Set(principalKey, &utils.Principal{UserID: "u1", Roles: roles})
+		}
+		c.Next()
+	}, guard, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec.Code
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{"holds the role", []string{models.RoleModerator}, http.StatusNoContent},
+		{"holds one of the roles", []string{models.RoleUser, models.RoleAdmin}, http.StatusNoContent},
+		{"holds another role", []string{models.RoleUser}, http.StatusForbidden},
+		{"holds no roles", []string{}, http.StatusForbidden},
+		{"not authenticated", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := RequireRole(models.RoleModerator, models.RoleAdmin)
+			if got := serveAs(t, tt.roles, guard); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name        string
+		roles       []string
+		permissions []models.Permission
+		want        int
+	}{
+		{"granted", []string{models.RoleSeller}, []models.Permission{models.PermissionListingCreate}, http.StatusNoContent},
+		{"granted by any role", []string{models.RoleUser, models.RoleModerator}, []models.Permission{models.PermissionUsersRead}, http.StatusNoContent},
+		{"not granted", []string{models.RoleModerator}, []models.Permission{models.PermissionRolesManage}, http.StatusForbidden},
+		{"only some granted", []string{models.RoleSeller}, []models.Permission{models.PermissionListingCreate, models.PermissionListingManage}, http.StatusForbidden},
+		{"unknown role", []string{"root"}, []models.Permission{models.PermissionProfileRead}, http.StatusForbidden},
+		{"not authenticated", nil, []models.Permission{models.PermissionProfileRead}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveAs(t, tt.roles, RequirePermission(tt.permissions...)); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail rejects callers whose email was not verified when
+// their access token was issued. It must run after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		if !principal.EmailVerified {
+			utils.ErrorResponse(c, http.StatusForbidden, "Email address is not verified")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
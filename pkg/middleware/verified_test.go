@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *utils.Principal
+		want      int
+	}{
+		{"verified", &utils.Principal{UserID: "u1", EmailVerified: true}, http.StatusNoContent},
+		{"unverified", &utils.Principal{UserID: "u1"}, http.StatusForbidden},
+		{"not authenticated", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				if tt.principal != nil {
+					c.Set(principalKey, tt.principal)
+				}
+				c.Next()
+			}, RequireVerifiedEmail(), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// discoveryDocument holds the parts of an OpenID configuration we use
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// discover fetches the OpenID configuration of issuer from discoveryURL, or
+// from the well-known location under the issuer if discoveryURL is empty
+func discover(ctx context.Context, client *http.Client, discoveryURL, issuer string) (*discoveryDocument, error) {
+	if discoveryURL == "" {
+		discoveryURL = strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	}
+
+	var doc discoveryDocument
+	if err := getJSON(ctx, client, discoveryURL, &doc); err != nil {
+		return nil, fmt.Errorf("discovery failed: %v", err)
+	}
+	if doc.Issuer != issuer {
+		return nil, fmt.Errorf("discovery returned issuer %q, expected %q", doc.Issuer, issuer)
+	}
+	if doc.JWKSURI == "" {
+		return nil, errors.New("discovery document has no jwks_uri")
+	}
+	return &doc, nil
+}
+
+// getJSON fetches url and decodes its JSON body into v
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
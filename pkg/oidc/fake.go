@@ -0,0 +1,138 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// FakeIssuer is a local OpenID Connect issuer for tests and development. It
+// signs ID tokens with an ephemeral key and serves its discovery document and
+// JWKS, so it can be registered through discovery like a real provider or
+// handed to a Provider directly as its KeySource.
+type FakeIssuer struct {
+	issuer string
+	key    *utils.SigningKey
+	keys   *utils.KeySet
+}
+
+// FakeClaims describes the user a FakeIssuer token is issued for
+type FakeClaims struct {
+	Subject       string `json:"sub"`
+	Audience      string `json:"aud"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	// TTL defaults to an hour
+	TTL time.Duration `json:"-"`
+}
+
+// NewFakeIssuer creates a FakeIssuer whose tokens carry issuer as iss
+func NewFakeIssuer(issuer string) (*FakeIssuer, error) {
+	key, err := utils.GenerateSigningKey("fake-" + uuid.NewString()[:8])
+	if err != nil {
+		return nil, err
+	}
+	keys, err := utils.NewKeySet(key)
+	if err != nil {
+		return nil, err
+	}
+	return &FakeIssuer{issuer: strings.TrimSuffix(issuer, "/"), key: key, keys: keys}, nil
+}
+
+// Issuer returns the iss value of the tokens
+func (f *FakeIssuer) Issuer() string {
+	return f.issuer
+}
+
+// Config returns a provider configuration that trusts the issuer's tokens
+// for the given client IDs without fetching its keys over HTTP
+func (f *FakeIssuer) Config(name string, clientIDs ...string) Config {
+	return Config{Name: name, Issuers: []string{f.issuer}, ClientIDs: clientIDs, Keys: f}
+}
+
+// Issue signs an ID token for the given claims
+func (f *FakeIssuer) Issue(claims FakeClaims) (string, error) {
+	if claims.Subject == "" || claims.Audience == "" {
+		return "", fmt.Errorf("fake ID token needs a subject and an audience")
+	}
+	ttl := claims.TTL
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(f.key.Method, jwt.MapClaims{
+		"iss":            f.issuer,
+		"sub":            claims.Subject,
+		"aud":            claims.Audience,
+		"iat":            now.Unix(),
+		"exp":            now.Add(ttl).Unix(),
+		"email":          claims.Email,
+		"email_verified": claims.EmailVerified,
+		"name":           claims.Name,
+	})
+	token.Header["kid"] = f.key.ID
+	return token.SignedString(f.key.Private)
+}
+
+// Key implements KeySource
+func (f *FakeIssuer) Key(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	key, ok := f.keys.Key(kid)
+	if !ok || key.Method.Alg() != alg {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key.Public, nil
+}
+
+// ServeHTTP serves the discovery document and JWKS under the issuer's path,
+// plus POST <issuer>/token, which issues a token for the FakeClaims in the body
+func (f *FakeIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if i := strings.LastIndex(path, "/.well-known/"); i >= 0 {
+		path = path[i:]
+	} else if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[i:]
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                f.issuer,
+			"jwks_uri":                              f.issuer + "/jwks.json",
+			"id_token_signing_alg_values_supported": f.keys.Algorithms(),
+			"subject_types_supported":               []string{"public"},
+			"response_types_supported":              []string{"id_token"},
+		})
+	case r.Method == http.MethodGet && path == "/jwks.json":
+		writeJSON(w, http.StatusOK, f.keys.JWKS())
+	case r.Method == http.MethodPost && path == "/token":
+		var claims FakeClaims
+		if err := json.NewDecoder(r.Body).Decode(&claims); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		token, err := f.Issue(claims)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"id_token": token})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,107 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dffarhn/bakulenapi/pkg/utils"
+)
+
+const (
+	// keySetTTL is how long fetched keys are trusted before they are fetched again
+	keySetTTL = time.Hour
+	// minRefreshInterval limits refetches triggered by unknown key IDs
+	minRefreshInterval = time.Minute
+)
+
+// KeySource returns the public key that verifies a token signed with alg by key kid
+type KeySource interface {
+	Key(ctx context.Context, kid, alg string) (crypto.PublicKey, error)
+}
+
+// RemoteKeySet is a KeySource backed by a provider's JWKS URL. Keys are
+// cached and refetched when they expire or a token names an unknown key,
+// which is how providers announce rotations.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]utils.JWK
+	fetchedAt time.Time
+}
+
+// NewRemoteKeySet creates a RemoteKeySet for the JWKS document at url
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	return &RemoteKeySet{url: url, client: client}
+}
+
+func (ks *RemoteKeySet) Key(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	jwk, ok := ks.keys[kid]
+	stale := time.Since(ks.fetchedAt) > keySetTTL
+	if (!ok || stale) && time.Since(ks.fetchedAt) > minRefreshInterval {
+		if err := ks.refresh(ctx); err != nil {
+			return nil, err
+		}
+		jwk, ok = ks.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return publicKeyFor(jwk, alg)
+}
+
+// refresh fetches the JWKS document; the caller holds ks.mu
+func (ks *RemoteKeySet) refresh(ctx context.Context) error {
+	var set utils.JWKS
+	if err := getJSON(ctx, ks.client, ks.url, &set); err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %v", err)
+	}
+
+	keys := make(map[string]utils.JWK, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			keys[key.KeyID] = key
+		}
+	}
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	return nil
+}
+
+// publicKeyFor decodes jwk and checks it may verify tokens signed with alg
+func publicKeyFor(jwk utils.JWK, alg string) (crypto.PublicKey, error) {
+	if jwk.Algorithm != "" && jwk.Algorithm != alg {
+		return nil, fmt.Errorf("key %q is for %s, token uses %s", jwk.KeyID, jwk.Algorithm, alg)
+	}
+
+	key, err := jwk.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches bool
+	switch key.(type) {
+	case *rsa.PublicKey:
+		matches = strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		matches = strings.HasPrefix(alg, "ES")
+	case ed25519.PublicKey:
+		matches = alg == "EdDSA"
+	}
+	if !matches {
+		return nil, fmt.Errorf("key %q cannot verify %s tokens", jwk.KeyID, alg)
+	}
+	return key, nil
+}
@@ -0,0 +1,45 @@
+package oidc
+
+// Google returns the configuration of Google sign-in for the given OAuth client IDs
+func Google(clientIDs ...string) Config {
+	return Config{
+		Name:      "google",
+		Issuers:   []string{"https://accounts.google.com", "accounts.google.com"},
+		ClientIDs: clientIDs,
+		JWKSURL:   "https://www.googleapis.com/oauth2/v3/certs",
+	}
+}
+
+// Apple returns the configuration of Sign in with Apple for the given bundle or service IDs
+func Apple(clientIDs ...string) Config {
+	return Config{
+		Name:      "apple",
+		Issuers:   []string{"https://appleid.apple.com"},
+		ClientIDs: clientIDs,
+		JWKSURL:   "https://appleid.apple.com/auth/keys",
+	}
+}
+
+// Facebook returns the configuration of Facebook Limited Login for the given app IDs
+func Facebook(appIDs ...string) Config {
+	return Config{
+		Name:      "facebook",
+		Issuers:   []string{"https://www.facebook.com"},
+		ClientIDs: appIDs,
+		JWKSURL:   "https://limited.facebook.com/.well-known/oauth/openid/jwks/",
+	}
+}
+
+// Preset returns the built-in configuration named name, if there is one
+func Preset(name string, clientIDs ...string) (Config, bool) {
+	switch name {
+	case "google":
+		return Google(clientIDs...), true
+	case "apple":
+		return Apple(clientIDs...), true
+	case "facebook":
+		return Facebook(clientIDs...), true
+	default:
+		return Config{}, false
+	}
+}
@@ -0,0 +1,195 @@
+// Package oidc verifies ID tokens issued by OpenID Connect providers such as
+// Google, Apple or any issuer configured by discovery URL or JWKS URL.
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned for ID tokens that fail verification
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// signingAlgorithms are the asymmetric algorithms accepted in ID tokens;
+// HMAC and "none" are never accepted
+var signingAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Config describes one identity provider
+type Config struct {
+	// Name identifies the provider in routes and linked identities, e.g. "apple"
+	Name string
+	// Issuers lists the accepted iss values; the first one is the canonical
+	// issuer used for discovery
+	Issuers []string
+	// ClientIDs lists the accepted audiences, one per app registered with the provider
+	ClientIDs []string
+	// DiscoveryURL locates the OpenID configuration; defaults to
+	// <issuer>/.well-known/openid-configuration. Unused when JWKSURL is set.
+	DiscoveryURL string
+	// JWKSURL locates the provider's signing keys, skipping discovery
+	JWKSURL string
+	// Keys overrides where verification keys come from, e.g. a FakeIssuer
+	Keys KeySource
+}
+
+// IDToken holds the verified claims of an ID token
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Expiry        time.Time
+	Claims        jwt.MapClaims
+}
+
+// Provider verifies the ID tokens of one identity provider
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu   sync.Mutex
+	keys KeySource
+}
+
+// NewProvider creates a Provider; keys are discovered lazily on first use so
+// an unreachable provider does not prevent startup
+func NewProvider(cfg Config, client *http.Client) (*Provider, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("oidc provider name is required")
+	}
+	if len(cfg.Issuers) == 0 || cfg.Issuers[0] == "" {
+		return nil, fmt.Errorf("oidc provider %s: issuer is required", cfg.Name)
+	}
+	if len(cfg.ClientIDs) == 0 {
+		return nil, fmt.Errorf("oidc provider %s: at least one client ID is required", cfg.Name)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{config: cfg, client: client, keys: cfg.Keys}, nil
+}
+
+// Name returns the name the provider is registered under
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// Verify checks the signature, issuer, audience and lifetime of an ID token
+func (p *Provider) Verify(ctx context.Context, rawToken string) (*IDToken, error) {
+	keys, err := p.keySource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys.Key(ctx, kid, token.Method.Alg())
+	},
+		jwt.WithValidMethods(signingAlgorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	issuer, _ := claims.GetIssuer()
+	if !contains(p.config.Issuers, issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, issuer)
+	}
+	audience, _ := claims.GetAudience()
+	if !containsAny(p.config.ClientIDs, audience) {
+		return nil, fmt.Errorf("%w: token was not issued for this app", ErrInvalidToken)
+	}
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	token := &IDToken{
+		Issuer:        issuer,
+		Subject:       subject,
+		Email:         stringClaim(claims, "email"),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		Name:          stringClaim(claims, "name"),
+		Claims:        claims,
+	}
+	if token.Name == "" {
+		token.Name = strings.TrimSpace(stringClaim(claims, "given_name") + " " + stringClaim(claims, "family_name"))
+	}
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		token.Expiry = exp.Time
+	}
+	return token, nil
+}
+
+// keySource returns the provider's key source, discovering it on first use
+func (p *Provider) keySource(ctx context.Context) (KeySource, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil {
+		return p.keys, nil
+	}
+
+	jwksURL := p.config.JWKSURL
+	if jwksURL == "" {
+		doc, err := discover(ctx, p.client, p.config.DiscoveryURL, p.config.Issuers[0])
+		if err != nil {
+			return nil, fmt.Errorf("oidc provider %s: %v", p.config.Name, err)
+		}
+		jwksURL = doc.JWKSURI
+	}
+	p.keys = NewRemoteKeySet(jwksURL, p.client)
+	return p.keys, nil
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim; some providers, such as Apple, send booleans as strings
+func boolClaim(claims jwt.MapClaims, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testClientID = "bakulen-app"
+
+// newServedIssuer returns a FakeIssuer served over HTTP at its issuer URL
+func newServedIssuer(t *testing.T) *FakeIssuer {
+	t.Helper()
+	var fake *FakeIssuer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	fake, err := NewFakeIssuer(srv.URL + "/fake")
+	if err != nil {
+		t.Fatalf("NewFakeIssuer() = %v", err)
+	}
+	return fake
+}
+
+// signWithIssuer signs claims with method and key, naming the issuer's key ID
+func signWithIssuer(t *testing.T, f *FakeIssuer, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = f.key.ID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() = %v", err)
+	}
+	return signed
+}
+
+func TestProviderVerify(t *testing.T) {
+	fake := newServedIssuer(t)
+	other := newServedIssuer(t)
+	now := time.Now()
+
+	issue := func(claims FakeClaims) string {
+		t.Helper()
+		token, err := fake.Issue(claims)
+		if err != nil {
+			t.Fatalf("Issue() = %v", err)
+		}
+		return token
+	}
+	valid := FakeClaims{Subject: "123", Audience: testClientID, Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+	// claims returns valid claims of fake's tokens changed by edit
+	claims := func(edit func(c jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss": fake.Issuer(),
+			"sub": "123",
+			"aud": testClientID,
+			"iat": now.Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		edit(c)
+		return c
+	}
+	otherToken, err := other.Issue(valid)
+	if err != nil {
+		t.Fatalf("Issue() = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", issue(valid), false},
+		{"other audience", issue(FakeClaims{Subject: "123", Audience: "other-app"}), true},
+		{"other issuer", otherToken, true},
+		{"expired beyond the leeway", signWithIssuer(t, fake, fake.key.Method, fake.key.Private, claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-2 * time.Minute).Unix()
+		})), true},
+		{"without expiry", signWithIssuer(t, fake, fake.key.Method, fake.key.Private, claims(func(c jwt.MapClaims) {
+			delete(c, "exp")
+		})), true},
+		{"issued in the future", signWithIssuer(t, fake, fake.key.Method, fake.key.Private, claims(func(c jwt.MapClaims) {
+			c["iat"] = now.Add(time.Hour).Unix()
+		})), true},
+		{"wrong issuer claim", signWithIssuer(t, fake, fake.key.Method, fake.key.Private, claims(func(c jwt.MapClaims) {
+			c["iss"] = other.Issuer()
+		})), true},
+		{"without subject", signWithIssuer(t, fake, fake.key.Method, fake.key.Private, claims(func(c jwt.MapClaims) {
+			delete(c, "sub")
+		})), true},
+		{"HMAC signed", signWithIssuer(t, fake, jwt.SigningMethodHS256, []byte("secret"), claims(func(c jwt.MapClaims) {})), true},
+		{"garbage", "not.a.token", true},
+	}
+
+	// The issuer's keys are found directly, through discovery or at a JWKS URL
+	configs := map[string]Config{
+		"direct keys": fake.Config("fake", testClientID),
+		"discovery":   {Name: "fake", Issuers: []string{fake.Issuer()}, ClientIDs: []string{testClientID}},
+		"JWKS URL":    {Name: "fake", Issuers: []string{fake.Issuer()}, ClientIDs: []string{testClientID}, JWKSURL: fake.Issuer() + "/jwks.json"},
+	}
+	for configName, cfg := range configs {
+		provider, err := NewProvider(cfg, nil)
+		if err != nil {
+			t.Fatalf("NewProvider() = %v", err)
+		}
+		for _, tt := range tests {
+			t.Run(configName+"/"+tt.name, func(t *testing.T) {
+				token, err := provider.Verify(context.Background(), tt.token)
+				if tt.wantErr {
+					if !errors.Is(err, ErrInvalidToken) {
+						t.Fatalf("Verify() = %+v, %v, want ErrInvalidToken", token, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Verify() = %v", err)
+				}
+				if token.Subject != valid.Subject || token.Email != valid.Email || !token.EmailVerified || token.Name != valid.Name || token.Issuer != fake.Issuer() {
+					t.Fatalf("Verify() = %+v, want the claims of %+v", token, valid)
+				}
+			})
+		}
+	}
+}
+
+func TestNewProviderRequiresConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"no name", Config{Issuers: []string{"https://issuer.example.com"}, ClientIDs: []string{testClientID}}},
+		{"no issuer", Config{Name: "fake", ClientIDs: []string{testClientID}}},
+		{"no client ID", Config{Name: "fake", Issuers: []string{"https://issuer.example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProvider(tt.cfg, nil); err == nil {
+				t.Fatal("NewProvider() accepted an incomplete configuration")
+			}
+		})
+	}
+}
+
+func TestFakeIssuerTokenEndpoint(t *testing.T) {
+	fake := newServedIssuer(t)
+	provider, err := NewProvider(fake.Config("fake", testClientID), nil)
+	if err != nil {
+		t.Fatalf("NewProvider() = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		claims     FakeClaims
+		wantStatus int
+	}{
+		{"complete claims", FakeClaims{Subject: "123", Audience: testClientID, Email: "alice@example.com"}, http.StatusOK},
+		{"without subject", FakeClaims{Audience: testClientID}, http.StatusBadRequest},
+		{"without audience", FakeClaims{Subject: "123"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.claims)
+			if err != nil {
+				t.Fatalf("json.Marshal() = %v", err)
+			}
+			resp, err := http.Post(fake.Issuer()+"/token", "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("POST /token = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("POST /token = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			var result struct {
+				IDToken string `json:"id_token"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("decoding response = %v", err)
+			}
+			if token, err := provider.Verify(context.Background(), result.IDToken); err != nil || token.Subject != tt.claims.Subject {
+				t.Fatalf("Verify() = %+v, %v, want subject %s", token, err, tt.claims.Subject)
+			}
+		})
+	}
+}
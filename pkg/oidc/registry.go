@@ -0,0 +1,41 @@
+package oidc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Registry holds the identity providers users can sign in with, by name
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]*Provider)}
+}
+
+// Register adds a provider, failing if its name is already taken
+func (r *Registry) Register(p *Provider) error {
+	if _, exists := r.providers[p.Name()]; exists {
+		return fmt.Errorf("oidc provider %q is registered twice", p.Name())
+	}
+	r.providers[p.Name()] = p
+	return nil
+}
+
+// Get returns the provider registered under name
+func (r *Registry) Get(name string) (*Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names returns the names of the registered providers in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
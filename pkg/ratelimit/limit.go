@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: it holds up to Burst tokens and refills at
+// Rate tokens per second. Every request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerPeriod returns a Limit allowing requests per period on average and bursts
+// of up to burst requests; a burst of zero allows the whole period's requests at once
+func PerPeriod(requests int, period time.Duration, burst int) Limit {
+	if burst <= 0 {
+		burst = requests
+	}
+	return Limit{Rate: float64(requests) / period.Seconds(), Burst: burst}
+}
+
+// Bucket is the stored state of one token bucket. The zero Bucket is full.
+type Bucket struct {
+	Tokens  float64   `firestore:"tokens"`
+	Updated time.Time `firestore:"updated"`
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until a token is available when the request was refused
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key. Stores shared between API instances, such
+// as one backed by a database, make the limits apply to the whole deployment
+// instead of each instance.
+type Store interface {
+	// Take takes one token from the bucket at key, refilled according to limit
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Take refills b up to now and takes one token from it if there is one. It
+// returns the new bucket state, which stores persist, and the result.
+func (l Limit) Take(b Bucket, now time.Time) (Bucket, Result) {
+	tokens := float64(l.Burst)
+	if !b.Updated.IsZero() {
+		elapsed := now.Sub(b.Updated).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(tokens, b.Tokens+elapsed*l.Rate)
+	}
+
+	if tokens < 1 {
+		wait := time.Duration(math.Ceil((1 - tokens) / l.Rate * float64(time.Second)))
+		return Bucket{Tokens: tokens, Updated: now}, Result{RetryAfter: wait}
+	}
+	tokens--
+	return Bucket{Tokens: tokens, Updated: now}, Result{Allowed: true, Remaining: int(tokens)}
+}
+
+// FullAt returns when b will be full again, after which its state can be dropped
+func (l Limit) FullAt(b Bucket) time.Time {
+	missing := float64(l.Burst) - b.Tokens
+	return b.Updated.Add(time.Duration(math.Ceil(missing / l.Rate * float64(time.Second))))
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPerPeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests int
+		period   time.Duration
+		burst    int
+		want     Limit
+	}{
+		{"with burst", 60, time.Minute, 10, Limit{Rate: 1, Burst: 10}},
+		{"without burst", 20, 10 * time.Second, 0, Limit{Rate: 2, Burst: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PerPeriod(tt.requests, tt.period, tt.burst); got != tt.want {
+				t.Fatalf("PerPeriod() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitTake(t *testing.T) {
+	// One token every two seconds, up to three at once
+	limit := Limit{Rate: 0.5, Burst: 3}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		bucket     Bucket
+		want       Result
+		wantTokens float64
+	}{
+		{"new bucket is full", Bucket{}, Result{Allowed: true, Remaining: 2}, 2},
+		{"last token", Bucket{Tokens: 1, Updated: now}, Result{Allowed: true, Remaining: 0}, 0},
+		{"empty bucket", Bucket{Tokens: 0, Updated: now}, Result{RetryAfter: 2 * time.Second}, 0},
+		{"partly refilled", Bucket{Tokens: 0, Updated: now.Add(-time.Second)}, Result{RetryAfter: time.Second}, 0.5},
+		{"refilled one token", Bucket{Tokens: 0, Updated: now.Add(-2 * time.Second)}, Result{Allowed: true, Remaining: 0}, 0},
+		{"refilled several tokens", Bucket{Tokens: 0.5, Updated: now.Add(-3 * time.Second)}, Result{Allowed: true, Remaining: 1}, 1},
+		{"refill stops at the burst", Bucket{Tokens: 1, Updated: now.Add(-time.Hour)}, Result{Allowed: true, Remaining: 2}, 2},
+		{"clock went backwards", Bucket{Tokens: 0, Updated: now.Add(time.Minute)}, Result{RetryAfter: 2 * time.Second}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, result := limit.Take(tt.bucket, now)
+			if result != tt.want {
+				t.Fatalf("Take() result = %+v, want %+v", result, tt.want)
+			}
+			if bucket.Tokens != tt.wantTokens || !bucket.Updated.Equal(now) {
+				t.Fatalf("Take() bucket = %+v, want %v tokens at %v", bucket, tt.wantTokens, now)
+			}
+		})
+	}
+}
+
+func TestLimitTakeSequence(t *testing.T) {
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// at is the offset of each request from now and whether it is allowed
+	steps := []struct {
+		at      time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{500 * time.Millisecond, false},
+		{time.Second, true},
+		{time.Second, false},
+		{10 * time.Second, true},
+		{10 * time.Second, true},
+		{10 * time.Second, false},
+	}
+	var bucket Bucket
+	for i, step := range steps {
+		var result Result
+		bucket, result = limit.Take(bucket, now.Add(step.at))
+		if result.Allowed != step.allowed {
+			t.Fatalf("request %d at %v allowed = %v, want %v", i, step.at, result.Allowed, step.allowed)
+		}
+	}
+}
+
+func TestLimitFullAt(t *testing.T) {
+	limit := Limit{Rate: 0.5, Burst: 3}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		bucket Bucket
+		want   time.Time
+	}{
+		{"full", Bucket{Tokens: 3, Updated: now}, now},
+		{"one token missing", Bucket{Tokens: 2, Updated: now}, now.Add(2 * time.Second)},
+		{"empty", Bucket{Tokens: 0, Updated: now}, now.Add(6 * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limit.FullAt(tt.bucket); !got.Equal(tt.want) {
+				t.Fatalf("FullAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
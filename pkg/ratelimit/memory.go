@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	bucket Bucket
+	fullAt time.Time
+}
+
+// MemoryStore keeps token buckets in process memory, so every API instance
+// enforces its own limits
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	bucket, result := limit.Take(s.buckets[key].bucket, now)
+	s.buckets[key] = memoryEntry{bucket: bucket, fullAt: limit.FullAt(bucket)}
+	return result, nil
+}
+
+// sweep drops buckets that are full again, which behave like missing ones; the caller holds s.mu
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.buckets {
+		if now.After(entry.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
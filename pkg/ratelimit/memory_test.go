@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1.0 / 3600, Burst: 2}
+
+	for i, want := range []bool{true, true, false} {
+		result, err := store.Take(ctx, "alice", limit)
+		if err != nil {
+			t.Fatalf("Take() = %v", err)
+		}
+		if result.Allowed != want {
+			t.Fatalf("request %d allowed = %v, want %v", i, result.Allowed, want)
+		}
+		if !result.Allowed && result.RetryAfter <= 0 {
+			t.Fatalf("refused request RetryAfter = %v, want positive", result.RetryAfter)
+		}
+	}
+
+	// Other keys have buckets of their own
+	if result, err := store.Take(ctx, "bob", limit); err != nil || !result.Allowed {
+		t.Fatalf("Take(bob) = %+v, %v, want allowed", result, err)
+	}
+}
+
+func TestMemoryStoreSweepKeepsPartialBuckets(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1.0 / 3600, Burst: 1}
+
+	if result, _ := store.Take(ctx, "alice", limit); !result.Allowed {
+		t.Fatal("first Take() was refused")
+	}
+	// Force a sweep on the next Take; the empty bucket must survive it
+	store.lastSweep = time.Now().Add(-2 * sweepInterval)
+	if result, _ := store.Take(ctx, "alice", limit); result.Allowed {
+		t.Fatal("Take() after a sweep allowed a request from an empty bucket")
+	}
+}
+
+func TestMemoryStoreConcurrentTakes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1.0 / 3600, Burst: 10}
+
+	var mu sync.Mutex
+	allowed := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take(ctx, "alice", limit)
+			if err != nil {
+				t.Errorf("Take() = %v", err)
+				return
+			}
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != limit.Burst {
+		t.Fatalf("%d of 50 concurrent requests allowed, want %d", allowed, limit.Burst)
+	}
+}
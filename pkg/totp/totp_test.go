@@ -0,0 +1,91 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors, "12345678901234567890", base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfcVectors are the SHA-1 test vectors of RFC 6238 appendix B, truncated to six digits
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, tt := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(T=%d) = %v", tt.unix, err)
+		}
+		if got != tt.code {
+			t.Errorf("Code(T=%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+
+	// Authenticator apps may show secrets in lower case
+	if got, err := Code(strings.ToLower(rfcSecret), 1); err != nil || got != "287082" {
+		t.Errorf("Code(lower case secret) = %s, %v, want 287082", got, err)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code(invalid secret) = nil error, want an error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range rfcVectors {
+		at := time.Unix(tt.unix, 0)
+		step, ok := Validate(rfcSecret, tt.code, at)
+		if !ok || step != Step(at) {
+			t.Errorf("Validate(T=%d) = %d, %v, want %d, true", tt.unix, step, ok, Step(at))
+		}
+	}
+
+	at := time.Unix(1111111111, 0)
+	now := Step(at)
+	code := func(step int64) string {
+		t.Helper()
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("Code() = %v", err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"previous step", rfcSecret, code(now - 1), now - 1, true},
+		{"next step", rfcSecret, code(now + 1), now + 1, true},
+		{"two steps early", rfcSecret, code(now - 2), 0, false},
+		{"two steps late", rfcSecret, code(now + 2), 0, false},
+		{"with spaces", rfcSecret, "050 471", now, true},
+		{"too short", rfcSecret, "05047", 0, false},
+		{"too long", rfcSecret, "0504710", 0, false},
+		{"eight digit code", rfcSecret, "14050471", 0, false},
+		{"empty", rfcSecret, "", 0, false},
+		{"invalid secret", "not base32!", "050471", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, at)
+			if step != tt.wantStep || ok != tt.wantOK {
+				t.Fatalf("Validate(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageURLs turns the object keys of stored images into URLs clients can load
+type ImageURLs interface {
+	URL(key string) (string, error)
+}
+
+// URLSigner mints signed URLs for objects, see ImageUploader.SignedURL
+type URLSigner interface {
+	SignedURL(key string, expires time.Time) (string, error)
+}
+
+// maxCachedURLs bounds the number of signed URLs kept for reuse
+const maxCachedURLs = 10000
+
+type cachedURL struct {
+	url     string
+	expires time.Time
+}
+
+// SignedImageURLs mints signed URLs when images are read. Each URL is reused
+// for half its lifetime, so clients can cache the image under a stable URL.
+type SignedImageURLs struct {
+	signer URLSigner
+	bucket string
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedURL
+}
+
+// NewSignedImageURLs creates SignedImageURLs for objects of bucket whose URLs are valid for ttl
+func NewSignedImageURLs(signer URLSigner, bucket string, ttl time.Duration) *SignedImageURLs {
+	return &SignedImageURLs{
+		signer: signer,
+		bucket: bucket,
+		ttl:    ttl,
+		cache:  make(map[string]cachedURL),
+	}
+}
+
+// URL returns a signed URL for the object
+func (s *SignedImageURLs) URL(key string) (string, error) {
+	key = legacyObjectKey(key, s.bucket)
+	if key == "" || isURL(key) {
+		return key, nil
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && s.reusable(cached, now) {
+		return cached.url, nil
+	}
+
+	expires := now.Add(s.ttl)
+	signed, err := s.signer.SignedURL(key, expires)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache) >= maxCachedURLs {
+		s.prune(now)
+	}
+	s.cache[key] = cachedURL{url: signed, expires: expires}
+	return signed, nil
+}
+
+// reusable reports whether a cached URL has at least half its lifetime left
+func (s *SignedImageURLs) reusable(cached cachedURL, now time.Time) bool {
+	return cached.expires.Sub(now) > s.ttl/2
+}
+
+// prune drops the URLs that are no longer reused, or all of them if that is not enough
+func (s *SignedImageURLs) prune(now time.Time) {
+	for key, cached := range s.cache {
+		if !s.reusable(cached, now) {
+			delete(s.cache, key)
+		}
+	}
+	if len(s.cache) >= maxCachedURLs {
+		s.cache = make(map[string]cachedURL)
+	}
+}
+
+// PublicImageURLs serves images from a public bucket or a CDN in front of it
+type PublicImageURLs struct {
+	baseURL string
+	bucket  string
+}
+
+// NewPublicImageURLs creates PublicImageURLs for objects of bucket served
+// under baseURL, or straight from the public bucket if baseURL is empty
+func NewPublicImageURLs(baseURL, bucket string) *PublicImageURLs {
+	if baseURL == "" {
+		baseURL = "https://storage.googleapis.com/" + bucket
+	}
+	return &PublicImageURLs{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		bucket:  bucket,
+	}
+}
+
+// URL returns the public URL of the object
+func (p *PublicImageURLs) URL(key string) (string, error) {
+	key = legacyObjectKey(key, p.bucket)
+	if key == "" || isURL(key) {
+		return key, nil
+	}
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return p.baseURL + "/" + strings.Join(segments, "/"), nil
+}
+
+func isURL(value string) bool {
+	return strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://")
+}
+
+// legacyObjectKey returns the object key of a Cloud Storage URL for the
+// bucket, which is what was stored before object keys were. Anything else is
+// returned unchanged.
+func legacyObjectKey(value, bucket string) string {
+	if !isURL(value) || bucket == "" {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+
+	switch {
+	case u.Host == "storage.googleapis.com" && strings.HasPrefix(u.Path, "/"+bucket+"/"):
+		return strings.TrimPrefix(u.Path, "/"+bucket+"/")
+	case u.Host == bucket+".storage.googleapis.com":
+		return strings.TrimPrefix(u.Path, "/")
+	}
+	return value
+}
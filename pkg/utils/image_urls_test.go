@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// countingSigner signs URLs that name the key and how many URLs were signed before
+type countingSigner struct {
+	signed int
+}
+
+func (s *countingSigner) SignedURL(key string, expires time.Time) (string, error) {
+	s.signed++
+	return fmt.Sprintf("https://signed.example.com/%s?n=%d", key, s.signed), nil
+}
+
+func TestSignedImageURLsReuse(t *testing.T) {
+	signer := &countingSigner{}
+	const ttl = 200 * time.Millisecond
+	urls := NewSignedImageURLs(signer, "bucket", ttl)
+
+	first, err := urls.URL("images/u1/avatar.webp")
+	if err != nil {
+		t.Fatalf("URL() = %v", err)
+	}
+	again, err := urls.URL("images/u1/avatar.webp")
+	if err != nil || again != first {
+		t.Fatalf("URL() again = %q, %v, want the cached %q", again, err, first)
+	}
+	other, err := urls.URL("images/u2/avatar.webp")
+	if err != nil || other == first {
+		t.Fatalf("URL(other key) = %q, %v, want its own URL", other, err)
+	}
+	if signer.signed != 2 {
+		t.Fatalf("signed %d URLs, want 2", signer.signed)
+	}
+
+	// Past half its lifetime a URL is signed again
+	time.Sleep(ttl/2 + 20*time.Millisecond)
+	renewed, err := urls.URL("images/u1/avatar.webp")
+	if err != nil || renewed == first {
+		t.Fatalf("URL() past half the TTL = %q, %v, want a new URL", renewed, err)
+	}
+}
+
+func TestSignedImageURLsPrune(t *testing.T) {
+	tests := []struct {
+		name string
+		// wait is how long after filling the cache the next URL is signed
+		wait time.Duration
+	}{
+		// Stale URLs are dropped, which makes room
+		{"stale URLs", 60 * time.Millisecond},
+		// Fresh URLs are all dropped when nothing is stale
+		{"fresh URLs", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const ttl = 100 * time.Millisecond
+			urls := NewSignedImageURLs(&countingSigner{}, "bucket", ttl)
+			if tt.wait == 0 {
+				urls.ttl = time.Hour
+			}
+			for i := 0; i < maxCachedURLs; i++ {
+				if _, err := urls.URL(fmt.Sprintf("images/u%d.webp", i)); err != nil {
+					t.Fatalf("URL() = %v", err)
+				}
+			}
+			if len(urls.cache) != maxCachedURLs {
+				t.Fatalf("%d cached URLs, want %d", len(urls.cache), maxCachedURLs)
+			}
+
+			time.Sleep(tt.wait)
+			if _, err := urls.URL("images/new.webp"); err != nil {
+				t.Fatalf("URL() = %v", err)
+			}
+			if len(urls.cache) != 1 {
+				t.Fatalf("%d cached URLs after pruning, want 1", len(urls.cache))
+			}
+		})
+	}
+}
+
+func TestImageURLsOfLegacyValues(t *testing.T) {
+	signed := NewSignedImageURLs(&countingSigner{}, "bakulen", time.Hour)
+	public := NewPublicImageURLs("https://cdn.example.com/", "bakulen")
+
+	tests := []struct {
+		name       string
+		value      string
+		wantSigned string
+		wantPublic string
+	}{
+		{"empty", "", "", ""},
+		{"object key", "images/u1/a.webp", "https://signed.example.com/images/u1/a.webp?n=1", "https://cdn.example.com/images/u1/a.webp"},
+		{"signed URL of the bucket", "https://storage.googleapis.com/bakulen/images/u1/b.webp?X-Goog-Signature=abc",
+			"https://signed.example.com/images/u1/b.webp?n=2", "https://cdn.example.com/images/u1/b.webp"},
+		{"virtual hosted URL of the bucket", "https://bakulen.storage.googleapis.com/images/u1/c.webp",
+			"https://signed.example.com/images/u1/c.webp?n=3", "https://cdn.example.com/images/u1/c.webp"},
+		{"URL of another bucket", "https://storage.googleapis.com/other/images/d.webp",
+			"https://storage.googleapis.com/other/images/d.webp", "https://storage.googleapis.com/other/images/d.webp"},
+		{"bucket name as a prefix", "https://storage.googleapis.com/bakulen-old/e.webp",
+			"https://storage.googleapis.com/bakulen-old/e.webp", "https://storage.googleapis.com/bakulen-old/e.webp"},
+		{"foreign URL", "https://lh3.googleusercontent.com/a/photo.jpg",
+			"https://lh3.googleusercontent.com/a/photo.jpg", "https://lh3.googleusercontent.com/a/photo.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := signed.URL(tt.value); err != nil || got != tt.wantSigned {
+				t.Errorf("SignedImageURLs.URL() = %q, %v, want %q", got, err, tt.wantSigned)
+			}
+			if got, err := public.URL(tt.value); err != nil || got != tt.wantPublic {
+				t.Errorf("PublicImageURLs.URL() = %q, %v, want %q", got, err, tt.wantPublic)
+			}
+		})
+	}
+}
+
+func TestPublicImageURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		key     string
+		want    string
+	}{
+		{"default base URL", "", "images/u1/a.webp", "https://storage.googleapis.com/bakulen/images/u1/a.webp"},
+		{"spaces and reserved characters", "https://cdn.example.com", "images/user 1/a?b#c%.webp", "https://cdn.example.com/images/user%201/a%3Fb%23c%25.webp"},
+		{"unicode", "https://cdn.example.com", "images/ü/ä.webp", "https://cdn.example.com/images/%C3%BC/%C3%A4.webp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPublicImageURLs(tt.baseURL, "bakulen").URL(tt.key)
+			if err != nil || got != tt.want {
+				t.Fatalf("URL(%q) = %q, %v, want %q", tt.key, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -1,26 +1,165 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var jwtSecret = []byte("your-secret-key") // Change this to a strong secret key
+// Claims are the claims carried by our access tokens
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles         []string `json:"roles,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+}
 
-// GenerateToken creates a JWT token
-func GenerateToken(uid string) (string, error) {
-	claims := jwt.MapClaims{
-		"uid":  uid,
-		"exp":  time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
+// Principal converts validated claims into the caller they identify
+func (c *Claims) Principal() *Principal {
+	p := &Principal{
+		UserID:        c.Subject,
+		SessionID:     c.SessionID,
+		TokenID:       c.ID,
+		Roles:         c.Roles,
+		EmailVerified: c.EmailVerified,
+	}
+	if c.IssuedAt != nil {
+		p.IssuedAt = c.IssuedAt.Time
+	}
+	if c.ExpiresAt != nil {
+		p.ExpiresAt = c.ExpiresAt.Time
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return p
+}
+
+// JWTManager issues and validates the JWTs handed out to clients
+type JWTManager struct {
+	keys     *KeySet
+	ttl      time.Duration
+	issuer   string
+	audience string
 }
 
-// ValidateToken parses and validates a JWT token
-func ValidateToken(tokenStr string) (*jwt.Token, error) {
-	return jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+// NewJWTManager creates a JWTManager signing with the active key of keys.
+// Tokens expire after ttl and are bound to the given issuer and audience.
+func NewJWTManager(keys *KeySet, ttl time.Duration, issuer, audience string) *JWTManager {
+	return &JWTManager{
+		keys:     keys,
+		ttl:      ttl,
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+// TTL returns the lifetime of the tokens this manager issues
+func (m *JWTManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// Keys returns the key set used to sign and verify tokens
+func (m *JWTManager) Keys() *KeySet {
+	return m.keys
+}
+
+// TokenSubject describes the user an access token is issued to
+type TokenSubject struct {
+	UserID        string
+	SessionID     string
+	Roles         []string
+	EmailVerified bool
+}
+
+// GenerateToken creates an access token for the user's session. Every token
+// gets a unique ID (jti) so it can be revoked.
+func (m *JWTManager) GenerateToken(subject TokenSubject) (string, error) {
+	claims := m.newClaims(subject.UserID, m.audience, m.ttl)
+	claims.Roles = subject.Roles
+	claims.SessionID = subject.SessionID
+	claims.EmailVerified = subject.EmailVerified
+	return m.sign(claims)
+}
+
+// GenerateActionToken creates a signed token that authorizes a single action,
+// such as verifying an email address. Its audience is specific to the purpose,
+// so it is never accepted as an access token or for another purpose.
+func (m *JWTManager) GenerateActionToken(userID, purpose string, ttl time.Duration) (string, *Claims, error) {
+	claims := m.newClaims(userID, m.actionAudience(purpose), ttl)
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// ValidateToken parses and validates an access token
+func (m *JWTManager) ValidateToken(tokenStr string) (*Claims, error) {
+	return m.parse(tokenStr, m.audience)
+}
+
+// ValidateActionToken parses and validates an action token issued for purpose
+func (m *JWTManager) ValidateActionToken(tokenStr, purpose string) (*Claims, error) {
+	return m.parse(tokenStr, m.actionAudience(purpose))
+}
+
+func (m *JWTManager) actionAudience(purpose string) string {
+	return m.audience + "/" + purpose
+}
+
+func (m *JWTManager) newClaims(subject, audience string, ttl time.Duration) *Claims {
+	now := time.Now()
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewString(),
+		},
+	}
+}
+
+func (m *JWTManager) sign(claims *Claims) (string, error) {
+	key := m.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// parse validates a token. The token must name a known key in its kid header,
+// be signed with exactly that key's algorithm and be issued by us for audience.
+func (m *JWTManager) parse(tokenStr, audience string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("token has no key ID")
+		}
+		key, ok := m.keys.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+		}
+		return key.Public, nil
+	},
+		jwt.WithValidMethods(m.keys.Algorithms()),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithAudience(audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" || claims.ID == "" || claims.IssuedAt == nil {
+		return nil, errors.New("token is missing required claims")
+	}
+	return claims, nil
 }
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "bakulen"
+)
+
+func newTestJWTManager(t *testing.T, active *SigningKey, others ...*SigningKey) *JWTManager {
+	t.Helper()
+	keys, err := NewKeySet(active, others...)
+	if err != nil {
+		t.Fatalf("NewKeySet() = %v", err)
+	}
+	return NewJWTManager(keys, 15*time.Minute, testIssuer, testAudience)
+}
+
+func TestJWTManagerRoundTrip(t *testing.T) {
+	for _, key := range []*SigningKey{newEd25519Key(t, "ed"), newRSAKey(t, "rsa")} {
+		t.Run(key.Method.Alg(), func(t *testing.T) {
+			m := newTestJWTManager(t, key)
+			token, err := m.GenerateToken(TokenSubject{UserID: "u1", SessionID: "s1", Roles: []string{"admin"}, EmailVerified: true})
+			if err != nil {
+				t.Fatalf("GenerateToken() = %v", err)
+			}
+
+			claims, err := m.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken() = %v", err)
+			}
+			p := claims.Principal()
+			if p.UserID != "u1" || p.SessionID != "s1" || len(p.Roles) != 1 || !p.EmailVerified || p.TokenID == "" {
+				t.Fatalf("Principal() = %+v", p)
+			}
+			if d := p.ExpiresAt.Sub(p.IssuedAt); d != m.TTL() {
+				t.Fatalf("token lives %v, want %v", d, m.TTL())
+			}
+		})
+	}
+}
+
+func TestJWTManagerUsesWholeSeconds(t *testing.T) {
+	m := newTestJWTManager(t, newEd25519Key(t, "ed"))
+	token, err := m.GenerateToken(TokenSubject{UserID: "u1"})
+	if err != nil {
+		t.Fatalf("GenerateToken() = %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatalf("decoding payload = %v", err)
+	}
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		if strings.ContainsAny(string(claims[name]), ".eE") || len(claims[name]) == 0 {
+			t.Errorf("%s = %s, want whole seconds", name, claims[name])
+		}
+	}
+}
+
+func TestJWTManagerRejects(t *testing.T) {
+	key := newEd25519Key(t, "k1")
+	m := newTestJWTManager(t, key)
+
+	// sign signs the claims m would issue, changed by edit, with signer's
+	// private key and the given method
+	sign := func(signer *SigningKey, method jwt.SigningMethod, edit func(c *Claims)) string {
+		claims := m.newClaims("u1", testAudience, time.Minute)
+		if edit != nil {
+			edit(claims)
+		}
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = signer.ID
+		var signingKey interface{} = signer.Private
+		if method == jwt.SigningMethodNone {
+			signingKey = jwt.UnsafeAllowNoneSignatureType
+		}
+		s, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("SignedString() = %v", err)
+		}
+		return s
+	}
+	actionToken, _, err := m.GenerateActionToken("u1", "verify_email", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateActionToken() = %v", err)
+	}
+	hmacKey := &SigningKey{ID: "k1", Private: []byte("secret")}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", sign(key, key.Method, func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})},
+		{"not yet valid", sign(key, key.Method, func(c *Claims) {
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		})},
+		{"without expiry", sign(key, key.Method, func(c *Claims) { c.ExpiresAt = nil })},
+		{"other issuer", sign(key, key.Method, func(c *Claims) { c.Issuer = "https://evil.example.com" })},
+		{"other audience", sign(key, key.Method, func(c *Claims) { c.Audience = jwt.ClaimStrings{"other"} })},
+		{"action token", actionToken},
+		{"without subject", sign(key, key.Method, func(c *Claims) { c.Subject = "" })},
+		{"without ID", sign(key, key.Method, func(c *Claims) { c.ID = "" })},
+		{"signed by an unknown key with a known ID", sign(newEd25519Key(t, "k1"), key.Method, nil)},
+		{"unknown key ID", sign(newEd25519Key(t, "k2"), key.Method, nil)},
+		{"HMAC with a known key ID", sign(hmacKey, jwt.SigningMethodHS256, nil)},
+		{"unsigned", sign(key, jwt.SigningMethodNone, nil)},
+		{"garbage", "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := m.ValidateToken(tt.token); err == nil {
+				t.Fatalf("ValidateToken() accepted the token: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestJWTManagerActionTokenPurpose(t *testing.T) {
+	m := newTestJWTManager(t, newEd25519Key(t, "k1"))
+	token, issued, err := m.GenerateActionToken("u1", "verify_email", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateActionToken() = %v", err)
+	}
+
+	claims, err := m.ValidateActionToken(token, "verify_email")
+	if err != nil || claims.ID != issued.ID {
+		t.Fatalf("ValidateActionToken() = %v, %v, want token %s", claims, err, issued.ID)
+	}
+	if _, err := m.ValidateActionToken(token, "reset_password"); err == nil {
+		t.Fatal("ValidateActionToken() accepted the token for another purpose")
+	}
+}
+
+func TestJWTManagerKeyRotation(t *testing.T) {
+	oldKey := newRSAKey(t, "old")
+	newKey := newEd25519Key(t, "new")
+	token, err := newTestJWTManager(t, oldKey).GenerateToken(TokenSubject{UserID: "u1"})
+	if err != nil {
+		t.Fatalf("GenerateToken() = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		m       *JWTManager
+		wantErr bool
+	}{
+		{"old key still verifies", newTestJWTManager(t, newKey, verifyOnly(oldKey)), false},
+		{"old key removed", newTestJWTManager(t, newKey), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.m.ValidateToken(token); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateToken() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is one key of a KeySet. Keys without a private part can only verify.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// KeySet holds the key used to sign new tokens plus every key still accepted
+// for verification, so keys can be rotated without invalidating live tokens
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+// NewKeySet creates a KeySet signing with active and also verifying with others
+func NewKeySet(active *SigningKey, others ...*SigningKey) (*KeySet, error) {
+	if active == nil || active.Private == nil {
+		return nil, errors.New("active signing key must have a private key")
+	}
+
+	ks := &KeySet{active: active, keys: make(map[string]*SigningKey)}
+	for _, key := range append([]*SigningKey{active}, others...) {
+		if key.ID == "" {
+			return nil, errors.New("signing key ID (kid) is required")
+		}
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate signing key ID %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+		ks.order = append(ks.order, key.ID)
+	}
+	return ks, nil
+}
+
+// Active returns the key new tokens are signed with
+func (ks *KeySet) Active() *SigningKey {
+	return ks.active
+}
+
+// Key returns the verification key with the given ID
+func (ks *KeySet) Key(id string) (*SigningKey, bool) {
+	key, ok := ks.keys[id]
+	return key, ok
+}
+
+// Algorithms returns the distinct algorithms used by the keys of the set
+func (ks *KeySet) Algorithms() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, id := range ks.order {
+		alg := ks.keys[id].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 and EC keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	// EC keys
+	Y string `json:"y,omitempty"`
+}
+
+// PublicKey decodes the RSA, EC or Ed25519 public key held by the JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeKeyParam(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeKeyParam(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if n.Sign() == 0 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA key %q", k.KeyID)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q in key %q", k.Curve, k.KeyID)
+		}
+		x, err := decodeKeyParam(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeKeyParam(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid EC key %q", k.KeyID)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q in key %q", k.Curve, k.KeyID)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key %q", k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q in key %q", k.KeyType, k.KeyID)
+	}
+}
+
+// decodeKeyParam decodes a base64url encoded big-endian integer of a JWK
+func decodeKeyParam(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK parameter: %v", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWKS is a JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set for publishing to other services
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, id := range ks.order {
+		key := ks.keys[id]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// GenerateSigningKey creates a fresh Ed25519 signing key; meant for local
+// development, as tokens signed with it become invalid on restart
+func GenerateSigningKey(id string) (*SigningKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Private: priv, Public: pub}, nil
+}
+
+// LoadKey reads a PEM encoded RSA or Ed25519 key. A private key (PKCS#8 or
+// PKCS#1) can sign and verify; a public key (PKIX) only verifies, which is how
+// retired keys stay valid for tokens issued before a rotation.
+func LoadKey(id, path string) (*SigningKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %v", path, err)
+		}
+		switch k := pub.(type) {
+		case *rsa.PublicKey:
+			return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Public: k}, nil
+		case ed25519.PublicKey:
+			return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T in %s", pub, path)
+		}
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key %s: %v", path, err)
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	default:
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key %s: %v", path, err)
+		}
+		switch k := priv.(type) {
+		case *rsa.PrivateKey:
+			return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+		case ed25519.PrivateKey:
+			return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T in %s", priv, path)
+		}
+	}
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newRSAKey returns an RS256 signing key; 1024 bits keeps the tests fast
+func newRSAKey(t *testing.T, id string) *SigningKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, Private: priv, Public: &priv.PublicKey}
+}
+
+// newEd25519Key returns a signing key, failing the test on error
+func newEd25519Key(t *testing.T, id string) *SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey(id)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey() = %v", err)
+	}
+	return key
+}
+
+// verifyOnly returns the public half of key
+func verifyOnly(key *SigningKey) *SigningKey {
+	return &SigningKey{ID: key.ID, Method: key.Method, Public: key.Public}
+}
+
+func TestNewKeySet(t *testing.T) {
+	active := newEd25519Key(t, "active")
+
+	tests := []struct {
+		name    string
+		active  *SigningKey
+		others  []*SigningKey
+		wantErr bool
+	}{
+		{"active key only", active, nil, false},
+		{"with verify-only keys", active, []*SigningKey{verifyOnly(newEd25519Key(t, "old"))}, false},
+		{"no active key", nil, nil, true},
+		{"active key without private key", verifyOnly(active), nil, true},
+		{"key without ID", active, []*SigningKey{verifyOnly(newEd25519Key(t, ""))}, true},
+		{"duplicate key ID", active, []*SigningKey{verifyOnly(newEd25519Key(t, "active"))}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeySet(tt.active, tt.others...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewKeySet() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKSRoundTrip(t *testing.T) {
+	active := newEd25519Key(t, "ed")
+	retired := verifyOnly(newRSAKey(t, "rsa"))
+	keys, err := NewKeySet(active, retired)
+	if err != nil {
+		t.Fatalf("NewKeySet() = %v", err)
+	}
+
+	// Decode the set the way a verifier would receive it
+	data, err := json.Marshal(keys.JWKS())
+	if err != nil {
+		t.Fatalf("json.Marshal() = %v", err)
+	}
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+
+	want := []*SigningKey{active, retired}
+	if len(set.Keys) != len(want) {
+		t.Fatalf("JWKS has %d keys, want %d", len(set.Keys), len(want))
+	}
+	for i, jwk := range set.Keys {
+		if jwk.KeyID != want[i].ID || jwk.Algorithm != want[i].Method.Alg() || jwk.Use != "sig" {
+			t.Errorf("key %d = %+v, want kid %s and alg %s", i, jwk, want[i].ID, want[i].Method.Alg())
+		}
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("PublicKey() of %s = %v", jwk.KeyID, err)
+		}
+		if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(want[i].Public) {
+			t.Errorf("PublicKey() of %s differs from the published key", jwk.KeyID)
+		}
+	}
+}
+
+func TestJWKPublicKeyRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+	}{
+		{"unknown key type", JWK{KeyType: "oct"}},
+		{"RSA key without modulus", JWK{KeyType: "RSA", E: "AQAB"}},
+		{"RSA key with tiny exponent", JWK{KeyType: "RSA", N: "AQAB", E: "AQ"}},
+		{"RSA key with bad encoding", JWK{KeyType: "RSA", N: "!!", E: "AQAB"}},
+		{"EC key with unknown curve", JWK{KeyType: "EC", Curve: "P-192"}},
+		{"EC point off the curve", JWK{KeyType: "EC", Curve: "P-256", X: "AQ", Y: "AQ"}},
+		{"OKP key with unknown curve", JWK{KeyType: "OKP", Curve: "X25519"}},
+		{"Ed25519 key of wrong length", JWK{KeyType: "OKP", Curve: "Ed25519", X: "AQAB"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.jwk.PublicKey(); err == nil {
+				t.Fatal("PublicKey() accepted an invalid key")
+			}
+		})
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() = %v", err)
+	}
+	pkcs8 := func(key crypto.PrivateKey) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("MarshalPKCS8PrivateKey() = %v", err)
+		}
+		return der
+	}
+	pkix := func(key crypto.PublicKey) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatalf("MarshalPKIXPublicKey() = %v", err)
+		}
+		return der
+	}
+
+	tests := []struct {
+		name        string
+		block       *pem.Block
+		wantAlg     string
+		wantPrivate bool
+		wantErr     bool
+	}{
+		{"PKCS#8 RSA private key", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8(rsaKey)}, "RS256", true, false},
+		{"PKCS#1 RSA private key", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, "RS256", true, false},
+		{"PKCS#8 Ed25519 private key", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8(edPriv)}, "EdDSA", true, false},
+		{"RSA public key", &pem.Block{Type: "PUBLIC KEY", Bytes: pkix(&rsaKey.PublicKey)}, "RS256", false, false},
+		{"Ed25519 public key", &pem.Block{Type: "PUBLIC KEY", Bytes: pkix(edPub)}, "EdDSA", false, false},
+		{"garbage", &pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key.pem")
+			if err := os.WriteFile(path, pem.EncodeToMemory(tt.block), 0o600); err != nil {
+				t.Fatalf("WriteFile() = %v", err)
+			}
+
+			key, err := LoadKey("k1", path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("LoadKey() accepted an invalid key")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKey() = %v", err)
+			}
+			if key.ID != "k1" || key.Method.Alg() != tt.wantAlg || (key.Private != nil) != tt.wantPrivate || key.Public == nil {
+				t.Fatalf("LoadKey() = %+v, want alg %s and private key %v", key, tt.wantAlg, tt.wantPrivate)
+			}
+		})
+	}
+}
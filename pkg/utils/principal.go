@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when a token is malformed, expired or not issued by us
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrTokenRevoked is returned when a valid token has been revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
+)
+
+// Principal is the authenticated caller of a request, derived from its access token
+type Principal struct {
+	UserID    string
+	SessionID string
+	TokenID   string
+	Roles     []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// EmailVerified is the user's verification state when the token was issued
+	EmailVerified bool
+}
+
+// HasRole reports whether the principal was granted the role
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -9,6 +13,7 @@ type Response struct {
 	StatusCode int         `json:"statusCode"` // Capitalized to be exported
 	Message    string      `json:"message"`
 	Data       interface{} `json:"data,omitempty"` // Capitalized to be exported
+	Errors     interface{} `json:"errors,omitempty"` // Field level details of a failed request
 }
 
 // SuccessResponse sends a success response
@@ -27,3 +32,25 @@ func ErrorResponse(c *gin.Context, statusCode int, message string) {
 		Message:    message,     // Use the capitalized field
 	})
 }
+
+// ValidationErrorResponse sends a 400 response listing what is wrong with each field
+func ValidationErrorResponse(c *gin.Context, errors interface{}) {
+	c.JSON(http.StatusBadRequest, Response{
+		StatusCode: http.StatusBadRequest,
+		Message:    "Validation failed",
+		Errors:     errors,
+	})
+}
+
+// TooManyRequestsResponse sends a 429 response telling the client when to retry
+func TooManyRequestsResponse(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.JSON(http.StatusTooManyRequests, Response{
+		StatusCode: http.StatusTooManyRequests,
+		Message:    message,
+	})
+}
@@ -1,65 +1,88 @@
 package utils
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
 	"time"
 
-	"cloud.google.com/go/storage"
-	"github.com/Dffarhn/bakulenapi/config"
+	"github.com/Dffarhn/bakulenapi/pkg/blob"
+	"github.com/Dffarhn/bakulenapi/pkg/imaging"
 )
 
-// UploadImage uploads the WebP image to Firebase Storage
+// ImageUploader uploads images to a blob store
+type ImageUploader struct {
+	store blob.Store
+}
 
-func UploadImage(filename string, fileContent []byte) (string, error) {
-	// Get Firebase Storage bucket
-	bucket := config.StorageClient.Bucket("bekaspakaistorage.appspot.com")
-	folder := fmt.Sprintf("images/bakulen/%s", filename)
+// NewImageUploader creates an ImageUploader that keeps images in store
+func NewImageUploader(store blob.Store) *ImageUploader {
+	return &ImageUploader{
+		store: store,
+	}
+}
 
-	// Create a writer for the file object
-	object := bucket.Object(folder)
-	writer := object.NewWriter(context.Background())
-	writer.ContentType = "image/webp" // Set correct content type
-	writer.ChunkSize = 0              // Write the entire file in one go
+// UploadImage uploads the WebP image and returns its object key; ImageURLs
+// turns keys into URLs when they are read
+func (u *ImageUploader) UploadImage(filename string, fileContent []byte) (string, error) {
+	key := fmt.Sprintf("images/bakulen/%s", filename)
 
-	// Write the WebP file content to Firebase Storage
-	if _, err := writer.Write(fileContent); err != nil {
-		log.Println("Error uploading image to Firebase Storage:", err)
+	// Write the WebP file content to the store
+	if err := u.store.Put(context.Background(), key, bytes.NewReader(fileContent), imaging.ContentType); err != nil {
+		log.Println("Error uploading image:", err)
 		return "", fmt.Errorf("failed to upload file: %v", err)
 	}
 
-	// Close the writer
-	if err := writer.Close(); err != nil {
-		log.Println("Error closing Firebase Storage writer:", err)
-		return "", fmt.Errorf("failed to close writer: %v", err)
-	}
+	return key, nil
+}
 
-	// Generate a signed URL
-	signedURL, err := getSignedURL(folder)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate signed URL: %v", err)
+// UploadVariants uploads the variants of one image as <base>_<size>.webp and
+// returns their object keys keyed by size. When one fails, the variants
+// already uploaded are deleted again.
+func (u *ImageUploader) UploadVariants(base string, variants []imaging.Variant) (map[string]string, error) {
+	keys := make(map[string]string, len(variants))
+	for _, variant := range variants {
+		key, err := u.UploadImage(fmt.Sprintf("%s_%d.webp", base, variant.Size), variant.Data)
+		if err != nil {
+			uploaded := make([]string, 0, len(keys))
+			for _, key := range keys {
+				uploaded = append(uploaded, key)
+			}
+			if deleteErr := u.DeleteImages(uploaded...); deleteErr != nil {
+				log.Println("Error deleting uploaded image variants:", deleteErr)
+			}
+			return nil, err
+		}
+		keys[strconv.Itoa(variant.Size)] = key
 	}
-
-	return signedURL, nil
+	return keys, nil
 }
 
-// getSignedURL generates a signed URL for accessing the file
-func getSignedURL(filename string) (string, error) {
-
-    // Define signing options
-    opts := &storage.SignedURLOptions{
-        GoogleAccessID: "firebase-adminsdk-hedsy@bekaspakaistorage.iam.gserviceaccount.com",
-        Method:         "GET",
-        Expires:        time.Now().Add(24 * time.Hour),
-    }
-
-    url, err := config.GetFirebaseStorageClient().Bucket("bekaspakaistorage.appspot.com").SignedURL(filename, opts)
-    if err != nil {
-        return "", err
-    }
+// DeleteImages removes the objects of images that are no longer used. Keys
+// that are not object keys, such as the URLs of pictures uploaded before keys
+// were stored, are skipped. Every key is attempted; the first error is returned.
+func (u *ImageUploader) DeleteImages(keys ...string) error {
+	var firstErr error
+	for _, key := range keys {
+		if !blob.ValidKey(key) {
+			continue
+		}
+		if err := u.store.Delete(context.Background(), key); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete %s: %v", key, err)
+		}
+	}
+	return firstErr
+}
 
-    return url, nil
+// SignedURL generates a signed URL for reading the object until expires
+func (u *ImageUploader) SignedURL(key string, expires time.Time) (string, error) {
+	return u.store.SignedURL(context.Background(), key, blob.SignedURLOptions{
+		Method:  http.MethodGet,
+		Expires: expires,
+	})
 }
 
 func GenerateUniqueFilename(path string) string {
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Dffarhn/bakulenapi/pkg/blob"
+	"github.com/Dffarhn/bakulenapi/pkg/imaging"
+)
+
+// failingStore is a MemoryStore whose Put fails once putsLeft uploads succeeded
+type failingStore struct {
+	*blob.MemoryStore
+	putsLeft int
+}
+
+func (s *failingStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if s.putsLeft == 0 {
+		return errors.New("storage unavailable")
+	}
+	s.putsLeft--
+	return s.MemoryStore.Put(ctx, key, r, contentType)
+}
+
+func TestUploadVariantsRemovesPartialUpload(t *testing.T) {
+	variants := []imaging.Variant{{Size: 64, Data: []byte("a")}, {Size: 256, Data: []byte("b")}, {Size: 1024, Data: []byte("c")}}
+	store := &failingStore{MemoryStore: blob.NewMemoryStore(nil), putsLeft: 2}
+	uploader := NewImageUploader(store)
+
+	if keys, err := uploader.UploadVariants("u1/avatar", variants); err == nil {
+		t.Fatalf("UploadVariants() = %v, want an error", keys)
+	}
+	// The variants written before the failure are gone again
+	for _, key := range []string{"images/bakulen/u1/avatar_64.webp", "images/bakulen/u1/avatar_256.webp"} {
+		if _, err := store.Stat(context.Background(), key); !errors.Is(err, blob.ErrNotFound) {
+			t.Errorf("Stat(%s) = %v, want ErrNotFound", key, err)
+		}
+	}
+
+	store.putsLeft = len(variants)
+	keys, err := uploader.UploadVariants("u1/avatar", variants)
+	if err != nil {
+		t.Fatalf("UploadVariants() = %v", err)
+	}
+	if keys["1024"] != "images/bakulen/u1/avatar_1024.webp" || len(keys) != len(variants) {
+		t.Fatalf("UploadVariants() = %v, want a key per size", keys)
+	}
+}
@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why one request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors; it is returned as an error by validating code
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Add appends an error for field
+func (e *Errors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// Err returns e as an error, or nil if there are no field errors
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// FromBindError converts the validation errors of a gin bind call into field
+// errors. It returns false for errors that are not validation errors, such as
+// malformed JSON.
+func FromBindError(err error) (Errors, bool) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil, false
+	}
+
+	var out Errors
+	for _, fe := range verrs {
+		out.Add(fe.Field(), tagMessage(fe))
+	}
+	return out, true
+}
+
+// tagMessage renders a readable message for a failed binding tag
+func tagMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "username":
+		return usernameRule
+	case "eqfield":
+		return "must match " + strings.ToLower(fe.Param())
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	default:
+		return fmt.Sprintf("is invalid (%s)", fe.Tag())
+	}
+}
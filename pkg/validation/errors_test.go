@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestFromBindError(t *testing.T) {
+	RegisterBindings()
+
+	type request struct {
+		Email    string `json:"email" binding:"required,email"`
+		Username string `json:"username" binding:"required,username"`
+		Password string `json:"password" binding:"required,min=8"`
+		Confirm  string `json:"confirm_password" binding:"eqfield=Password"`
+	}
+
+	err := binding.Validator.ValidateStruct(&request{
+		Email:    "alice@localhost",
+		Username: "_alice",
+		Password: "short",
+		Confirm:  "other",
+	})
+	errs, ok := FromBindError(err)
+	if !ok {
+		t.Fatalf("FromBindError(%v) did not recognise a validation error", err)
+	}
+
+	want := Errors{
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "username", Message: usernameRule},
+		{Field: "password", Message: "must be at least 8 characters"},
+		{Field: "confirm_password", Message: "must match password"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("FromBindError() = %v, want %v", errs, want)
+	}
+	for i := range errs {
+		if errs[i] != want[i] {
+			t.Fatalf("FromBindError() = %v, want %v", errs, want)
+		}
+	}
+}
+
+func TestFromBindErrorIgnoresOtherErrors(t *testing.T) {
+	if errs, ok := FromBindError(errors.New("unexpected EOF")); ok {
+		t.Fatalf("FromBindError() = %v, true, want false", errs)
+	}
+}
+
+func TestErrorsErr(t *testing.T) {
+	var errs Errors
+	if err := errs.Err(); err != nil {
+		t.Fatalf("Err() of no field errors = %v, want nil", err)
+	}
+
+	errs.CheckEmail("email", "")
+	errs.CheckUsername("username", "a")
+	err := errs.Err()
+	var got Errors
+	if !errors.As(err, &got) || len(got) != 2 {
+		t.Fatalf("Err() = %v, want both field errors", err)
+	}
+	if want := "validation failed: email is required; username " + usernameRule; err.Error() != want {
+		t.Fatalf("Error() = %q, want %q", err.Error(), want)
+	}
+}
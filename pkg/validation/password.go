@@ -0,0 +1,160 @@
+package validation
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy decides which passwords users may choose
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MinCharClasses is how many of lowercase, uppercase, digits and symbols must appear
+	MinCharClasses   int
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// breached holds upper-case SHA-1 hex digests of known breached passwords
+	breached map[string]struct{}
+}
+
+var sha1Line = regexp.MustCompile(`^[0-9A-Fa-f]{40}(:\d+)?$`)
+
+// LoadBreachedPasswords reads a breached-password list into the policy. Each
+// line is either a plain password or a SHA-1 hex digest, optionally followed
+// by ":count" as in the Have I Been Pwned downloads. Blank lines and lines
+// starting with # are ignored.
+func (p *PasswordPolicy) LoadBreachedPasswords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %v", err)
+	}
+	defer file.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if sha1Line.MatchString(line) {
+			digest, _, _ := strings.Cut(line, ":")
+			breached[strings.ToUpper(digest)] = struct{}{}
+		} else {
+			breached[passwordDigest(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read breached password list: %v", err)
+	}
+
+	p.breached = breached
+	return nil
+}
+
+// Check returns every rule the password breaks. userInputs are values such as
+// the username or email that the password must not simply repeat.
+func (p *PasswordPolicy) Check(password string, userInputs ...string) []string {
+	var problems []string
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	// bcrypt ignores everything after 72 bytes
+	if (p.MaxLength > 0 && length > p.MaxLength) || len(password) > 72 {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters", p.maxLength()))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.RequireLowercase && !lower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.RequireUppercase && !upper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+	if classes := countTrue(lower, upper, digit, symbol); classes < p.MinCharClasses {
+		problems = append(problems, fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharClasses))
+	}
+
+	for _, input := range userInputs {
+		if input != "" && strings.EqualFold(password, input) {
+			problems = append(problems, "must not be the same as your username or email")
+			break
+		}
+	}
+
+	if _, ok := p.breached[passwordDigest(password)]; ok {
+		problems = append(problems, "has appeared in a data breach, please choose another")
+	}
+
+	return problems
+}
+
+// CheckPassword adds a field error for every rule the password breaks
+func (e *Errors) CheckPassword(field, password string, policy *PasswordPolicy, userInputs ...string) {
+	if password == "" {
+		e.Add(field, "is required")
+		return
+	}
+	for _, problem := range policy.Check(password, userInputs...) {
+		e.Add(field, problem)
+	}
+}
+
+func (p *PasswordPolicy) maxLength() int {
+	if p.MaxLength > 0 && p.MaxLength < 72 {
+		return p.MaxLength
+	}
+	return 72
+}
+
+func passwordDigest(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// DefaultPasswordPolicy returns the policy used when none is configured
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      72,
+		MinCharClasses: 2,
+	}
+}
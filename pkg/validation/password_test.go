@@ -0,0 +1,139 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	strict := &PasswordPolicy{
+		MinLength:        10,
+		MaxLength:        20,
+		RequireLowercase: true,
+		RequireUppercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+	}
+
+	tests := []struct {
+		name       string
+		policy     *PasswordPolicy
+		password   string
+		userInputs []string
+		want       []string
+	}{
+		{"good password", DefaultPasswordPolicy(), "correct-horse-9", nil, nil},
+		{"too short", DefaultPasswordPolicy(), "abc12", nil, []string{"must be at least 8 characters"}},
+		{"length counts characters, not bytes", DefaultPasswordPolicy(), "pässwört1", nil, nil},
+		{"too long", strict, "Abcdefghijklmnopqrst1!", nil, []string{"must be at most 20 characters"}},
+		{"longer than bcrypt accepts", DefaultPasswordPolicy(), strings.Repeat("ab1", 25), nil, []string{"must be at most 72 characters"}},
+		{"too few character classes", DefaultPasswordPolicy(), "abcdefghij", nil, []string{
+			"must mix at least 2 of lowercase letters, uppercase letters, digits and symbols",
+		}},
+		{"missing required classes", strict, "abcdefghijk", nil, []string{
+			"must contain an uppercase letter",
+			"must contain a digit",
+			"must contain a symbol",
+		}},
+		{"all required classes", strict, "Abcdefghij1!", nil, nil},
+		{"same as username", DefaultPasswordPolicy(), "Alice_2024", []string{"alice_2024", "alice@example.com"}, []string{
+			"must not be the same as your username or email",
+		}},
+		{"same as email", DefaultPasswordPolicy(), "alice@example.com", []string{"alice", "alice@example.com"}, []string{
+			"must not be the same as your username or email",
+		}},
+		{"contains the username", DefaultPasswordPolicy(), "alice-secret-9", []string{"alice"}, nil},
+		{"empty user input is ignored", DefaultPasswordPolicy(), "", []string{""}, []string{
+			"must be at least 8 characters",
+			"must mix at least 2 of lowercase letters, uppercase letters, digits and symbols",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Check(tt.password, tt.userInputs...)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyBreachedPasswords(t *testing.T) {
+	// "Password1" as a SHA-1 digest with a count, the others in plain text
+	list := strings.Join([]string{
+		"# breached passwords",
+		"",
+		"70ccd9007338d6d81dd3b6271621b9cf9a97ea00:4",
+		"letmein123",
+		"  qwerty-2024  ",
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	policy := DefaultPasswordPolicy()
+	if err := policy.LoadBreachedPasswords(path); err != nil {
+		t.Fatalf("LoadBreachedPasswords() = %v", err)
+	}
+
+	tests := []struct {
+		password string
+		breached bool
+	}{
+		{"Password1", true},
+		{"letmein123", true},
+		{"qwerty-2024", true},
+		{"password1", false},
+		{"# breached passwords", false},
+		{"correct-horse-9", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			got := false
+			for _, problem := range policy.Check(tt.password) {
+				got = got || problem == "has appeared in a data breach, please choose another"
+			}
+			if got != tt.breached {
+				t.Fatalf("Check() reported breached %v, want %v", got, tt.breached)
+			}
+		})
+	}
+}
+
+func TestLoadBreachedPasswordsMissingFile(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	if err := policy.LoadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("LoadBreachedPasswords() accepted a missing file")
+	}
+}
+
+func TestErrorsCheckPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     Errors
+	}{
+		{"valid", "correct-horse-9", nil},
+		{"missing", "", Errors{{Field: "password", Message: "is required"}}},
+		{"one problem per field error", "abc", Errors{
+			{Field: "password", Message: "must be at least 8 characters"},
+			{Field: "password", Message: "must mix at least 2 of lowercase letters, uppercase letters, digits and symbols"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs Errors
+			errs.CheckPassword("password", tt.password, DefaultPasswordPolicy(), "alice")
+			if len(errs) != len(tt.want) {
+				t.Fatalf("CheckPassword() = %v, want %v", errs, tt.want)
+			}
+			for i := range errs {
+				if errs[i] != tt.want[i] {
+					t.Fatalf("CheckPassword() = %v, want %v", errs, tt.want)
+				}
+			}
+		})
+	}
+}
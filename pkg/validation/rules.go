@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	maxEmailLength = 254
+	usernameRule   = "must be 3-30 characters of letters, digits, underscores or dots, starting with a letter or digit"
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.]{2,29}$`)
+	phonePattern    = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+// ValidEmail reports whether email is a single bare address such as user@example.com
+func ValidEmail(email string) bool {
+	if email == "" || len(email) > maxEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return false
+	}
+	// Require a dot in the domain part, mail.ParseAddress accepts "user@localhost"
+	at := strings.LastIndex(email, "@")
+	return strings.Contains(email[at+1:], ".")
+}
+
+// ValidUsername reports whether username satisfies the username rules
+func ValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
+
+// ValidPhone reports whether phone is an E.164 number such as +628123456789
+func ValidPhone(phone string) bool {
+	return phonePattern.MatchString(phone)
+}
+
+// CheckEmail adds a field error when email is missing or malformed
+func (e *Errors) CheckEmail(field, email string) {
+	switch {
+	case email == "":
+		e.Add(field, "is required")
+	case !ValidEmail(email):
+		e.Add(field, "must be a valid email address")
+	}
+}
+
+// CheckUsername adds a field error when username is missing or breaks the username rules
+func (e *Errors) CheckUsername(field, username string) {
+	switch {
+	case username == "":
+		e.Add(field, "is required")
+	case !ValidUsername(username):
+		e.Add(field, usernameRule)
+	}
+}
+
+// CheckPhone adds a field error when a non-empty phone is not an E.164 number
+func (e *Errors) CheckPhone(field, phone string) {
+	if phone != "" && !ValidPhone(phone) {
+		e.Add(field, "must be an international number such as +628123456789")
+	}
+}
+
+// CheckMaxLength adds a field error when value is longer than max characters
+func (e *Errors) CheckMaxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		e.Add(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+var registerOnce sync.Once
+
+// RegisterBindings teaches gin's validator the custom "username" and
+// stricter "email" tags and to report fields by their JSON names. Both tags
+// ignore surrounding whitespace, which the services trim when normalizing.
+func RegisterBindings() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				return field.Name
+			}
+			return name
+		})
+		v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+			return ValidUsername(strings.TrimSpace(fl.Field().String()))
+		})
+		v.RegisterValidation("email", func(fl validator.FieldLevel) bool {
+			return ValidEmail(strings.TrimSpace(fl.Field().String()))
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"alice@example.com", true},
+		{"alice.smith+shop@mail.example.co.id", true},
+		{"", false},
+		{"alice", false},
+		{"alice@localhost", false},
+		{"Alice <alice@example.com>", false},
+		{"alice@example.com, bob@example.com", false},
+		{strings.Repeat("a", 250) + "@example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if got := ValidEmail(tt.email); got != tt.want {
+				t.Fatalf("ValidEmail(%q) = %v, want %v", tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"alice", true},
+		{"alice_smith.99", true},
+		{"42nd", true},
+		{"al", false},
+		{strings.Repeat("a", 31), false},
+		{"_alice", false},
+		{".alice", false},
+		{"alice smith", false},
+		{"alice-smith", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			if got := ValidUsername(tt.username); got != tt.want {
+				t.Fatalf("ValidUsername(%q) = %v, want %v", tt.username, got, tt.want)
+			}
+		})
+	}
+}